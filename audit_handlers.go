@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"olimpo-vicedecanatura/config"
+	"olimpo-vicedecanatura/functions"
+)
+
+// auditEntityTypes son los tipos de entidad que admite el filtro entity_type
+var auditEntityTypes = map[string]bool{
+	functions.AuditEntityCareer:      true,
+	functions.AuditEntityStudyPlan:   true,
+	functions.AuditEntitySubject:     true,
+	functions.AuditEntityEquivalence: true,
+	functions.AuditEntitySede:        true,
+	functions.AuditEntityFaculty:     true,
+}
+
+// auditEntityTypeList lista los tipos de entidad que admite el filtro entity_type, en orden alfabético
+func auditEntityTypeList() string {
+	types := make([]string, 0, len(auditEntityTypes))
+	for entityType := range auditEntityTypes {
+		types = append(types, entityType)
+	}
+	slices.Sort(types)
+	return strings.Join(types, ", ")
+}
+
+// AuditLogResponse es un registro de la auditoría con los estados antes y después como JSON
+type AuditLogResponse struct {
+	ID         uint            `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   uint            `json:"entity_id"`
+	EntityKey  string          `json:"entity_key"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Reason     string          `json:"reason,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// getAuditLogs consulta la auditoría de cambios del catálogo, del más reciente al más antiguo.
+// Admite filtros opcionales por entity_type (uno de auditEntityTypes), entity_id,
+// entity_key (por ejemplo el código de la materia), actor y fechas from y to (AAAA-MM-DD, ambas inclusive),
+// y paginación con limit y offset.
+func getAuditLogs(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro limit debe estar entre 1 y 200"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro offset es inválido"})
+		return
+	}
+
+	filter := functions.AuditFilter{
+		EntityType: c.Query("entity_type"),
+		EntityKey:  c.Query("entity_key"),
+		Actor:      c.Query("actor"),
+		Limit:      limit,
+		Offset:     offset,
+	}
+	if filter.EntityType != "" && !auditEntityTypes[filter.EntityType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "entity_type inválido, use uno de: " + auditEntityTypeList()})
+		return
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		id, err := strconv.ParseUint(entityID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro entity_id es inválido"})
+			return
+		}
+		filter.EntityID = uint(id)
+	}
+	if from := c.Query("from"); from != "" {
+		date, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Fecha from inválida, use el formato AAAA-MM-DD"})
+			return
+		}
+		filter.From = &date
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Fecha to inválida, use el formato AAAA-MM-DD"})
+			return
+		}
+		// La fecha final es inclusive: se consulta hasta el inicio del día siguiente
+		end := date.AddDate(0, 0, 1)
+		filter.To = &end
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La fecha from debe ser anterior o igual a to"})
+		return
+	}
+
+	entries, total, err := functions.ListAuditLogs(config.DB, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]AuditLogResponse, len(entries))
+	for i, entry := range entries {
+		response[i] = AuditLogResponse{
+			ID:         entry.ID,
+			Actor:      entry.Actor,
+			Action:     entry.Action,
+			EntityType: entry.EntityType,
+			EntityID:   entry.EntityID,
+			EntityKey:  entry.EntityKey,
+			Before:     rawAuditState(entry.Before),
+			After:      rawAuditState(entry.After),
+			Reason:     entry.Reason,
+			CreatedAt:  entry.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"audit_logs": response,
+		"total":      total,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+// rawAuditState entrega el estado guardado tal cual; sin estado se responde null
+func rawAuditState(state *string) json.RawMessage {
+	if state == nil {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(*state)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"olimpo-vicedecanatura/config"
+	"olimpo-vicedecanatura/functions"
+	"olimpo-vicedecanatura/models"
+)
+
+// Límites de las comparaciones en lote
+const (
+	maxBatchItems    = 1000
+	maxBatchZipBytes = 32 << 20 // 32 MB
+	maxBatchTextSize = 1 << 20  // 1 MB por historia
+	maxBatchZipText  = 64 << 20 // 64 MB descomprimidos por zip
+)
+
+// BatchCompareRequest estructura para la solicitud de comparación en lote en formato JSON
+type BatchCompareRequest struct {
+	TargetCareerCode string             `json:"target_career_code"`
+	StudyPlanID      uint               `json:"study_plan_id"`
+	AdmissionPeriod  string             `json:"admission_period"` // Resuelve el plan vigente en ese periodo
+	Sede             string             `json:"sede"`             // Sede de los estudiantes: elige el plan de la sede y la variante de los códigos
+	Items            []BatchCompareItem `json:"items" binding:"required"`
+}
+
+// BatchCompareItem representa una historia del lote, en texto plano o ya estructurada
+type BatchCompareItem struct {
+	ID                  string                `json:"id"`
+	AcademicHistoryText string                `json:"academic_history_text"`
+	Subjects            []models.SubjectInput `json:"subjects"`
+}
+
+// compareBatch compara muchas historias académicas contra un mismo plan de estudio.
+// Acepta JSON con un arreglo de historias o form-data con un archivo zip de archivos .txt.
+// Con ?async=true el lote se encola como trabajo asíncrono y se responde con el trabajo creado.
+func compareBatch(c *gin.Context) {
+	opts, ok := compareOptions(c)
+	if !ok {
+		return
+	}
+	studyPlan, items, err := readBatchRequest(c, opts.AsOf)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if async, _ := strconv.ParseBool(c.Query("async")); async {
+		payload := batchComparePayload{
+			StudyPlanID: studyPlan.ID,
+			Items:       items,
+			Explain:     opts.Explain,
+			AsOf:        opts.AsOf,
+		}
+		job, err := jobQueue.Submit(jobTypeBatchCompare, payload, requestActor(c))
+		if err != nil {
+			respondSubmitError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"job": jobResponse(job),
+		})
+		return
+	}
+
+	workers := config.BatchWorkers()
+	results, err := functions.CompareBatch(c.Request.Context(), config.DB, studyPlan.ID, items, workers, opts, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"study_plan_info": gin.H{
+			"id":      studyPlan.ID,
+			"version": studyPlan.Version,
+			"career":  studyPlan.Career.Name,
+		},
+		"summary": batchSummary(results, workers),
+	})
+}
+
+// batchSummary resume cuántas historias del lote se compararon correctamente
+func batchSummary(results []models.BatchItemResult, workers int) gin.H {
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	return gin.H{
+		"total_items": len(results),
+		"succeeded":   len(results) - failed,
+		"failed":      failed,
+		"workers":     workers,
+	}
+}
+
+// readBatchRequest lee el plan de estudio y las historias del lote desde JSON o desde un zip. El plan se
+// resuelve en el catálogo del instante asOf, o en el vigente si es nulo.
+func readBatchRequest(c *gin.Context, asOf *time.Time) (*models.StudyPlan, []models.BatchItem, error) {
+	var careerCode, studyPlanParam, admissionPeriod, sede string
+	var items []models.BatchItem
+
+	contentType := c.GetHeader("Content-Type")
+	if strings.HasPrefix(contentType, "application/json") {
+		var req BatchCompareRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return nil, nil, errors.New("Datos de entrada inválidos: " + err.Error())
+		}
+		careerCode = req.TargetCareerCode
+		admissionPeriod = req.AdmissionPeriod
+		sede = req.Sede
+		if req.StudyPlanID != 0 {
+			studyPlanParam = strconv.FormatUint(uint64(req.StudyPlanID), 10)
+		}
+		for i, reqItem := range req.Items {
+			id := reqItem.ID
+			if id == "" {
+				id = strconv.Itoa(i + 1)
+			}
+			if reqItem.AcademicHistoryText != "" {
+				items = append(items, batchItemFromText(id, reqItem.AcademicHistoryText, careerCode))
+			} else {
+				items = append(items, models.BatchItem{
+					ID:              id,
+					AcademicHistory: models.AcademicHistoryInput{CareerCode: careerCode, Subjects: reqItem.Subjects},
+				})
+			}
+		}
+	} else if strings.HasPrefix(contentType, "multipart/form-data") {
+		careerCode = c.PostForm("target_career_code")
+		studyPlanParam = c.PostForm("study_plan_id")
+		admissionPeriod = c.PostForm("admission_period")
+		sede = c.PostForm("sede")
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, nil, errors.New("Falta el archivo zip en el campo file")
+		}
+		if fileHeader.Size > maxBatchZipBytes {
+			return nil, nil, errors.New("El archivo zip supera el tamaño máximo permitido")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, nil, errors.New("No se pudo leer el archivo zip")
+		}
+		defer file.Close()
+		items, err = batchItemsFromZip(file, fileHeader.Size, careerCode)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		return nil, nil, errors.New("Content-Type no soportado. Usa application/json o form-data con un archivo zip.")
+	}
+
+	if len(items) == 0 {
+		return nil, nil, errors.New("El lote no contiene historias académicas")
+	}
+	if len(items) > maxBatchItems {
+		return nil, nil, errors.New("El lote supera el máximo de " + strconv.Itoa(maxBatchItems) + " historias")
+	}
+	for i := range items {
+		items[i].AcademicHistory.Sede = sede
+	}
+
+	// Resolver el plan de estudio contra el que se compara todo el lote
+	var studyPlan *models.StudyPlan
+	if studyPlanParam != "" {
+		studyPlanID, err := strconv.ParseUint(studyPlanParam, 10, 32)
+		if err != nil {
+			return nil, nil, errors.New("ID de plan de estudio inválido")
+		}
+		plan, err := functions.GetStudyPlanAsOf(config.DB, uint(studyPlanID), asOf)
+		if err != nil {
+			return nil, nil, err
+		}
+		studyPlan = plan
+	} else if careerCode != "" {
+		plan, err := functions.ResolveStudyPlanAsOf(config.DB, careerCode, admissionPeriod, sede, asOf)
+		if err != nil {
+			return nil, nil, err
+		}
+		studyPlan = plan
+	} else {
+		return nil, nil, errors.New("Se requiere target_career_code o study_plan_id")
+	}
+
+	return studyPlan, items, nil
+}
+
+// batchItemsFromZip lee cada archivo .txt del zip como una historia académica en texto plano
+func batchItemsFromZip(file io.ReaderAt, size int64, careerCode string) ([]models.BatchItem, error) {
+	reader, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, errors.New("El archivo no es un zip válido")
+	}
+
+	var items []models.BatchItem
+	var total int
+	for _, entry := range reader.File {
+		if entry.FileInfo().IsDir() || !strings.EqualFold(path.Ext(entry.Name), ".txt") {
+			continue
+		}
+		// Cortar antes de descomprimir más historias de las que se pueden comparar
+		if len(items) == maxBatchItems {
+			return nil, errors.New("El lote supera el máximo de " + strconv.Itoa(maxBatchItems) + " historias")
+		}
+		id := entry.Name
+		if entry.UncompressedSize64 > maxBatchTextSize {
+			items = append(items, models.BatchItem{ID: id, Error: "el archivo supera el tamaño máximo por historia"})
+			continue
+		}
+		content, err := entry.Open()
+		if err != nil {
+			items = append(items, models.BatchItem{ID: id, Error: "no se pudo leer el archivo"})
+			continue
+		}
+		text, err := io.ReadAll(io.LimitReader(content, maxBatchTextSize))
+		content.Close()
+		if err != nil {
+			items = append(items, models.BatchItem{ID: id, Error: "no se pudo leer el archivo"})
+			continue
+		}
+		total += len(text)
+		if total > maxBatchZipText {
+			return nil, errors.New("El contenido descomprimido del zip supera el tamaño máximo permitido")
+		}
+		items = append(items, batchItemFromText(id, string(text), careerCode))
+	}
+	return items, nil
+}
+
+// batchItemFromText parsea una historia académica en texto plano como elemento del lote
+func batchItemFromText(id, text, careerCode string) models.BatchItem {
+	item := models.BatchItem{ID: id}
+	parsedSubjects, err := parseAcademicHistoryText(preprocessAcademicHistoryText(text))
+	if err != nil {
+		item.Error = "error parseando historia académica: " + err.Error()
+		return item
+	}
+	if len(parsedSubjects) == 0 {
+		item.Error = "no se encontraron materias en la historia académica"
+		return item
+	}
+	item.AcademicHistory = models.AcademicHistoryInput{
+		CareerCode: careerCode,
+		Subjects:   parsedSubjectsToInputs(parsedSubjects),
+	}
+	return item
+}
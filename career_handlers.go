@@ -0,0 +1,226 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/config"
+	"olimpo-vicedecanatura/functions"
+	"olimpo-vicedecanatura/models"
+)
+
+// CareerRequest estructura para la creación o actualización de una carrera
+type CareerRequest struct {
+	Code        string  `json:"code" binding:"required"`
+	Name        string  `json:"name" binding:"required"`
+	Description string  `json:"description"`
+	FacultyCode *string `json:"faculty_code"` // Facultad de la carrera; al actualizar, si se omite se conserva y vacío la quita
+}
+
+// getCareerByCode obtiene una carrera por su código
+func getCareerByCode(c *gin.Context) {
+	career, ok := findCareer(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"career": career,
+	})
+}
+
+// createCareer registra una nueva carrera. Si hay una carrera eliminada con el mismo código se restaura con
+// los datos nuevos, conservando su ID.
+func createCareer(c *gin.Context) {
+	var req CareerRequest
+	if !bindCareerRequest(c, &req) {
+		return
+	}
+	var deleted models.Career
+	if err := config.DB.Unscoped().Where("code = ? AND deleted_at IS NOT NULL", req.Code).Limit(1).Find(&deleted).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando las carreras eliminadas"})
+		return
+	}
+	if careerCodeTaken(c, req.Code, deleted.ID) {
+		return
+	}
+	faculty, ok := careerFaculty(c, req.FacultyCode)
+	if !ok {
+		return
+	}
+
+	career := models.Career{
+		Code:        req.Code,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	setCareerFaculty(&career, faculty)
+	action := functions.AuditActionCreate
+	if deleted.ID != 0 {
+		career.ID, career.CreatedAt = deleted.ID, deleted.CreatedAt
+		action = functions.AuditActionRestore
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Save crea la carrera o, al restaurarla, la actualiza quitando la marca de borrado
+		if err := tx.Unscoped().Omit("Faculty").Save(&career).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), action, functions.AuditEntityCareer, career.ID, career.Code, nil, career)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando la carrera"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"career": career,
+	})
+}
+
+// updateCareer actualiza el código, el nombre, la descripción y la facultad de una carrera
+func updateCareer(c *gin.Context) {
+	career, ok := findCareer(c)
+	if !ok {
+		return
+	}
+
+	var req CareerRequest
+	if !bindCareerRequest(c, &req) {
+		return
+	}
+	if careerCodeTaken(c, req.Code, career.ID) {
+		return
+	}
+	faculty, ok := careerFaculty(c, req.FacultyCode)
+	if !ok {
+		return
+	}
+
+	before := *career
+	career.Code = req.Code
+	career.Name = req.Name
+	career.Description = req.Description
+	if req.FacultyCode != nil {
+		setCareerFaculty(career, faculty)
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Faculty").Save(career).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), functions.AuditActionUpdate, functions.AuditEntityCareer, career.ID, career.Code, before, career)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando la carrera"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusOK, gin.H{
+		"career": career,
+	})
+}
+
+// deleteCareer elimina una carrera que no tenga planes de estudio. El borrado es lógico: las comparaciones
+// guardadas y la auditoría siguen refiriéndose a ella.
+func deleteCareer(c *gin.Context) {
+	career, ok := findCareer(c)
+	if !ok {
+		return
+	}
+
+	var planCount int64
+	if err := config.DB.Model(&models.StudyPlan{}).Where("career_id = ?", career.ID).Count(&planCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando los planes de estudio de la carrera"})
+		return
+	}
+	if planCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "No se puede eliminar la carrera porque tiene planes de estudio asociados"})
+		return
+	}
+
+	before := *career
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(career).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), functions.AuditActionDelete, functions.AuditEntityCareer, career.ID, career.Code, before, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando la carrera"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Carrera eliminada",
+	})
+}
+
+// findCareer obtiene la carrera indicada en la ruta o responde con el error correspondiente
+func findCareer(c *gin.Context) (*models.Career, bool) {
+	var career models.Career
+	if err := config.DB.Preload("Faculty.Sede").Where("code = ?", c.Param("code")).First(&career).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Carrera no encontrada"})
+		return nil, false
+	}
+	return &career, true
+}
+
+// bindCareerRequest lee y normaliza los datos de una carrera, validando el formato del código
+func bindCareerRequest(c *gin.Context, req *CareerRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return false
+	}
+
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	req.Name = strings.TrimSpace(req.Name)
+	if !models.ValidarCodigoCarrera(req.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código de carrera inválido: debe tener entre 2 y 20 letras mayúsculas o dígitos, separados opcionalmente por guiones"})
+		return false
+	}
+	if req.Name == "" || len(req.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El nombre de la carrera debe tener entre 1 y 100 caracteres"})
+		return false
+	}
+	return true
+}
+
+// careerCodeTaken verifica si el código ya lo usa otra carrera y, de ser así, responde con un conflicto
+func careerCodeTaken(c *gin.Context, code string, exceptID uint) bool {
+	// Las carreras eliminadas conservan su código
+	var existing models.Career
+	if err := config.DB.Unscoped().Select("id", "deleted_at").Where("code = ? AND id <> ?", code, exceptID).Limit(1).Find(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando el código de la carrera"})
+		return true
+	}
+	if existing.ID != 0 && existing.DeletedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "El código " + code + " pertenece a una carrera eliminada"})
+		return true
+	}
+	if existing.ID != 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ya existe una carrera con el código " + code})
+		return true
+	}
+	return false
+}
+
+// careerFaculty obtiene la facultad indicada para una carrera; sin código la carrera queda sin facultad
+func careerFaculty(c *gin.Context, code *string) (*models.Faculty, bool) {
+	if code == nil || strings.TrimSpace(*code) == "" {
+		return nil, true
+	}
+	return findFacultyByCode(c, *code)
+}
+
+// setCareerFaculty asigna (o quita, si es nil) la facultad de una carrera
+func setCareerFaculty(career *models.Career, faculty *models.Faculty) {
+	career.Faculty = faculty
+	career.FacultyID = nil
+	if faculty != nil {
+		career.FacultyID = &faculty.ID
+	}
+}
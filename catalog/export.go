@@ -0,0 +1,260 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// ErrCareerNotFound se retorna cuando la carrera a exportar no existe
+var ErrCareerNotFound = errors.New("carrera no encontrada")
+
+// Export arma el archivo de catálogo de una carrera con todas sus versiones de plan. El archivo
+// está en el mismo formato que acepta Import, por lo que exportar e importar no pierde información.
+// Incluye todas las materias que usan los planes, también las de otras carreras que solo aparecen
+// como origen de una equivalencia.
+func Export(db *gorm.DB, careerCode string) (*File, error) {
+	var career models.Career
+	if err := db.Preload("Faculty").Where("code = ?", normalizeCode(careerCode)).First(&career).Error; err != nil {
+		return nil, ErrCareerNotFound
+	}
+
+	var studyPlans []models.StudyPlan
+	if err := db.Preload("Groups.Subjects").Where("career_id = ?", career.ID).
+		Order("effective_from, id").Find(&studyPlans).Error; err != nil {
+		return nil, errors.New("error obteniendo los planes de la carrera")
+	}
+
+	var sedes []models.Sede
+	if err := db.Find(&sedes).Error; err != nil {
+		return nil, errors.New("error obteniendo las sedes")
+	}
+	sedeCodes := make(map[uint]string)
+	for _, sede := range sedes {
+		sedeCodes[sede.ID] = sede.Code
+	}
+
+	file := &File{
+		Career:   CareerEntry{Code: career.Code, Name: career.Name, Description: career.Description},
+		Subjects: []SubjectEntry{},
+		Plans:    []PlanEntry{},
+	}
+	if career.Faculty != nil {
+		file.Career.Faculty = career.Faculty.Code
+	}
+	subjects := make(map[uint]models.Subject)
+	use := func(subject models.Subject) string {
+		subjects[subject.ID] = subject
+		return subject.Code
+	}
+
+	for _, studyPlan := range studyPlans {
+		entry := PlanEntry{
+			Version:       studyPlan.Version,
+			Active:        studyPlan.IsActive,
+			EffectiveFrom: studyPlan.EffectiveFrom,
+			EffectiveTo:   studyPlan.EffectiveTo,
+			Requirements: Requirements{
+				TotalCredits:           studyPlan.TotalCredits,
+				FundObligatoriaCredits: studyPlan.FundObligatoriaCredits,
+				FundOptativaCredits:    studyPlan.FundOptativaCredits,
+				DisObligatoriaCredits:  studyPlan.DisObligatoriaCredits,
+				DisOptativaCredits:     studyPlan.DisOptativaCredits,
+				LibreCredits:           studyPlan.LibreCredits,
+			},
+			Subjects: []PlanSubjectEntry{},
+		}
+		if studyPlan.SedeID != nil {
+			entry.Sede = sedeCodes[*studyPlan.SedeID]
+		}
+
+		// Materias del plan con la tipología y los créditos que tienen en él
+		var links []struct {
+			models.StudyPlanSubject
+			Code string
+		}
+		if err := db.Table("study_plan_subjects").
+			Select("study_plan_subjects.*, subjects.code").
+			Joins("JOIN subjects ON subjects.id = study_plan_subjects.subject_id").
+			Where("study_plan_subjects.study_plan_id = ?", studyPlan.ID).
+			Order("subjects.code").Scan(&links).Error; err != nil {
+			return nil, errors.New("error obteniendo las materias del plan " + studyPlan.Version)
+		}
+		var subjectIDs []uint
+		for _, link := range links {
+			subjectIDs = append(subjectIDs, link.SubjectID)
+			entry.Subjects = append(entry.Subjects, PlanSubjectEntry{
+				Code:    link.Code,
+				Type:    string(link.Type),
+				Credits: link.Credits,
+			})
+		}
+		if len(subjectIDs) > 0 {
+			var planSubjects []models.Subject
+			if err := db.Where("id IN ?", subjectIDs).Find(&planSubjects).Error; err != nil {
+				return nil, errors.New("error obteniendo las materias del plan " + studyPlan.Version)
+			}
+			for _, subject := range planSubjects {
+				use(subject)
+			}
+		}
+
+		groups := append([]models.PlanGroup(nil), studyPlan.Groups...)
+		sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+		for _, group := range groups {
+			groupEntry := GroupEntry{Name: group.Name, Type: string(group.Type), MinCredits: group.MinCredits, Subjects: []string{}}
+			for _, subject := range group.Subjects {
+				groupEntry.Subjects = append(groupEntry.Subjects, use(subject))
+			}
+			sort.Strings(groupEntry.Subjects)
+			entry.Groups = append(entry.Groups, groupEntry)
+		}
+
+		var prerequisites []models.PlanPrerequisite
+		if err := db.Preload("Subject").Preload("Prerequisite").
+			Where("study_plan_id = ?", studyPlan.ID).Find(&prerequisites).Error; err != nil {
+			return nil, errors.New("error obteniendo los prerrequisitos del plan " + studyPlan.Version)
+		}
+		requires := make(map[string][]string)
+		for _, row := range prerequisites {
+			code := use(row.Subject)
+			requires[code] = append(requires[code], use(row.Prerequisite))
+		}
+		for _, code := range sortedKeys(requires) {
+			sort.Strings(requires[code])
+			entry.Prerequisites = append(entry.Prerequisites, PrerequisiteEntry{Subject: code, Requires: requires[code]})
+		}
+
+		equivalences, err := equivalenceHistories(db, studyPlan.ID)
+		if err != nil {
+			return nil, errors.New("error obteniendo las equivalencias del plan " + studyPlan.Version)
+		}
+		for _, decisions := range equivalences {
+			latest := decisions[len(decisions)-1]
+			equivalenceEntry := EquivalenceEntry{
+				Source:              use(latest.SourceSubject),
+				Target:              use(latest.TargetSubject),
+				EquivalenceDecision: equivalenceDecision(latest),
+			}
+			for _, earlier := range decisions[:len(decisions)-1] {
+				equivalenceEntry.History = append(equivalenceEntry.History, equivalenceDecision(earlier))
+			}
+			entry.Equivalences = append(entry.Equivalences, equivalenceEntry)
+		}
+
+		file.Plans = append(file.Plans, entry)
+	}
+
+	var subjectIDs []uint
+	for id := range subjects {
+		subjectIDs = append(subjectIDs, id)
+	}
+	var aliases []models.SubjectAlias
+	if err := db.Where("subject_id IN ?", subjectIDs).Order("alias").Find(&aliases).Error; err != nil {
+		return nil, errors.New("error obteniendo los alias de las materias")
+	}
+	aliasesBySubject := make(map[uint][]AliasEntry)
+	for _, alias := range aliases {
+		aliasesBySubject[alias.SubjectID] = append(aliasesBySubject[alias.SubjectID], AliasEntry{Alias: alias.Alias, Notes: alias.Notes, CreatedBy: alias.CreatedBy})
+	}
+
+	for _, subject := range subjects {
+		entry := SubjectEntry{
+			Code:        subject.Code,
+			Name:        subject.Name,
+			Credits:     subject.Credits,
+			Type:        string(subject.Type),
+			Description: subject.Description,
+		}
+		if subject.SedeID != nil {
+			entry.Sede = sedeCodes[*subject.SedeID]
+		}
+		entry.Aliases = aliasesBySubject[subject.ID]
+		file.Subjects = append(file.Subjects, entry)
+	}
+	sort.Slice(file.Subjects, func(i, j int) bool { return file.Subjects[i].Code < file.Subjects[j].Code })
+	return file, nil
+}
+
+// equivalenceHistories obtiene las equivalencias del plan agrupadas por par de materias, cada grupo con las
+// decisiones de la más antigua a la más reciente. Se omiten los pares con alguna materia eliminada, que no
+// pueden volver a importarse.
+func equivalenceHistories(db *gorm.DB, studyPlanID uint) ([][]models.Equivalence, error) {
+	var equivalences []models.Equivalence
+	if err := db.Preload("SourceSubject").Preload("TargetSubject").
+		Where("study_plan_id = ?", studyPlanID).Order("id").Find(&equivalences).Error; err != nil {
+		return nil, err
+	}
+
+	type pair struct{ source, target uint }
+	byPair := make(map[pair]int)
+	var histories [][]models.Equivalence
+	for _, equivalence := range equivalences {
+		if equivalence.SourceSubject.ID == 0 || equivalence.TargetSubject.ID == 0 {
+			continue
+		}
+		key := pair{equivalence.SourceSubjectID, equivalence.TargetSubjectID}
+		i, exists := byPair[key]
+		if !exists {
+			i = len(histories)
+			byPair[key] = i
+			histories = append(histories, nil)
+		}
+		histories[i] = append(histories[i], equivalence)
+	}
+	sort.Slice(histories, func(i, j int) bool {
+		a, b := histories[i][0], histories[j][0]
+		if a.SourceSubject.Code != b.SourceSubject.Code {
+			return a.SourceSubject.Code < b.SourceSubject.Code
+		}
+		return a.TargetSubject.Code < b.TargetSubject.Code
+	})
+	return histories, nil
+}
+
+// equivalenceDecision arma la decisión del archivo a partir de una equivalencia guardada
+func equivalenceDecision(equivalence models.Equivalence) EquivalenceDecision {
+	decision := EquivalenceDecision{
+		Type:             equivalence.Type,
+		Status:           equivalence.Status,
+		ResolutionNumber: equivalence.ResolutionNumber,
+		Notes:            equivalence.Notes,
+		ProposedBy:       equivalence.ProposedBy,
+		ReviewedBy:       equivalence.ReviewedBy,
+		ReviewNotes:      equivalence.ReviewNotes,
+	}
+	if equivalence.ResolutionDate != nil {
+		decision.ResolutionDate = equivalence.ResolutionDate.Format("2006-01-02")
+	}
+	if equivalence.ReviewedAt != nil {
+		decision.ReviewedAt = equivalence.ReviewedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return decision
+}
+
+// sortedKeys retorna las claves de un mapa en orden
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Marshal escribe un archivo de catálogo en el formato indicado ("json" o "yaml")
+func Marshal(file *File, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(file, "", "  ")
+	case "yaml":
+		return yaml.Marshal(file)
+	default:
+		return nil, errors.New("formato de catálogo no soportado: " + format)
+	}
+}
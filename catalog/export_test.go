@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"olimpo-vicedecanatura/database"
+)
+
+// roundTripFile es un catálogo con todo lo que la exportación debe conservar: alias, materias del plan sin
+// tipología ni créditos propios, agrupaciones, prerrequisitos y equivalencias con sus decisiones anteriores
+func roundTripFile() *File {
+	return &File{
+		Career: CareerEntry{Code: "PRUEBA-RT", Name: "Carrera de prueba"},
+		Subjects: []SubjectEntry{
+			{Code: "9900001", Name: "Materia A", Credits: 4, Type: "FUND. OBLIGATORIA",
+				Aliases: []AliasEntry{{Alias: "9900001-X", Notes: "Código anterior", CreatedBy: "registro"}}},
+			{Code: "9900002", Name: "Materia B", Credits: 3, Type: "DISCIPLINAR OBLIGATORIA"},
+			{Code: "9900003", Name: "Materia C", Credits: 3, Type: "LIBRE ELECCIÓN"},
+			{Code: "9900004", Name: "Materia externa", Credits: 4, Type: "FUND. OBLIGATORIA"},
+		},
+		Plans: []PlanEntry{{
+			Version:       "2024-1",
+			EffectiveFrom: "2024-1",
+			Requirements:  Requirements{TotalCredits: 10, FundObligatoriaCredits: 4, DisObligatoriaCredits: 6},
+			Subjects: []PlanSubjectEntry{
+				{Code: "9900001"},
+				{Code: "9900002", Type: "DISCIPLINAR OPTATIVA"},
+				{Code: "9900003", Credits: 2},
+			},
+			Groups:        []GroupEntry{{Name: "Optativas", Type: "DISCIPLINAR OPTATIVA", MinCredits: 3, Subjects: []string{"9900002"}}},
+			Prerequisites: []PrerequisiteEntry{{Subject: "9900002", Requires: []string{"9900001"}}},
+			Equivalences: []EquivalenceEntry{{
+				Source: "9900004",
+				Target: "9900001",
+				EquivalenceDecision: EquivalenceDecision{Type: "TOTAL", Status: "APROBADA", ResolutionNumber: "025",
+					ResolutionDate: "2024-02-10", ProposedBy: "asesor", ReviewedBy: "comite", ReviewNotes: "Contenidos equivalentes",
+					ReviewedAt: "2024-02-10T15:04:05Z"},
+				History: []EquivalenceDecision{{Type: "TOTAL", Status: "RECHAZADA", ProposedBy: "asesor", ReviewedBy: "comite",
+					ReviewNotes: "Falta el programa", ReviewedAt: "2024-01-20T09:00:00Z"}},
+			}},
+		}},
+	}
+}
+
+func TestMarshalParseRoundTrip(t *testing.T) {
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			want := roundTripFile()
+			data, err := Marshal(want, format)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			got, err := Parse(data, format)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("el archivo cambió al escribirlo y leerlo:\n got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+// TestExportImportRoundTrip importa un catálogo, lo exporta, importa lo exportado y lo vuelve a exportar:
+// ambas exportaciones deben ser iguales al archivo original. Necesita una base de datos PostgreSQL de
+// prueba (OLIMPO_TEST_DSN); los cambios se deshacen al terminar.
+func TestExportImportRoundTrip(t *testing.T) {
+	dsn := os.Getenv("OLIMPO_TEST_DSN")
+	if dsn == "" {
+		t.Skip("OLIMPO_TEST_DSN no está definida")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("conectando a la base de datos: %v", err)
+	}
+	database.RunMigrations(db)
+
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	want := roundTripFile()
+	if _, err := Import(tx, want, Options{Actor: "prueba"}); err != nil {
+		t.Fatalf("primera importación: %v", err)
+	}
+	first, err := Export(tx, want.Career.Code)
+	if err != nil {
+		t.Fatalf("primera exportación: %v", err)
+	}
+	if !reflect.DeepEqual(first, want) {
+		t.Fatalf("la exportación no conserva el archivo importado:\n got %+v\nwant %+v", first, want)
+	}
+
+	report, err := Import(tx, first, Options{Actor: "prueba"})
+	if err != nil {
+		t.Fatalf("segunda importación: %v", err)
+	}
+	if len(report.Changes) > 0 {
+		t.Errorf("importar lo exportado no debería hacer cambios: %v", report.Changes)
+	}
+	second, err := Export(tx, want.Career.Code)
+	if err != nil {
+		t.Fatalf("segunda exportación: %v", err)
+	}
+	if !reflect.DeepEqual(second, first) {
+		t.Errorf("las exportaciones son distintas:\n got %+v\nwant %+v", second, first)
+	}
+}
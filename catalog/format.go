@@ -0,0 +1,396 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"olimpo-vicedecanatura/functions"
+	"olimpo-vicedecanatura/models"
+)
+
+// File es el formato de un archivo de catálogo: una carrera con sus versiones de plan de estudio.
+// Se puede escribir en JSON o en YAML con los mismos nombres de campo.
+//
+//	career:
+//	  code: ISIS
+//	  name: Ingeniería de Sistemas
+//	  faculty: FMINAS
+//	subjects:
+//	  - {code: 1000004-M, name: Cálculo Diferencial, credits: 4, type: FUND. OBLIGATORIA, sede: MED,
+//	     aliases: [{alias: 1000004, notes: Código anterior}]}
+//	plans:
+//	  - version: "2023-1"
+//	    active: true
+//	    sede: MED
+//	    requirements: {total_credits: 160, fund_obligatoria_credits: 40, ...}
+//	    subjects:
+//	      - {code: 1000004-M}
+//	    groups:
+//	      - {name: Matemáticas, type: FUND. OPTATIVA, min_credits: 8, subjects: [1000006-M]}
+//	    prerequisites:
+//	      - {subject: 1000005-M, requires: [1000004-M]}
+//	    equivalences:
+//	      - {source: 2015734, target: 1000004-M, resolution_number: "025", resolution_date: "2023-02-10",
+//	         history: [{status: RECHAZADA, reviewed_by: comite, review_notes: Falta el programa}]}
+type File struct {
+	Career   CareerEntry    `json:"career" yaml:"career"`
+	Subjects []SubjectEntry `json:"subjects" yaml:"subjects"`
+	Plans    []PlanEntry    `json:"plans" yaml:"plans"`
+}
+
+// CareerEntry describe la carrera del archivo
+type CareerEntry struct {
+	Code        string `json:"code" yaml:"code"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Faculty     string `json:"faculty,omitempty" yaml:"faculty,omitempty"` // Código de una facultad registrada; vacío no la cambia
+}
+
+// SubjectEntry describe una materia del catálogo con su tipología y créditos generales
+type SubjectEntry struct {
+	Code        string `json:"code" yaml:"code"`
+	Name        string `json:"name" yaml:"name"`
+	Credits     int    `json:"credits" yaml:"credits"`
+	Type        string `json:"type" yaml:"type"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Sede        string `json:"sede,omitempty" yaml:"sede,omitempty"` // Código de una sede registrada; vacío no la cambia
+	// Otros códigos con los que la materia aparece en las historias académicas; se agregan los que falten
+	Aliases []AliasEntry `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+}
+
+// AliasEntry es otro código de una materia (antiguo, de otra sede o mal digitado)
+type AliasEntry struct {
+	Alias     string `json:"alias" yaml:"alias"`
+	Notes     string `json:"notes,omitempty" yaml:"notes,omitempty"`
+	CreatedBy string `json:"created_by,omitempty" yaml:"created_by,omitempty"`
+}
+
+// PlanEntry describe una versión del plan de estudio. Las materias, agrupaciones y prerrequisitos
+// del archivo reemplazan a los del plan; las equivalencias se agregan o actualizan.
+type PlanEntry struct {
+	Version       string              `json:"version" yaml:"version"`
+	Active        bool                `json:"active" yaml:"active"`
+	EffectiveFrom string              `json:"effective_from,omitempty" yaml:"effective_from,omitempty"`
+	EffectiveTo   string              `json:"effective_to,omitempty" yaml:"effective_to,omitempty"`
+	Sede          string              `json:"sede,omitempty" yaml:"sede,omitempty"` // Sede en la que rige el plan; vacío conserva la registrada (un plan nuevo rige en todas)
+	Requirements  Requirements        `json:"requirements" yaml:"requirements"`
+	Subjects      []PlanSubjectEntry  `json:"subjects" yaml:"subjects"`
+	Groups        []GroupEntry        `json:"groups,omitempty" yaml:"groups,omitempty"`
+	Prerequisites []PrerequisiteEntry `json:"prerequisites,omitempty" yaml:"prerequisites,omitempty"`
+	Equivalences  []EquivalenceEntry  `json:"equivalences,omitempty" yaml:"equivalences,omitempty"`
+}
+
+// Requirements son los créditos exigidos por el plan en cada tipología
+type Requirements struct {
+	TotalCredits           int `json:"total_credits" yaml:"total_credits"`
+	FundObligatoriaCredits int `json:"fund_obligatoria_credits" yaml:"fund_obligatoria_credits"`
+	FundOptativaCredits    int `json:"fund_optativa_credits" yaml:"fund_optativa_credits"`
+	DisObligatoriaCredits  int `json:"dis_obligatoria_credits" yaml:"dis_obligatoria_credits"`
+	DisOptativaCredits     int `json:"dis_optativa_credits" yaml:"dis_optativa_credits"`
+	LibreCredits           int `json:"libre_credits" yaml:"libre_credits"`
+}
+
+// PlanSubjectEntry asocia una materia al plan; la tipología y los créditos son opcionales y,
+// si se omiten, se usan los generales de la materia
+type PlanSubjectEntry struct {
+	Code    string `json:"code" yaml:"code"`
+	Type    string `json:"type,omitempty" yaml:"type,omitempty"`
+	Credits int    `json:"credits,omitempty" yaml:"credits,omitempty"`
+}
+
+// GroupEntry describe una agrupación de materias del plan con su mínimo de créditos
+type GroupEntry struct {
+	Name       string   `json:"name" yaml:"name"`
+	Type       string   `json:"type" yaml:"type"`
+	MinCredits int      `json:"min_credits" yaml:"min_credits"`
+	Subjects   []string `json:"subjects" yaml:"subjects"`
+}
+
+// PrerequisiteEntry indica las materias que se deben aprobar antes de cursar una materia del plan
+type PrerequisiteEntry struct {
+	Subject  string   `json:"subject" yaml:"subject"`
+	Requires []string `json:"requires" yaml:"requires"`
+}
+
+// EquivalenceEntry describe una equivalencia que aplica al plan: la decisión vigente sobre el par de
+// materias y, en History, las anteriores. Si no se indica el estado se importa aprobada.
+type EquivalenceEntry struct {
+	Source              string `json:"source" yaml:"source"`
+	Target              string `json:"target" yaml:"target"`
+	EquivalenceDecision `yaml:",inline"`
+	// Decisiones anteriores sobre el mismo par, de la más antigua a la más reciente (por ejemplo una
+	// propuesta rechazada antes de la aprobada)
+	History []EquivalenceDecision `json:"history,omitempty" yaml:"history,omitempty"`
+}
+
+// EquivalenceDecision son los datos de una decisión sobre una equivalencia. Quién la propuso y los datos
+// de la revisión, si se omiten, no cambian los registrados.
+type EquivalenceDecision struct {
+	Type             string `json:"type,omitempty" yaml:"type,omitempty"`
+	Status           string `json:"status,omitempty" yaml:"status,omitempty"`
+	ResolutionNumber string `json:"resolution_number,omitempty" yaml:"resolution_number,omitempty"`
+	ResolutionDate   string `json:"resolution_date,omitempty" yaml:"resolution_date,omitempty"` // Formato YYYY-MM-DD
+	Notes            string `json:"notes,omitempty" yaml:"notes,omitempty"`
+	ProposedBy       string `json:"proposed_by,omitempty" yaml:"proposed_by,omitempty"`
+	ReviewedBy       string `json:"reviewed_by,omitempty" yaml:"reviewed_by,omitempty"`
+	ReviewNotes      string `json:"review_notes,omitempty" yaml:"review_notes,omitempty"`
+	ReviewedAt       string `json:"reviewed_at,omitempty" yaml:"reviewed_at,omitempty"` // RFC 3339
+}
+
+// Parse lee un archivo de catálogo en el formato indicado ("json" o "yaml")
+func Parse(data []byte, format string) (*File, error) {
+	var file File
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, invalid("el archivo JSON no es válido: " + err.Error())
+		}
+	case "yaml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil {
+			return nil, invalid("el archivo YAML no es válido: " + err.Error())
+		}
+	default:
+		return nil, invalid("formato de catálogo no soportado: " + format)
+	}
+
+	file.normalize()
+	if err := file.Validate(); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// FormatFromName deduce el formato de un archivo de catálogo por su extensión
+func FormatFromName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	default:
+		return "json"
+	}
+}
+
+// normalize quita espacios y pasa a mayúsculas los códigos del archivo
+func (f *File) normalize() {
+	f.Career.Code = normalizeCode(f.Career.Code)
+	f.Career.Name = strings.TrimSpace(f.Career.Name)
+	f.Career.Faculty = normalizeCode(f.Career.Faculty)
+	for i := range f.Subjects {
+		f.Subjects[i].Code = normalizeCode(f.Subjects[i].Code)
+		f.Subjects[i].Name = strings.TrimSpace(f.Subjects[i].Name)
+		f.Subjects[i].Sede = normalizeCode(f.Subjects[i].Sede)
+		for j := range f.Subjects[i].Aliases {
+			// Los alias se guardan en la forma canónica de los códigos de las historias
+			f.Subjects[i].Aliases[j].Alias = functions.NormalizeSubjectCode(f.Subjects[i].Aliases[j].Alias)
+		}
+	}
+	for i := range f.Plans {
+		plan := &f.Plans[i]
+		plan.Version = strings.TrimSpace(plan.Version)
+		plan.Sede = normalizeCode(plan.Sede)
+		for j := range plan.Subjects {
+			plan.Subjects[j].Code = normalizeCode(plan.Subjects[j].Code)
+		}
+		for j := range plan.Groups {
+			for k := range plan.Groups[j].Subjects {
+				plan.Groups[j].Subjects[k] = normalizeCode(plan.Groups[j].Subjects[k])
+			}
+		}
+		for j := range plan.Prerequisites {
+			plan.Prerequisites[j].Subject = normalizeCode(plan.Prerequisites[j].Subject)
+			for k := range plan.Prerequisites[j].Requires {
+				plan.Prerequisites[j].Requires[k] = normalizeCode(plan.Prerequisites[j].Requires[k])
+			}
+		}
+		for j := range plan.Equivalences {
+			plan.Equivalences[j].Source = normalizeCode(plan.Equivalences[j].Source)
+			plan.Equivalences[j].Target = normalizeCode(plan.Equivalences[j].Target)
+		}
+	}
+}
+
+// normalizeCode quita espacios y pasa a mayúsculas un código
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate revisa la estructura del archivo sin consultar la base de datos: códigos y tipologías
+// válidos, elementos repetidos, un solo plan activo por sede y prerrequisitos sin ciclos
+func (f *File) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if !models.ValidarCodigoCarrera(f.Career.Code) {
+		addf("código de carrera inválido: %q", f.Career.Code)
+	}
+	if f.Career.Name == "" {
+		addf("la carrera no tiene nombre")
+	}
+
+	subjectCodes := make(map[string]bool)
+	for _, subject := range f.Subjects {
+		if !models.ValidarCodigoMateria(subject.Code) {
+			addf("código de materia inválido: %q", subject.Code)
+		}
+		if subjectCodes[subject.Code] {
+			addf("la materia %s está repetida", subject.Code)
+		}
+		subjectCodes[subject.Code] = true
+		if subject.Name == "" {
+			addf("la materia %s no tiene nombre", subject.Code)
+		}
+		if subject.Credits <= 0 {
+			addf("la materia %s debe tener créditos mayores que cero", subject.Code)
+		}
+		if !models.ValidarTipologia(subject.Type) {
+			addf("la materia %s tiene una tipología inválida: %q", subject.Code, subject.Type)
+		}
+		for _, alias := range subject.Aliases {
+			if !models.ValidarCodigoMateria(alias.Alias) || alias.Alias == subject.Code {
+				addf("la materia %s tiene un alias inválido: %q", subject.Code, alias.Alias)
+			}
+		}
+	}
+
+	versions := make(map[string]bool)
+	activePlans := make(map[string]int) // Sede -> planes activos; vacía para los que rigen en todas
+	for _, plan := range f.Plans {
+		if plan.Version == "" {
+			addf("hay un plan sin versión")
+		}
+		if versions[plan.Version] {
+			addf("la versión de plan %s está repetida", plan.Version)
+		}
+		versions[plan.Version] = true
+		if plan.Active {
+			activePlans[plan.Sede]++
+		}
+		if plan.EffectiveFrom != "" && !models.ValidarPeriodo(plan.EffectiveFrom) {
+			addf("plan %s: inicio de vigencia inválido: %q", plan.Version, plan.EffectiveFrom)
+		}
+		if plan.EffectiveTo != "" && !models.ValidarPeriodo(plan.EffectiveTo) {
+			addf("plan %s: fin de vigencia inválido: %q", plan.Version, plan.EffectiveTo)
+		}
+
+		inPlan := make(map[string]bool)
+		for _, subject := range plan.Subjects {
+			if inPlan[subject.Code] {
+				addf("plan %s: la materia %s está repetida", plan.Version, subject.Code)
+			}
+			inPlan[subject.Code] = true
+			if subject.Type != "" && !models.ValidarTipologia(subject.Type) {
+				addf("plan %s: la materia %s tiene una tipología inválida: %q", plan.Version, subject.Code, subject.Type)
+			}
+			if subject.Credits < 0 {
+				addf("plan %s: la materia %s tiene créditos negativos", plan.Version, subject.Code)
+			}
+		}
+
+		groupNames := make(map[string]bool)
+		for _, group := range plan.Groups {
+			if group.Name == "" || groupNames[group.Name] {
+				addf("plan %s: la agrupación %q no tiene nombre o está repetida", plan.Version, group.Name)
+			}
+			groupNames[group.Name] = true
+			if !models.ValidarTipologia(group.Type) {
+				addf("plan %s: la agrupación %s tiene una tipología inválida: %q", plan.Version, group.Name, group.Type)
+			}
+			if group.MinCredits <= 0 {
+				addf("plan %s: la agrupación %s debe tener un mínimo de créditos mayor que cero", plan.Version, group.Name)
+			}
+			for _, code := range group.Subjects {
+				if !inPlan[code] {
+					addf("plan %s: la materia %s de la agrupación %s no pertenece al plan", plan.Version, code, group.Name)
+				}
+			}
+		}
+
+		edges := make(map[string][]string)
+		for _, prerequisite := range plan.Prerequisites {
+			for _, code := range append([]string{prerequisite.Subject}, prerequisite.Requires...) {
+				if !inPlan[code] {
+					addf("plan %s: la materia %s de los prerrequisitos no pertenece al plan", plan.Version, code)
+				}
+			}
+			edges[prerequisite.Subject] = append(edges[prerequisite.Subject], prerequisite.Requires...)
+		}
+		if cycle := findCycle(edges); cycle != nil {
+			addf("plan %s: los prerrequisitos forman un ciclo: %s", plan.Version, strings.Join(cycle, " → "))
+		}
+
+		for _, equivalence := range plan.Equivalences {
+			if equivalence.Source == "" || equivalence.Target == "" || equivalence.Source == equivalence.Target {
+				addf("plan %s: la equivalencia %s → %s no es válida", plan.Version, equivalence.Source, equivalence.Target)
+			}
+			for _, decision := range append([]EquivalenceDecision{equivalence.EquivalenceDecision}, equivalence.History...) {
+				if problem := decision.validate(); problem != "" {
+					addf("plan %s: la equivalencia %s → %s %s", plan.Version, equivalence.Source, equivalence.Target, problem)
+				}
+			}
+		}
+	}
+	sedes := make([]string, 0, len(activePlans))
+	for sede := range activePlans {
+		sedes = append(sedes, sede)
+	}
+	sort.Strings(sedes)
+	for _, sede := range sedes {
+		switch {
+		case activePlans[sede] <= 1:
+		case sede == "":
+			addf("solo un plan de la carrera puede estar activo y el archivo marca %d", activePlans[sede])
+		default:
+			addf("solo un plan de la carrera puede estar activo en la sede %s y el archivo marca %d", sede, activePlans[sede])
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validate revisa el tipo y las fechas de una decisión; retorna el problema encontrado o vacío
+func (d EquivalenceDecision) validate() string {
+	if d.Type != "" && d.Type != "TOTAL" && d.Type != "PARCIAL" {
+		return fmt.Sprintf("tiene un tipo inválido: %q", d.Type)
+	}
+	if d.ResolutionDate != "" {
+		if _, err := time.Parse("2006-01-02", d.ResolutionDate); err != nil {
+			return fmt.Sprintf("tiene una fecha de resolución inválida: %q", d.ResolutionDate)
+		}
+	}
+	if d.ReviewedAt != "" {
+		if _, err := time.Parse(time.RFC3339, d.ReviewedAt); err != nil {
+			return fmt.Sprintf("tiene una fecha de revisión inválida: %q", d.ReviewedAt)
+		}
+	}
+	return ""
+}
+
+// ValidationError agrupa los problemas encontrados en un archivo de catálogo
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "el archivo de catálogo no es válido: " + strings.Join(e.Problems, "; ")
+}
+
+// findCycle busca un ciclo en un grafo de prerrequisitos expresado con códigos, con la misma búsqueda
+// que usa el catálogo al agregar prerrequisitos
+func findCycle(edges map[string][]string) []string {
+	return functions.PrerequisiteCycle(edges)
+}
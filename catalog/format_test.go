@@ -0,0 +1,101 @@
+package catalog
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFindCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges map[string][]string
+		want  []string
+	}{
+		{
+			name:  "sin prerrequisitos",
+			edges: map[string][]string{},
+			want:  nil,
+		},
+		{
+			name:  "cadena sin ciclo",
+			edges: map[string][]string{"C": {"B"}, "B": {"A"}},
+			want:  nil,
+		},
+		{
+			name:  "dos caminos al mismo prerrequisito",
+			edges: map[string][]string{"D": {"B", "C"}, "B": {"A"}, "C": {"A"}},
+			want:  nil,
+		},
+		{
+			name:  "materia que se requiere a sí misma",
+			edges: map[string][]string{"A": {"A"}},
+			want:  []string{"A", "A"},
+		},
+		{
+			name:  "ciclo de dos materias",
+			edges: map[string][]string{"A": {"B"}, "B": {"A"}},
+			want:  []string{"A", "B", "A"},
+		},
+		{
+			name:  "ciclo detrás de una materia sin ciclo",
+			edges: map[string][]string{"A": {"B"}, "B": {"C"}, "C": {"D"}, "D": {"B"}},
+			want:  []string{"B", "C", "D", "B"},
+		},
+		{
+			name:  "se reporta el ciclo de la primera materia en orden",
+			edges: map[string][]string{"Z": {"Y"}, "Y": {"Z"}, "M": {"N"}, "N": {"M"}},
+			want:  []string{"M", "N", "M"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findCycle(tt.edges); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findCycle(%v) = %v, want %v", tt.edges, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReportsValidationErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format string
+	}{
+		{"JSON mal formado", `{"career": `, "json"},
+		{"YAML mal formado", "career: [", "yaml"},
+		{"formato desconocido", `{}`, "xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data), tt.format)
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Errorf("Parse(%q, %q) = %v, want *ValidationError", tt.data, tt.format, err)
+			}
+		})
+	}
+}
+
+func TestPrefixError(t *testing.T) {
+	err := prefixError("plan 2023-1: ", invalid("la sede MED no está registrada"))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("prefixError(ValidationError) = %T, want *ValidationError", err)
+	}
+	if want := []string{"plan 2023-1: la sede MED no está registrada"}; !reflect.DeepEqual(validationErr.Problems, want) {
+		t.Errorf("problemas = %v, want %v", validationErr.Problems, want)
+	}
+
+	internal := errors.New("error creando la materia 1000004")
+	err = prefixError("carrera ISIS: ", internal)
+	if errors.As(err, &validationErr) || !errors.Is(err, internal) {
+		t.Errorf("prefixError(%v) = %v, want un error interno que envuelva el original", internal, err)
+	}
+	if got, want := err.Error(), "carrera ISIS: error creando la materia 1000004"; got != want {
+		t.Errorf("prefixError(...).Error() = %q, want %q", got, want)
+	}
+}
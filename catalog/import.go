@@ -0,0 +1,918 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/functions"
+	"olimpo-vicedecanatura/models"
+)
+
+// Counts cuenta los elementos creados, actualizados, sin cambios y eliminados de un tipo
+type Counts struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Deleted   int `json:"deleted"`
+}
+
+// Report resume el resultado de una importación. En modo de prueba (dry run) describe los cambios
+// que se harían sin guardarlos.
+type Report struct {
+	DryRun   bool               `json:"dry_run"`
+	Career   string             `json:"career"`
+	Counts   map[string]*Counts `json:"counts"`   // Por tipo de elemento (ver CountKinds)
+	Changes  []string           `json:"changes"`  // Descripción de cada cambio
+	Warnings []string           `json:"warnings"` // Inconsistencias de planes inactivos, que no impiden la importación
+}
+
+// CountKinds son los tipos de elemento que cuenta el reporte, en el orden en que se importan
+var CountKinds = []string{"careers", "subjects", "aliases", "plans", "plan_subjects", "groups", "prerequisites", "equivalences"}
+
+// Options son las opciones de una importación
+type Options struct {
+	DryRun bool
+	Actor  string // Quien hace la importación; queda como proponente de las equivalencias nuevas y en la auditoría
+	Reason string // Justificación que queda en la auditoría; por defecto "Importación de catálogo"
+}
+
+// errDryRun deshace la transacción de una importación de prueba
+var errDryRun = errors.New("importación de prueba")
+
+// Import aplica un archivo de catálogo dentro de una transacción. Los elementos se identifican por sus
+// claves naturales (códigos de carrera y materia, versión del plan, nombre de agrupación, par de materias
+// de la equivalencia), por lo que importar dos veces el mismo archivo no hace cambios.
+func Import(db *gorm.DB, file *File, opts Options) (*Report, error) {
+	reports, err := ImportAll(db, []*File{file}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return reports[0], nil
+}
+
+// ImportAll aplica varios archivos de catálogo en una sola transacción: si alguno falla no se guarda ninguno.
+// Retorna un reporte por archivo.
+func ImportAll(db *gorm.DB, files []*File, opts Options) ([]*Report, error) {
+	reports := make([]*Report, 0, len(files))
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, file := range files {
+			report := newReport(file, opts)
+			imp := &importer{
+				tx:         tx,
+				report:     report,
+				who:        opts.auditActor(),
+				subjects:   make(map[string]models.Subject),
+				sedes:      make(map[string]uint),
+				planStates: make(map[uint]*functions.StudyPlanAuditState),
+			}
+			if err := imp.run(file); err != nil {
+				if len(files) > 1 {
+					return prefixError("carrera "+file.Career.Code+": ", err)
+				}
+				return err
+			}
+			reports = append(reports, report)
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	if !opts.DryRun {
+		functions.InvalidateCatalog()
+	}
+	return reports, nil
+}
+
+// auditActor es quien queda en la auditoría como autor de los cambios de la importación
+func (opts Options) auditActor() functions.AuditActor {
+	who := functions.AuditActor{Actor: opts.Actor, Reason: opts.Reason}
+	if who.Reason == "" {
+		who.Reason = "Importación de catálogo"
+	}
+	return who
+}
+
+// newReport crea el reporte vacío de la importación de un archivo
+func newReport(file *File, opts Options) *Report {
+	report := &Report{
+		DryRun:   opts.DryRun,
+		Career:   file.Career.Code,
+		Counts:   make(map[string]*Counts),
+		Changes:  []string{},
+		Warnings: []string{},
+	}
+	for _, kind := range CountKinds {
+		report.Counts[kind] = &Counts{}
+	}
+	return report
+}
+
+// importer guarda el estado de una importación en curso
+type importer struct {
+	tx         *gorm.DB
+	report     *Report
+	who        functions.AuditActor
+	career     models.Career
+	subjects   map[string]models.Subject               // Por código, con los datos ya importados
+	sedes      map[string]uint                         // Código de sede -> ID, de las sedes ya consultadas
+	planStates map[uint]*functions.StudyPlanAuditState // Estado de cada plan del archivo antes de importarlo; nil si es nuevo
+}
+
+// record registra un cambio en el reporte
+func (imp *importer) record(kind, action, format string, args ...interface{}) {
+	counts := imp.report.Counts[kind]
+	switch action {
+	case "created":
+		counts.Created++
+	case "updated":
+		counts.Updated++
+	case "deleted":
+		counts.Deleted++
+	default:
+		counts.Unchanged++
+		return
+	}
+	imp.report.Changes = append(imp.report.Changes, fmt.Sprintf(format, args...))
+}
+
+// run importa la carrera, las materias y cada versión del plan, y luego activa y valida los planes
+func (imp *importer) run(file *File) error {
+	if err := imp.importCareer(file.Career); err != nil {
+		return err
+	}
+	for _, entry := range file.Subjects {
+		if err := imp.importSubject(entry); err != nil {
+			return err
+		}
+	}
+	for _, entry := range file.Subjects {
+		if err := imp.importAliases(entry); err != nil {
+			return err
+		}
+	}
+
+	plans := make([]*models.StudyPlan, len(file.Plans))
+	wasActive := make([]bool, len(file.Plans))
+	for i, entry := range file.Plans {
+		plan, active, err := imp.importPlan(entry)
+		if err != nil {
+			return err
+		}
+		plans[i], wasActive[i] = plan, active
+	}
+
+	// Los planes se activan al final, cuando todos los que dejan de estar vigentes ya se desactivaron.
+	// Si el archivo marca un plan activo, los demás planes de la carrera en la misma sede dejan de estarlo.
+	for i, entry := range file.Plans {
+		if !entry.Active {
+			continue
+		}
+		sedeID := uint(0)
+		if plans[i].SedeID != nil {
+			sedeID = *plans[i].SedeID
+		}
+		var others []models.StudyPlan
+		if err := imp.tx.Where("career_id = ? AND id <> ? AND is_active AND COALESCE(sede_id, 0) = ?", imp.career.ID, plans[i].ID, sedeID).Find(&others).Error; err != nil {
+			return errors.New("error obteniendo los planes activos de la carrera")
+		}
+		for j := range others {
+			err := functions.RecordStudyPlanChange(imp.tx, imp.who, functions.AuditActionUpdate, others[j].ID, func() error {
+				if err := imp.tx.Model(&others[j]).Update("is_active", false).Error; err != nil {
+					return fmt.Errorf("plan %s: error desactivando el plan", others[j].Version)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			imp.record("plans", "updated", "plan %s: desactivado porque el archivo activa el plan %s", others[j].Version, entry.Version)
+		}
+	}
+	for i, entry := range file.Plans {
+		if entry.Active {
+			if err := imp.tx.Model(plans[i]).Update("is_active", true).Error; err != nil {
+				return fmt.Errorf("plan %s: error activando el plan", entry.Version)
+			}
+		}
+		if entry.Active != wasActive[i] {
+			imp.record("plans", "updated", "plan %s: activo %t → %t", entry.Version, wasActive[i], entry.Active)
+		}
+	}
+
+	for i, entry := range file.Plans {
+		studyPlan, err := functions.LoadStudyPlanForValidation(imp.tx, plans[i].ID)
+		if err != nil {
+			return err
+		}
+		if err := functions.CheckPlanConflicts(imp.tx, studyPlan); err != nil {
+			return prefixError("plan "+entry.Version+": ", err)
+		}
+		issues := functions.ValidateStudyPlan(*studyPlan)
+		if len(issues) > 0 && entry.Active {
+			return &ValidationError{Problems: prefixAll("plan "+entry.Version+": ", issues)}
+		}
+		imp.report.Warnings = append(imp.report.Warnings, prefixAll("plan "+entry.Version+": ", issues)...)
+	}
+	return imp.auditPlans(plans)
+}
+
+// auditPlans registra en la auditoría los planes del archivo que se crearon o cambiaron, con su estado
+// antes y después de la importación
+func (imp *importer) auditPlans(plans []*models.StudyPlan) error {
+	for _, studyPlan := range plans {
+		before := imp.planStates[studyPlan.ID]
+		after, err := functions.LoadStudyPlanAuditState(imp.tx, studyPlan.ID)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			err = functions.RecordAudit(imp.tx, imp.who, functions.AuditActionCreate, functions.AuditEntityStudyPlan, studyPlan.ID, functions.StudyPlanAuditKey(after), nil, after)
+		} else if !reflect.DeepEqual(before, after) {
+			err = functions.RecordAudit(imp.tx, imp.who, functions.AuditActionUpdate, functions.AuditEntityStudyPlan, studyPlan.ID, functions.StudyPlanAuditKey(after), before, after)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importCareer crea o actualiza la carrera del archivo. Si el archivo no indica la facultad se conserva la registrada.
+func (imp *importer) importCareer(entry CareerEntry) error {
+	var facultyID *uint
+	if entry.Faculty != "" {
+		var faculty models.Faculty
+		if err := imp.tx.Where("code = ?", entry.Faculty).First(&faculty).Error; err != nil {
+			return invalid("la facultad " + entry.Faculty + " no está registrada")
+		}
+		facultyID = &faculty.ID
+	}
+
+	err := imp.tx.Unscoped().Where("code = ?", entry.Code).First(&imp.career).Error
+	if err == nil && imp.career.DeletedAt.Valid {
+		// Una carrera eliminada se restaura con los datos del archivo
+		imp.career.Name = entry.Name
+		imp.career.Description = entry.Description
+		imp.career.FacultyID = facultyID
+		imp.career.DeletedAt = gorm.DeletedAt{}
+		if err := imp.tx.Unscoped().Save(&imp.career).Error; err != nil {
+			return errors.New("error restaurando la carrera " + entry.Code)
+		}
+		imp.record("careers", "created", "carrera %s restaurada", entry.Code)
+		return functions.RecordAudit(imp.tx, imp.who, functions.AuditActionRestore, functions.AuditEntityCareer, imp.career.ID, imp.career.Code, nil, imp.career)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		imp.career = models.Career{Code: entry.Code, Name: entry.Name, Description: entry.Description, FacultyID: facultyID}
+		if err := imp.tx.Create(&imp.career).Error; err != nil {
+			return errors.New("error creando la carrera " + entry.Code)
+		}
+		imp.record("careers", "created", "carrera %s creada", entry.Code)
+		return functions.RecordAudit(imp.tx, imp.who, functions.AuditActionCreate, functions.AuditEntityCareer, imp.career.ID, imp.career.Code, nil, imp.career)
+	}
+	if err != nil {
+		return errors.New("error obteniendo la carrera " + entry.Code)
+	}
+
+	if facultyID == nil {
+		facultyID = imp.career.FacultyID
+	}
+	if imp.career.Name == entry.Name && imp.career.Description == entry.Description && sameID(imp.career.FacultyID, facultyID) {
+		imp.record("careers", "unchanged", "")
+		return nil
+	}
+	before := imp.career
+	imp.career.Name = entry.Name
+	imp.career.Description = entry.Description
+	imp.career.FacultyID = facultyID
+	if err := imp.tx.Save(&imp.career).Error; err != nil {
+		return errors.New("error actualizando la carrera " + entry.Code)
+	}
+	imp.record("careers", "updated", "carrera %s actualizada", entry.Code)
+	return functions.RecordAudit(imp.tx, imp.who, functions.AuditActionUpdate, functions.AuditEntityCareer, imp.career.ID, imp.career.Code, before, imp.career)
+}
+
+// importSubject crea o actualiza una materia del catálogo. Si el archivo no indica la sede se conserva la registrada.
+func (imp *importer) importSubject(entry SubjectEntry) error {
+	sedeID, err := imp.sede(entry.Sede)
+	if err != nil {
+		return err
+	}
+
+	var subject models.Subject
+	err = imp.tx.Unscoped().Where("code = ?", entry.Code).First(&subject).Error
+	if err == nil && subject.DeletedAt.Valid {
+		// Una materia eliminada se restaura con los datos del archivo
+		if sedeID == nil {
+			sedeID = subject.SedeID
+		}
+		subject.Name = entry.Name
+		subject.Credits = entry.Credits
+		subject.Type = models.TipologiaAsignatura(entry.Type)
+		subject.Description = entry.Description
+		subject.SedeID = sedeID
+		subject.DeletedAt = gorm.DeletedAt{}
+		if err := imp.tx.Unscoped().Save(&subject).Error; err != nil {
+			return errors.New("error restaurando la materia " + entry.Code)
+		}
+		imp.subjects[subject.Code] = subject
+		imp.record("subjects", "created", "materia %s restaurada", entry.Code)
+		return functions.RecordAudit(imp.tx, imp.who, functions.AuditActionRestore, functions.AuditEntitySubject, subject.ID, subject.Code, nil, subject)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		subject = models.Subject{
+			Code:        entry.Code,
+			Name:        entry.Name,
+			Credits:     entry.Credits,
+			Type:        models.TipologiaAsignatura(entry.Type),
+			Description: entry.Description,
+			SedeID:      sedeID,
+		}
+		if err := imp.tx.Create(&subject).Error; err != nil {
+			return errors.New("error creando la materia " + entry.Code)
+		}
+		imp.subjects[subject.Code] = subject
+		imp.record("subjects", "created", "materia %s creada", entry.Code)
+		return functions.RecordAudit(imp.tx, imp.who, functions.AuditActionCreate, functions.AuditEntitySubject, subject.ID, subject.Code, nil, subject)
+	}
+	if err != nil {
+		return errors.New("error obteniendo la materia " + entry.Code)
+	}
+
+	if sedeID == nil {
+		sedeID = subject.SedeID
+	}
+	if subject.Name == entry.Name && subject.Credits == entry.Credits &&
+		string(subject.Type) == entry.Type && subject.Description == entry.Description && sameID(subject.SedeID, sedeID) {
+		imp.subjects[subject.Code] = subject
+		imp.record("subjects", "unchanged", "")
+		return nil
+	}
+	before := subject
+	subject.Name = entry.Name
+	subject.Credits = entry.Credits
+	subject.Type = models.TipologiaAsignatura(entry.Type)
+	subject.Description = entry.Description
+	subject.SedeID = sedeID
+	if err := imp.tx.Save(&subject).Error; err != nil {
+		return errors.New("error actualizando la materia " + entry.Code)
+	}
+	imp.subjects[subject.Code] = subject
+	imp.record("subjects", "updated", "materia %s actualizada", entry.Code)
+	return functions.RecordAudit(imp.tx, imp.who, functions.AuditActionUpdate, functions.AuditEntitySubject, subject.ID, subject.Code, before, subject)
+}
+
+// importAliases agrega a la materia los alias del archivo que aún no tiene. Los alias registrados se
+// conservan aunque el archivo no los incluya.
+func (imp *importer) importAliases(entry SubjectEntry) error {
+	subject := imp.subjects[entry.Code]
+	for _, aliasEntry := range entry.Aliases {
+		var existing models.SubjectAlias
+		err := imp.tx.Where("alias = ?", aliasEntry.Alias).First(&existing).Error
+		if err == nil {
+			if existing.SubjectID != subject.ID {
+				return invalid(fmt.Sprintf("materia %s: el alias %s ya está registrado para otra materia", entry.Code, aliasEntry.Alias))
+			}
+			imp.record("aliases", "unchanged", "")
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("error obteniendo el alias " + aliasEntry.Alias)
+		}
+
+		created, err := functions.AddSubjectAlias(imp.tx, subject, aliasEntry.Alias, aliasEntry.Notes, imp.who)
+		var aliasErr *functions.SubjectAliasError
+		var aliasConflictErr *functions.SubjectAliasConflictError
+		if errors.As(err, &aliasErr) || errors.As(err, &aliasConflictErr) {
+			return invalid("materia " + entry.Code + ": " + err.Error())
+		}
+		if err != nil {
+			return fmt.Errorf("materia %s: %v", entry.Code, err)
+		}
+		// Quien registró el alias en el catálogo de origen se conserva
+		if aliasEntry.CreatedBy != "" && aliasEntry.CreatedBy != created.CreatedBy {
+			if err := imp.tx.Model(created).Update("created_by", aliasEntry.CreatedBy).Error; err != nil {
+				return errors.New("error guardando el alias " + aliasEntry.Alias)
+			}
+		}
+		imp.record("aliases", "created", "materia %s: alias %s agregado", entry.Code, aliasEntry.Alias)
+	}
+	return nil
+}
+
+// sede obtiene el ID de una sede registrada por su código; sin código retorna nil
+func (imp *importer) sede(code string) (*uint, error) {
+	if code == "" {
+		return nil, nil
+	}
+	if id, exists := imp.sedes[code]; exists {
+		return &id, nil
+	}
+	var sede models.Sede
+	if err := imp.tx.Where("code = ?", code).First(&sede).Error; err != nil {
+		return nil, invalid("la sede " + code + " no está registrada")
+	}
+	imp.sedes[code] = sede.ID
+	return &sede.ID, nil
+}
+
+// subject obtiene una materia por código, del archivo o de la base de datos
+func (imp *importer) subject(code string) (models.Subject, error) {
+	if subject, exists := imp.subjects[code]; exists {
+		return subject, nil
+	}
+	var subject models.Subject
+	if err := imp.tx.Where("code = ?", code).First(&subject).Error; err != nil {
+		return subject, invalid("la materia " + code + " no está en el archivo ni en el catálogo")
+	}
+	imp.subjects[code] = subject
+	return subject, nil
+}
+
+// importPlan crea o actualiza una versión del plan, dejándola inactiva hasta el final de la importación.
+// Si el archivo no indica la sede se conserva la registrada. Retorna el plan y si estaba activo antes de importar.
+func (imp *importer) importPlan(entry PlanEntry) (*models.StudyPlan, bool, error) {
+	var studyPlan models.StudyPlan
+	requirements := functions.PlanRequirements{
+		TotalCredits:           entry.Requirements.TotalCredits,
+		FundObligatoriaCredits: entry.Requirements.FundObligatoriaCredits,
+		FundOptativaCredits:    entry.Requirements.FundOptativaCredits,
+		DisObligatoriaCredits:  entry.Requirements.DisObligatoriaCredits,
+		DisOptativaCredits:     entry.Requirements.DisOptativaCredits,
+		LibreCredits:           entry.Requirements.LibreCredits,
+	}
+	if issues := functions.ValidateRequirements(requirements); len(issues) > 0 {
+		return nil, false, &ValidationError{Problems: prefixAll("plan "+entry.Version+": ", issues)}
+	}
+	sedeID, err := imp.sede(entry.Sede)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = imp.tx.Where("career_id = ? AND version = ?", imp.career.ID, entry.Version).First(&studyPlan).Error
+	wasActive := false
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		studyPlan = models.StudyPlan{
+			CareerID:      imp.career.ID,
+			SedeID:        sedeID,
+			Version:       entry.Version,
+			EffectiveFrom: entry.EffectiveFrom,
+			EffectiveTo:   entry.EffectiveTo,
+		}
+		functions.ApplyPlanRequirements(&studyPlan, requirements)
+		if err := imp.tx.Create(&studyPlan).Error; err != nil {
+			return nil, false, errors.New("error creando el plan " + entry.Version)
+		}
+		imp.record("plans", "created", "plan %s creado", entry.Version)
+		imp.planStates[studyPlan.ID] = nil
+	} else if err != nil {
+		return nil, false, errors.New("error obteniendo el plan " + entry.Version)
+	} else {
+		state, err := functions.LoadStudyPlanAuditState(imp.tx, studyPlan.ID)
+		if err != nil {
+			return nil, false, err
+		}
+		imp.planStates[studyPlan.ID] = state
+		wasActive = studyPlan.IsActive
+		before := studyPlan
+		if entry.Sede != "" {
+			studyPlan.SedeID = sedeID
+		}
+		studyPlan.EffectiveFrom = entry.EffectiveFrom
+		studyPlan.EffectiveTo = entry.EffectiveTo
+		functions.ApplyPlanRequirements(&studyPlan, requirements)
+		if samePlanSettings(before, studyPlan) {
+			imp.record("plans", "unchanged", "")
+		} else {
+			imp.record("plans", "updated", "plan %s: sede, créditos o vigencia actualizados", entry.Version)
+		}
+	}
+
+	// Los planes quedan inactivos hasta el final de la importación, donde se activan los que marca el archivo
+	studyPlan.IsActive = false
+	if err := imp.tx.Save(&studyPlan).Error; err != nil {
+		return nil, false, errors.New("error guardando el plan " + entry.Version)
+	}
+
+	if err := imp.importPlanSubjects(&studyPlan, entry); err != nil {
+		return nil, false, err
+	}
+	if err := imp.importGroups(&studyPlan, entry); err != nil {
+		return nil, false, err
+	}
+	if err := imp.importPrerequisites(&studyPlan, entry); err != nil {
+		return nil, false, err
+	}
+	if err := imp.importEquivalences(&studyPlan, entry); err != nil {
+		return nil, false, err
+	}
+	return &studyPlan, wasActive, nil
+}
+
+// samePlanSettings indica si dos planes tienen la misma sede, los mismos créditos exigidos y la misma vigencia
+func samePlanSettings(a, b models.StudyPlan) bool {
+	return sameID(a.SedeID, b.SedeID) && a.EffectiveFrom == b.EffectiveFrom && a.EffectiveTo == b.EffectiveTo &&
+		a.TotalCredits == b.TotalCredits && a.FundObligatoriaCredits == b.FundObligatoriaCredits &&
+		a.FundOptativaCredits == b.FundOptativaCredits && a.DisObligatoriaCredits == b.DisObligatoriaCredits &&
+		a.DisOptativaCredits == b.DisOptativaCredits && a.LibreCredits == b.LibreCredits &&
+		a.FoundationalCredits == b.FoundationalCredits && a.DisciplinaryCredits == b.DisciplinaryCredits &&
+		a.ElectiveCreditsPercentage == b.ElectiveCreditsPercentage
+}
+
+// importPlanSubjects deja asociadas al plan exactamente las materias del archivo, con su tipología y créditos
+func (imp *importer) importPlanSubjects(studyPlan *models.StudyPlan, entry PlanEntry) error {
+	var existing []models.StudyPlanSubject
+	if err := imp.tx.Where("study_plan_id = ?", studyPlan.ID).Find(&existing).Error; err != nil {
+		return errors.New("error obteniendo las materias del plan " + entry.Version)
+	}
+	existingBySubject := make(map[uint]models.StudyPlanSubject)
+	for _, link := range existing {
+		existingBySubject[link.SubjectID] = link
+	}
+
+	wanted := make(map[uint]bool)
+	for _, planSubject := range entry.Subjects {
+		subject, err := imp.subject(planSubject.Code)
+		if err != nil {
+			return prefixError("plan "+entry.Version+": ", err)
+		}
+		wanted[subject.ID] = true
+
+		// Sin tipología o sin créditos el plan usa los generales de la materia
+		link := models.StudyPlanSubject{
+			StudyPlanID: studyPlan.ID,
+			SubjectID:   subject.ID,
+			Type:        models.TipologiaAsignatura(planSubject.Type),
+			Credits:     planSubject.Credits,
+		}
+		effectiveType := link.Type
+		if effectiveType == "" {
+			effectiveType = subject.Type
+		}
+
+		current, exists := existingBySubject[subject.ID]
+		switch {
+		case !exists:
+			if err := imp.tx.Create(&link).Error; err != nil {
+				return fmt.Errorf("plan %s: error asociando la materia %s", entry.Version, subject.Code)
+			}
+			imp.record("plan_subjects", "created", "plan %s: materia %s agregada como %s", entry.Version, subject.Code, effectiveType)
+		case current.Type != link.Type || current.Credits != link.Credits:
+			if err := imp.tx.Save(&link).Error; err != nil {
+				return fmt.Errorf("plan %s: error actualizando la materia %s", entry.Version, subject.Code)
+			}
+			effectiveCredits := link.Credits
+			if effectiveCredits == 0 {
+				effectiveCredits = subject.Credits
+			}
+			imp.record("plan_subjects", "updated", "plan %s: materia %s ahora es %s con %d créditos", entry.Version, subject.Code, effectiveType, effectiveCredits)
+		default:
+			imp.record("plan_subjects", "unchanged", "")
+		}
+	}
+
+	for _, link := range existing {
+		if wanted[link.SubjectID] {
+			continue
+		}
+		// La materia puede estar eliminada y seguir en el plan: se busca también entre las eliminadas
+		var subject models.Subject
+		if err := imp.tx.Unscoped().First(&subject, link.SubjectID).Error; err != nil {
+			return fmt.Errorf("plan %s: error obteniendo la materia %d a retirar", entry.Version, link.SubjectID)
+		}
+		if err := functions.DetachSubjectFromPlan(imp.tx, studyPlan.ID, subject); err != nil {
+			return prefixError("plan "+entry.Version+": ", err)
+		}
+		imp.record("plan_subjects", "deleted", "plan %s: materia %s retirada", entry.Version, subject.Code)
+	}
+	return nil
+}
+
+// importGroups deja en el plan exactamente las agrupaciones del archivo, identificadas por nombre
+func (imp *importer) importGroups(studyPlan *models.StudyPlan, entry PlanEntry) error {
+	var existing []models.PlanGroup
+	if err := imp.tx.Preload("Subjects").Where("study_plan_id = ?", studyPlan.ID).Find(&existing).Error; err != nil {
+		return errors.New("error obteniendo las agrupaciones del plan " + entry.Version)
+	}
+	existingByName := make(map[string]*models.PlanGroup)
+	for i := range existing {
+		existingByName[existing[i].Name] = &existing[i]
+	}
+
+	wanted := make(map[string]bool)
+	for _, groupEntry := range entry.Groups {
+		wanted[groupEntry.Name] = true
+		var subjects []models.Subject
+		for _, code := range groupEntry.Subjects {
+			subject, err := imp.subject(code)
+			if err != nil {
+				return prefixError("plan "+entry.Version+": ", err)
+			}
+			subjects = append(subjects, subject)
+		}
+
+		group, exists := existingByName[groupEntry.Name]
+		if !exists {
+			group = &models.PlanGroup{
+				StudyPlanID: studyPlan.ID,
+				Name:        groupEntry.Name,
+				Type:        models.TipologiaAsignatura(groupEntry.Type),
+				MinCredits:  groupEntry.MinCredits,
+				Subjects:    subjects,
+			}
+			if err := imp.tx.Omit("Subjects.*").Create(group).Error; err != nil {
+				return fmt.Errorf("plan %s: error creando la agrupación %s", entry.Version, groupEntry.Name)
+			}
+			imp.record("groups", "created", "plan %s: agrupación %s creada", entry.Version, groupEntry.Name)
+			continue
+		}
+
+		if string(group.Type) == groupEntry.Type && group.MinCredits == groupEntry.MinCredits && sameSubjectCodes(group.Subjects, groupEntry.Subjects) {
+			imp.record("groups", "unchanged", "")
+			continue
+		}
+		group.Type = models.TipologiaAsignatura(groupEntry.Type)
+		group.MinCredits = groupEntry.MinCredits
+		if err := imp.tx.Omit("Subjects").Save(group).Error; err != nil {
+			return fmt.Errorf("plan %s: error actualizando la agrupación %s", entry.Version, groupEntry.Name)
+		}
+		if err := imp.tx.Model(group).Omit("Subjects.*").Association("Subjects").Replace(subjects); err != nil {
+			return fmt.Errorf("plan %s: error actualizando las materias de la agrupación %s", entry.Version, groupEntry.Name)
+		}
+		imp.record("groups", "updated", "plan %s: agrupación %s actualizada", entry.Version, groupEntry.Name)
+	}
+
+	for i := range existing {
+		if wanted[existing[i].Name] {
+			continue
+		}
+		if err := imp.tx.Select("Subjects").Delete(&existing[i]).Error; err != nil {
+			return fmt.Errorf("plan %s: error eliminando la agrupación %s", entry.Version, existing[i].Name)
+		}
+		imp.record("groups", "deleted", "plan %s: agrupación %s eliminada", entry.Version, existing[i].Name)
+	}
+	return nil
+}
+
+// sameSubjectCodes indica si las materias de una agrupación son exactamente los códigos indicados
+func sameSubjectCodes(subjects []models.Subject, codes []string) bool {
+	if len(subjects) != len(codes) {
+		return false
+	}
+	current := make([]string, 0, len(subjects))
+	for _, subject := range subjects {
+		current = append(current, subject.Code)
+	}
+	wanted := append([]string(nil), codes...)
+	sort.Strings(current)
+	sort.Strings(wanted)
+	for i := range current {
+		if current[i] != wanted[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// importPrerequisites deja en el plan exactamente los prerrequisitos del archivo
+func (imp *importer) importPrerequisites(studyPlan *models.StudyPlan, entry PlanEntry) error {
+	var existing []models.PlanPrerequisite
+	if err := imp.tx.Preload("Subject").Preload("Prerequisite").Where("study_plan_id = ?", studyPlan.ID).Find(&existing).Error; err != nil {
+		return errors.New("error obteniendo los prerrequisitos del plan " + entry.Version)
+	}
+	type edge struct{ subjectID, prerequisiteID uint }
+	current := make(map[edge]bool)
+	for _, row := range existing {
+		current[edge{row.SubjectID, row.PrerequisiteID}] = true
+	}
+
+	wanted := make(map[edge]bool)
+	for _, prerequisiteEntry := range entry.Prerequisites {
+		subject, err := imp.subject(prerequisiteEntry.Subject)
+		if err != nil {
+			return prefixError("plan "+entry.Version+": ", err)
+		}
+		for _, code := range prerequisiteEntry.Requires {
+			prerequisite, err := imp.subject(code)
+			if err != nil {
+				return prefixError("plan "+entry.Version+": ", err)
+			}
+			e := edge{subject.ID, prerequisite.ID}
+			if wanted[e] {
+				continue
+			}
+			wanted[e] = true
+			if current[e] {
+				imp.record("prerequisites", "unchanged", "")
+				continue
+			}
+			row := models.PlanPrerequisite{StudyPlanID: studyPlan.ID, SubjectID: subject.ID, PrerequisiteID: prerequisite.ID}
+			if err := imp.tx.Omit("Subject", "Prerequisite").Create(&row).Error; err != nil {
+				return fmt.Errorf("plan %s: error guardando el prerrequisito %s → %s", entry.Version, subject.Code, prerequisite.Code)
+			}
+			imp.record("prerequisites", "created", "plan %s: %s requiere %s", entry.Version, subject.Code, prerequisite.Code)
+		}
+	}
+
+	for _, row := range existing {
+		if wanted[edge{row.SubjectID, row.PrerequisiteID}] {
+			continue
+		}
+		if err := imp.tx.Where("study_plan_id = ? AND subject_id = ? AND prerequisite_id = ?", row.StudyPlanID, row.SubjectID, row.PrerequisiteID).
+			Delete(&models.PlanPrerequisite{}).Error; err != nil {
+			return errors.New("error eliminando prerrequisitos del plan " + entry.Version)
+		}
+		imp.record("prerequisites", "deleted", "plan %s: %s ya no requiere %s", entry.Version, row.Subject.Code, row.Prerequisite.Code)
+	}
+	return nil
+}
+
+// importEquivalences agrega o actualiza las equivalencias del plan, identificadas por el par de materias.
+// La decisión vigente del archivo corresponde a la más reciente guardada y las anteriores (History), en
+// orden, a las demás decisiones del par.
+func (imp *importer) importEquivalences(studyPlan *models.StudyPlan, entry PlanEntry) error {
+	for _, equivalenceEntry := range entry.Equivalences {
+		source, err := imp.subject(equivalenceEntry.Source)
+		if err != nil {
+			return prefixError("plan "+entry.Version+": ", err)
+		}
+		target, err := imp.subject(equivalenceEntry.Target)
+		if err != nil {
+			return prefixError("plan "+entry.Version+": ", err)
+		}
+		key := source.Code + " → " + target.Code
+
+		var current []models.Equivalence
+		if err := imp.tx.Where("source_subject_id = ? AND target_subject_id = ? AND study_plan_id = ?", source.ID, target.ID, studyPlan.ID).
+			Order("id").Find(&current).Error; err != nil {
+			return fmt.Errorf("plan %s: error obteniendo la equivalencia %s", entry.Version, key)
+		}
+
+		if len(current) == 0 {
+			// Las decisiones se crean de la más antigua a la vigente para que esta quede como la más reciente
+			base := models.Equivalence{SourceSubjectID: source.ID, TargetSubjectID: target.ID, StudyPlanID: studyPlan.ID, ProposedBy: imp.who.Actor}
+			for _, decision := range append(append([]EquivalenceDecision(nil), equivalenceEntry.History...), equivalenceEntry.EquivalenceDecision) {
+				if err := imp.createEquivalence(entry.Version, key, base, decision); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		earlier := current[:len(current)-1]
+		for i, decision := range equivalenceEntry.History {
+			if i >= len(earlier) {
+				imp.report.Warnings = append(imp.report.Warnings, fmt.Sprintf("plan %s: la equivalencia %s tiene menos decisiones anteriores que el archivo; la decisión anterior %d no se importa", entry.Version, key, i+1))
+				continue
+			}
+			if err := imp.updateEquivalence(entry.Version, key, earlier[i], decision); err != nil {
+				return err
+			}
+		}
+		if err := imp.updateEquivalence(entry.Version, key, current[len(current)-1], equivalenceEntry.EquivalenceDecision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createEquivalence guarda una decisión del archivo como una equivalencia nueva
+func (imp *importer) createEquivalence(version, key string, base models.Equivalence, decision EquivalenceDecision) error {
+	wanted, err := applyDecision(base, decision)
+	if err != nil {
+		return invalid(fmt.Sprintf("plan %s: la equivalencia %s %v", version, key, err))
+	}
+	if err := imp.tx.Create(&wanted).Error; err != nil {
+		return fmt.Errorf("plan %s: error creando la equivalencia %s", version, key)
+	}
+	imp.record("equivalences", "created", "plan %s: equivalencia %s creada (%s)", version, key, wanted.Status)
+	return functions.RecordAudit(imp.tx, imp.who, functions.AuditActionCreate, functions.AuditEntityEquivalence, wanted.ID, key, nil, wanted)
+}
+
+// updateEquivalence deja una equivalencia guardada con los datos de una decisión del archivo
+func (imp *importer) updateEquivalence(version, key string, current models.Equivalence, decision EquivalenceDecision) error {
+	wanted, err := applyDecision(current, decision)
+	if err != nil {
+		return invalid(fmt.Sprintf("plan %s: la equivalencia %s %v", version, key, err))
+	}
+	if current.Type == wanted.Type && current.Status == wanted.Status && current.ResolutionNumber == wanted.ResolutionNumber &&
+		current.Notes == wanted.Notes && sameDate(current.ResolutionDate, wanted.ResolutionDate) &&
+		current.ProposedBy == wanted.ProposedBy && current.ReviewedBy == wanted.ReviewedBy &&
+		current.ReviewNotes == wanted.ReviewNotes && sameInstant(current.ReviewedAt, wanted.ReviewedAt) {
+		imp.record("equivalences", "unchanged", "")
+		return nil
+	}
+	if err := imp.tx.Model(&current).Updates(map[string]interface{}{
+		"type":              wanted.Type,
+		"status":            wanted.Status,
+		"resolution_number": wanted.ResolutionNumber,
+		"resolution_date":   wanted.ResolutionDate,
+		"notes":             wanted.Notes,
+		"proposed_by":       wanted.ProposedBy,
+		"reviewed_by":       wanted.ReviewedBy,
+		"review_notes":      wanted.ReviewNotes,
+		"reviewed_at":       wanted.ReviewedAt,
+	}).Error; err != nil {
+		return fmt.Errorf("plan %s: error actualizando la equivalencia %s", version, key)
+	}
+	imp.record("equivalences", "updated", "plan %s: equivalencia %s actualizada (%s)", version, key, wanted.Status)
+	wanted.UpdatedAt = current.UpdatedAt
+	return functions.RecordAudit(imp.tx, imp.who, functions.AuditActionUpdate, functions.AuditEntityEquivalence, current.ID, key, current, wanted)
+}
+
+// applyDecision aplica a una equivalencia los datos de una decisión del archivo. Sin tipo la equivalencia
+// es total y sin estado queda aprobada; quién la propuso y los datos de la revisión, si se omiten, se
+// conservan.
+func applyDecision(equivalence models.Equivalence, decision EquivalenceDecision) (models.Equivalence, error) {
+	equivalence.Type = decision.Type
+	equivalence.Status = decision.Status
+	equivalence.ResolutionNumber = decision.ResolutionNumber
+	equivalence.Notes = decision.Notes
+	if equivalence.Type == "" {
+		equivalence.Type = "TOTAL"
+	}
+	if equivalence.Status == "" {
+		equivalence.Status = models.EquivalenceStatusApproved
+	}
+	equivalence.ResolutionDate = nil
+	if decision.ResolutionDate != "" {
+		resolutionDate, err := time.Parse("2006-01-02", decision.ResolutionDate)
+		if err != nil {
+			return equivalence, errors.New("tiene una fecha de resolución inválida")
+		}
+		equivalence.ResolutionDate = &resolutionDate
+	}
+	if decision.ProposedBy != "" {
+		equivalence.ProposedBy = decision.ProposedBy
+	}
+	if decision.ReviewedBy != "" {
+		equivalence.ReviewedBy = decision.ReviewedBy
+	}
+	if decision.ReviewNotes != "" {
+		equivalence.ReviewNotes = decision.ReviewNotes
+	}
+	if decision.ReviewedAt != "" {
+		reviewedAt, err := time.Parse(time.RFC3339, decision.ReviewedAt)
+		if err != nil {
+			return equivalence, errors.New("tiene una fecha de revisión inválida")
+		}
+		equivalence.ReviewedAt = &reviewedAt
+	}
+	return equivalence, nil
+}
+
+// sameDate compara dos fechas opcionales por día
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
+
+// sameInstant compara dos instantes opcionales
+func sameInstant(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// sameID compara dos referencias opcionales
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// invalid crea el error de validación de un único problema del archivo
+func invalid(problem string) error {
+	return &ValidationError{Problems: []string{problem}}
+}
+
+// prefixError antepone un contexto al error. Un *ValidationError lo recibe en cada problema para que
+// siga siendo un error de validación.
+func prefixError(prefix string, err error) error {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return &ValidationError{Problems: prefixAll(prefix, validationErr.Problems)}
+	}
+	return fmt.Errorf("%s%w", prefix, err)
+}
+
+// prefixAll antepone un prefijo a cada mensaje
+func prefixAll(prefix string, messages []string) []string {
+	prefixed := make([]string, 0, len(messages))
+	for _, message := range messages {
+		prefixed = append(prefixed, prefix+message)
+	}
+	return prefixed
+}
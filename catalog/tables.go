@@ -0,0 +1,195 @@
+package catalog
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Table es una hoja del catálogo exportado para revisarlo en una hoja de cálculo
+type Table struct {
+	Name   string
+	Header []string
+	Rows   [][]interface{} // Cada celda es string o int
+}
+
+// TableNames son los nombres de las hojas de Tables, en orden
+var TableNames = []string{"plans", "subjects", "aliases", "plan_subjects", "groups", "prerequisites", "equivalences"}
+
+// Tables convierte un archivo de catálogo en una tabla por tipo de elemento, con la versión del plan
+// en la primera columna de las tablas que dependen de un plan
+func Tables(file *File) []Table {
+	subjectNames := make(map[string]string)
+	subjects := Table{Name: "subjects", Header: []string{"code", "name", "credits", "type", "sede", "description"}}
+	aliases := Table{Name: "aliases", Header: []string{"code", "name", "alias", "notes"}}
+	for _, subject := range file.Subjects {
+		subjectNames[subject.Code] = subject.Name
+		subjects.Rows = append(subjects.Rows, []interface{}{subject.Code, subject.Name, subject.Credits, subject.Type, subject.Sede, subject.Description})
+		for _, alias := range subject.Aliases {
+			aliases.Rows = append(aliases.Rows, []interface{}{subject.Code, subject.Name, alias.Alias, alias.Notes})
+		}
+	}
+
+	plans := Table{Name: "plans", Header: []string{"version", "active", "sede", "effective_from", "effective_to", "total_credits",
+		"fund_obligatoria_credits", "fund_optativa_credits", "dis_obligatoria_credits", "dis_optativa_credits", "libre_credits"}}
+	planSubjects := Table{Name: "plan_subjects", Header: []string{"version", "code", "name", "type", "credits"}}
+	groups := Table{Name: "groups", Header: []string{"version", "group", "type", "min_credits", "code", "name"}}
+	prerequisites := Table{Name: "prerequisites", Header: []string{"version", "code", "name", "requires_code", "requires_name"}}
+	equivalences := Table{Name: "equivalences", Header: []string{"version", "source_code", "source_name", "target_code", "target_name",
+		"type", "status", "resolution_number", "resolution_date", "notes", "proposed_by", "reviewed_by", "review_notes", "reviewed_at"}}
+
+	for _, plan := range file.Plans {
+		r := plan.Requirements
+		plans.Rows = append(plans.Rows, []interface{}{plan.Version, yesNo(plan.Active), plan.Sede, plan.EffectiveFrom, plan.EffectiveTo, r.TotalCredits,
+			r.FundObligatoriaCredits, r.FundOptativaCredits, r.DisObligatoriaCredits, r.DisOptativaCredits, r.LibreCredits})
+		for _, subject := range plan.Subjects {
+			planSubjects.Rows = append(planSubjects.Rows, []interface{}{plan.Version, subject.Code, subjectNames[subject.Code], subject.Type, subject.Credits})
+		}
+		for _, group := range plan.Groups {
+			for _, code := range group.Subjects {
+				groups.Rows = append(groups.Rows, []interface{}{plan.Version, group.Name, group.Type, group.MinCredits, code, subjectNames[code]})
+			}
+		}
+		for _, prerequisite := range plan.Prerequisites {
+			for _, code := range prerequisite.Requires {
+				prerequisites.Rows = append(prerequisites.Rows, []interface{}{plan.Version, prerequisite.Subject, subjectNames[prerequisite.Subject], code, subjectNames[code]})
+			}
+		}
+		for _, e := range plan.Equivalences {
+			equivalences.Rows = append(equivalences.Rows, []interface{}{plan.Version, e.Source, subjectNames[e.Source], e.Target, subjectNames[e.Target],
+				e.Type, e.Status, e.ResolutionNumber, e.ResolutionDate, e.Notes, e.ProposedBy, e.ReviewedBy, e.ReviewNotes, e.ReviewedAt})
+		}
+	}
+	return []Table{plans, subjects, aliases, planSubjects, groups, prerequisites, equivalences}
+}
+
+// yesNo muestra un booleano como se lee en la hoja de cálculo
+func yesNo(value bool) string {
+	if value {
+		return "SI"
+	}
+	return "NO"
+}
+
+// FindTable busca una tabla por nombre
+func FindTable(tables []Table, name string) (Table, error) {
+	for _, table := range tables {
+		if table.Name == name {
+			return table, nil
+		}
+	}
+	return Table{}, errors.New("tabla desconocida: " + name + " (use " + strings.Join(TableNames, ", ") + ")")
+}
+
+// WriteCSV escribe una tabla en CSV con la fila de encabezado
+func WriteCSV(w io.Writer, table Table) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(table.Header); err != nil {
+		return err
+	}
+	for _, row := range table.Rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = fmt.Sprint(cell)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteXLSX escribe las tablas como un libro de Excel con una hoja por tabla. El libro se arma
+// directamente con archive/zip y los XML mínimos del formato SpreadsheetML; los textos van como
+// cadenas en línea para no tener que generar la tabla de cadenas compartidas.
+func WriteXLSX(w io.Writer, tables []Table) error {
+	archive := zip.NewWriter(w)
+
+	var sheets, sheetRels, sheetTypes strings.Builder
+	for i, table := range tables {
+		n := i + 1
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(table.Name), n, n)
+		fmt.Fprintf(&sheetRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+		fmt.Fprintf(&sheetTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+	}
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			sheetTypes.String() + `</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			sheetRels.String() + `</Relationships>`},
+	}
+	for i, table := range tables {
+		parts = append(parts, struct{ name, content string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), worksheetXML(table)})
+	}
+
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// worksheetXML arma el XML de una hoja: el encabezado en la primera fila y luego una fila por registro
+func worksheetXML(table Table) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	header := make([]interface{}, len(table.Header))
+	for i, name := range table.Header {
+		header[i] = name
+	}
+	for r, row := range append([][]interface{}{header}, table.Rows...) {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := columnName(c) + strconv.Itoa(r+1)
+			switch value := cell.(type) {
+			case int:
+				fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, value)
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(fmt.Sprint(value)))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// columnName convierte un índice de columna (desde 0) en su nombre de hoja de cálculo: A, B, ..., Z, AA, ...
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xmlEscape escapa un texto para incluirlo en un XML
+func xmlEscape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
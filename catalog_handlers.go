@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"olimpo-vicedecanatura/catalog"
+	"olimpo-vicedecanatura/config"
+	"olimpo-vicedecanatura/functions"
+)
+
+// Tamaño máximo de un archivo de catálogo
+const maxCatalogFileBytes = 10 << 20
+
+// importCatalog importa un archivo de catálogo (JSON o YAML) con una carrera y sus planes de estudio.
+// Con dry_run=true solo reporta los cambios que haría. Con async=true la importación se encola como
+// trabajo asíncrono y se responde con el trabajo creado; el reporte queda en su resultado.
+func importCatalog(c *gin.Context) {
+	data, format, err := readCatalogFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := catalog.Parse(data, format)
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+
+	who := requestAuditActor(c)
+	dryRun := c.Query("dry_run") == "true"
+	if async, _ := strconv.ParseBool(c.Query("async")); async {
+		payload := catalogImportPayload{
+			Format:  format,
+			Content: string(data),
+			DryRun:  dryRun,
+			Reason:  who.Reason,
+		}
+		job, err := jobQueue.Submit(jobTypeCatalogImport, payload, who.Actor)
+		if err != nil {
+			respondSubmitError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"job": jobResponse(job),
+		})
+		return
+	}
+
+	report, err := catalog.Import(config.DB, file, catalog.Options{
+		DryRun: dryRun,
+		Actor:  who.Actor,
+		Reason: who.Reason,
+	})
+	if err != nil {
+		respondCatalogError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
+
+// exportCatalog exporta una carrera con sus planes de estudio. Los formatos json y yaml son los que
+// acepta la importación; csv entrega una sola tabla (parámetro table) y xlsx un libro con todas.
+// Con async=true el archivo se genera en un trabajo asíncrono y se descarga desde /jobs/:id/download.
+func exportCatalog(c *gin.Context) {
+	payload := catalogReportPayload{
+		CareerCode: c.Param("code"),
+		Format:     c.DefaultQuery("format", "json"),
+		Table:      c.DefaultQuery("table", "plan_subjects"),
+	}
+	if err := payload.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if async, _ := strconv.ParseBool(c.Query("async")); async {
+		job, err := jobQueue.Submit(jobTypeCatalogReport, payload, requestActor(c))
+		if err != nil {
+			respondSubmitError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"job": jobResponse(job),
+		})
+		return
+	}
+
+	report, err := buildCatalogReport(payload)
+	if err != nil {
+		if errors.Is(err, catalog.ErrCareerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Carrera no encontrada"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sendCatalogReport(c, report)
+}
+
+// catalogReport es un archivo generado a partir del catálogo de una carrera
+type catalogReport struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"` // En base64 al guardarse como resultado de un trabajo
+}
+
+// validate revisa el formato y la tabla pedidos antes de generar el archivo
+func (p catalogReportPayload) validate() error {
+	if strings.TrimSpace(p.CareerCode) == "" {
+		return errors.New("Falta el código de la carrera")
+	}
+	switch p.Format {
+	case "json", "yaml", "xlsx":
+		return nil
+	case "csv":
+		_, err := catalog.FindTable(catalog.Tables(&catalog.File{}), p.Table)
+		return err
+	default:
+		return errors.New("Formato no soportado. Usa json, yaml, csv o xlsx.")
+	}
+}
+
+// buildCatalogReport exporta la carrera y arma el archivo en el formato pedido
+func buildCatalogReport(p catalogReportPayload) (*catalogReport, error) {
+	file, err := catalog.Export(config.DB, p.CareerCode)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := "catalogo-" + file.Career.Code
+	switch p.Format {
+	case "json", "yaml":
+		data, err := catalog.Marshal(file, p.Format)
+		if err != nil {
+			return nil, err
+		}
+		contentType := "application/json"
+		if p.Format == "yaml" {
+			contentType = "application/x-yaml"
+		}
+		return &catalogReport{Filename: filename + "." + p.Format, ContentType: contentType, Data: data}, nil
+	case "csv":
+		table, err := catalog.FindTable(catalog.Tables(file), p.Table)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := catalog.WriteCSV(&buf, table); err != nil {
+			return nil, err
+		}
+		return &catalogReport{Filename: filename + "-" + table.Name + ".csv", ContentType: "text/csv; charset=utf-8", Data: buf.Bytes()}, nil
+	case "xlsx":
+		var buf bytes.Buffer
+		if err := catalog.WriteXLSX(&buf, catalog.Tables(file)); err != nil {
+			return nil, err
+		}
+		return &catalogReport{Filename: filename + ".xlsx", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Data: buf.Bytes()}, nil
+	default:
+		return nil, errors.New("Formato no soportado. Usa json, yaml, csv o xlsx.")
+	}
+}
+
+// sendCatalogReport responde con el archivo como descarga
+func sendCatalogReport(c *gin.Context, report *catalogReport) {
+	c.Header("Content-Disposition", `attachment; filename="`+report.Filename+`"`)
+	c.Data(http.StatusOK, report.ContentType, report.Data)
+}
+
+// readCatalogFile lee el archivo de catálogo del cuerpo de la solicitud o del campo file de un formulario
+func readCatalogFile(c *gin.Context) ([]byte, string, error) {
+	contentType := c.GetHeader("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "multipart/form-data"):
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", errors.New("Falta el archivo de catálogo en el campo file")
+		}
+		if fileHeader.Size > maxCatalogFileBytes {
+			return nil, "", errors.New("El archivo de catálogo supera el tamaño máximo permitido")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, "", errors.New("No se pudo leer el archivo de catálogo")
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, "", errors.New("No se pudo leer el archivo de catálogo")
+		}
+		return data, catalog.FormatFromName(fileHeader.Filename), nil
+	case strings.HasPrefix(contentType, "application/json"),
+		strings.HasPrefix(contentType, "application/x-yaml"),
+		strings.HasPrefix(contentType, "application/yaml"),
+		strings.HasPrefix(contentType, "text/yaml"):
+		data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCatalogFileBytes+1))
+		if err != nil {
+			return nil, "", errors.New("No se pudo leer el cuerpo de la solicitud")
+		}
+		if len(data) > maxCatalogFileBytes {
+			return nil, "", errors.New("El archivo de catálogo supera el tamaño máximo permitido")
+		}
+		if strings.HasPrefix(contentType, "application/json") {
+			return data, "json", nil
+		}
+		return data, "yaml", nil
+	default:
+		return nil, "", errors.New("Content-Type no soportado. Usa application/json, application/x-yaml o form-data con el archivo en el campo file.")
+	}
+}
+
+// respondCatalogError responde con los problemas de un archivo de catálogo inválido, con el conflicto entre
+// planes que causaría o con el error interno de la importación
+func respondCatalogError(c *gin.Context, err error) {
+	var validationErr *catalog.ValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "El archivo de catálogo no es válido",
+			"problems": validationErr.Problems,
+		})
+		return
+	}
+	var conflictErr *functions.PlanConflictError
+	if errors.As(err, &conflictErr) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	var periodErr *functions.PlanPeriodError
+	if errors.As(err, &periodErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
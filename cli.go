@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/catalog"
+	"olimpo-vicedecanatura/seeds"
+)
+
+// runCommand ejecuta el subcomando indicado en los argumentos, si lo hay. Retorna false cuando no se
+// indicó ningún subcomando y se debe iniciar el servidor.
+//
+//	go run . import-catalog [-dry-run] catalogo.yaml
+//	go run . export-catalog [-format yaml] [-o catalogo.yaml] ISIS
+//	go run . seed [-dry-run] [-career ISIS]
+func runCommand(db *gorm.DB, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "import-catalog":
+		return true, importCatalogCommand(db, args[1:])
+	case "export-catalog":
+		return true, exportCatalogCommand(db, args[1:])
+	case "seed":
+		return true, seedCommand(db, args[1:])
+	default:
+		return true, fmt.Errorf("subcomando desconocido: %s", args[0])
+	}
+}
+
+// seedCommand importa los datos iniciales incluidos en el binario (paquete seeds) en una sola transacción.
+// Se puede ejecutar varias veces: solo crea o actualiza lo que cambió en los archivos.
+func seedCommand(db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "solo reportar los cambios, sin guardarlos")
+	career := flags.String("career", "", "importar solo la carrera con este código")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	loaded, err := seeds.Load()
+	if err != nil {
+		return err
+	}
+	var files []*catalog.File
+	for _, seed := range loaded {
+		if *career == "" || strings.EqualFold(seed.File.Career.Code, *career) {
+			files = append(files, seed.File)
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no hay datos iniciales para la carrera %s", *career)
+	}
+
+	reports, err := catalog.ImportAll(db, files, catalog.Options{DryRun: *dryRun, Actor: "seed"})
+	if err != nil {
+		return err
+	}
+	for _, report := range reports {
+		fmt.Printf("Carrera %s:\n", report.Career)
+		for _, kind := range catalog.CountKinds {
+			counts := report.Counts[kind]
+			fmt.Printf("  %-14s creados %d, actualizados %d, sin cambios %d, eliminados %d\n",
+				kind, counts.Created, counts.Updated, counts.Unchanged, counts.Deleted)
+		}
+		for _, warning := range report.Warnings {
+			fmt.Printf("  aviso: %s\n", warning)
+		}
+	}
+	if *dryRun {
+		fmt.Println("Modo de prueba: no se guardó ningún cambio")
+	}
+	return nil
+}
+
+// importCatalogCommand importa un archivo de catálogo e imprime el reporte en JSON
+func importCatalogCommand(db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("import-catalog", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "solo reportar los cambios, sin guardarlos")
+	actor := flags.String("actor", "cli", "usuario que realiza la importación")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("uso: import-catalog [-dry-run] [-actor usuario] archivo.(json|yaml)")
+	}
+
+	path := flags.Arg(0)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("no se pudo leer %s: %v", path, err)
+	}
+	file, err := catalog.Parse(data, catalog.FormatFromName(path))
+	if err != nil {
+		return err
+	}
+	report, err := catalog.Import(db, file, catalog.Options{DryRun: *dryRun, Actor: *actor})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// exportCatalogCommand exporta una carrera en el formato indicado, a un archivo o a la salida estándar
+func exportCatalogCommand(db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("export-catalog", flag.ContinueOnError)
+	format := flags.String("format", "", "json, yaml o xlsx (por defecto según la extensión de -o, o json)")
+	output := flags.String("o", "", "archivo de salida (por defecto la salida estándar)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("uso: export-catalog [-format json|yaml|xlsx] [-o archivo] CODIGO_CARRERA")
+	}
+	if *format == "" {
+		*format = "json"
+		if *output != "" {
+			*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+			if *format == "yml" {
+				*format = "yaml"
+			}
+		}
+	}
+
+	file, err := catalog.Export(db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if *format == "xlsx" {
+		var buf bytes.Buffer
+		if err := catalog.WriteXLSX(&buf, catalog.Tables(file)); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	} else if data, err = catalog.Marshal(file, *format); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0o644)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"olimpo-vicedecanatura/config"
+	"olimpo-vicedecanatura/functions"
+	"olimpo-vicedecanatura/models"
+)
+
+// getComparisonRuns lista las comparaciones guardadas, de la más reciente a la más antigua.
+// Admite filtros opcionales por career_code, study_plan_id, created_by, sede (sede con la que se resolvieron
+// los códigos) y faculty (facultad de la carrera), y paginación con limit y offset.
+func getComparisonRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro limit debe estar entre 1 y 200"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro offset es inválido"})
+		return
+	}
+
+	query := config.DB.Model(&models.ComparisonRun{}).
+		Select("id, endpoint, career_code, sede_code, study_plan_id, study_plan_version, engine_version, explain, as_of, rerun_of_id, created_by, created_at")
+	if careerCode := c.Query("career_code"); careerCode != "" {
+		query = query.Where("career_code = ?", careerCode)
+	}
+	if studyPlanID := c.Query("study_plan_id"); studyPlanID != "" {
+		query = query.Where("study_plan_id = ?", studyPlanID)
+	}
+	if createdBy := c.Query("created_by"); createdBy != "" {
+		query = query.Where("created_by = ?", createdBy)
+	}
+	if sede := c.Query("sede"); sede != "" {
+		query = query.Where("sede_code = ?", strings.ToUpper(sede))
+	}
+	if faculty := c.Query("faculty"); faculty != "" {
+		query = query.Where("career_code IN (SELECT careers.code FROM careers JOIN faculties ON faculties.id = careers.faculty_id WHERE faculties.code = ?)",
+			strings.ToUpper(faculty))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo comparaciones"})
+		return
+	}
+
+	var runs []models.ComparisonRun
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo comparaciones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comparison_runs": runs,
+		"total":           total,
+		"limit":           limit,
+		"offset":          offset,
+	})
+}
+
+// getComparisonRun obtiene una comparación guardada con su entrada y el resultado exacto que se obtuvo
+func getComparisonRun(c *gin.Context) {
+	runID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de comparación inválido"})
+		return
+	}
+
+	run, err := functions.GetComparisonRun(config.DB, uint(runID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comparisonRunResponse(run))
+}
+
+// rerunComparison vuelve a ejecutar una comparación guardada con el catálogo actual.
+// Con ?use_active_plan=true se compara contra el plan activo de la carrera. Con ?as_of= se usa el catálogo
+// de una fecha, y con ?as_of=original el catálogo con el que se hizo la comparación original.
+func rerunComparison(c *gin.Context) {
+	runID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de comparación inválido"})
+		return
+	}
+	var rerun functions.RerunOptions
+	var ok bool
+	rerun.UseActivePlan, _ = strconv.ParseBool(c.Query("use_active_plan"))
+	if c.Query("as_of") == "original" {
+		rerun.OriginalCatalog = true
+	} else if rerun.AsOf, ok = parseAsOf(c); !ok {
+		return
+	}
+
+	run, _, warnings, err := functions.RerunComparison(config.DB, uint(runID), rerun, requestActor(c))
+	if err != nil {
+		var beforeAudit *functions.AsOfBeforeAuditError
+		switch {
+		case errors.Is(err, functions.ErrComparisonRunNotFound), errors.Is(err, functions.ErrStudyPlanNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.As(err, &beforeAudit):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	response := comparisonRunResponse(run)
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// comparisonRunResponse arma la respuesta de una comparación guardada sin volver a serializar su contenido
+func comparisonRunResponse(run *models.ComparisonRun) gin.H {
+	return gin.H{
+		"comparison_run": gin.H{
+			"id":                 run.ID,
+			"endpoint":           run.Endpoint,
+			"career_code":        run.CareerCode,
+			"study_plan_id":      run.StudyPlanID,
+			"study_plan_version": run.StudyPlanVersion,
+			"engine_version":     run.EngineVersion,
+			"explain":            run.Explain,
+			"rerun_of_id":        run.RerunOfID,
+			"as_of":              run.AsOf,
+			"created_by":         run.CreatedBy,
+			"created_at":         run.CreatedAt,
+		},
+		"academic_history":  json.RawMessage(run.InputSnapshot),
+		"comparison_result": json.RawMessage(run.Result),
+	}
+}
+
+// diffComparisonRuns compara dos comparaciones guardadas (?from=ID&to=ID) y lista lo que cambió entre ellas
+func diffComparisonRuns(c *gin.Context) {
+	fromID, err := strconv.ParseUint(c.Query("from"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro from debe ser un ID de comparación válido"})
+		return
+	}
+	toID, err := strconv.ParseUint(c.Query("to"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro to debe ser un ID de comparación válido"})
+		return
+	}
+
+	fromRun, err := functions.GetComparisonRun(config.DB, uint(fromID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	toRun, err := functions.GetComparisonRun(config.DB, uint(toID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	fromHistory, fromResult, err := functions.DecodeComparisonRun(fromRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	toHistory, toResult, err := functions.DecodeComparisonRun(toRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	diff := functions.DiffComparisonResults(fromResult, toResult)
+
+	c.JSON(http.StatusOK, gin.H{
+		"from": gin.H{
+			"id":                 fromRun.ID,
+			"study_plan_id":      fromRun.StudyPlanID,
+			"study_plan_version": fromRun.StudyPlanVersion,
+			"engine_version":     fromRun.EngineVersion,
+			"created_at":         fromRun.CreatedAt,
+		},
+		"to": gin.H{
+			"id":                 toRun.ID,
+			"study_plan_id":      toRun.StudyPlanID,
+			"study_plan_version": toRun.StudyPlanVersion,
+			"engine_version":     toRun.EngineVersion,
+			"created_at":         toRun.CreatedAt,
+		},
+		"same_study_plan":       fromRun.StudyPlanID == toRun.StudyPlanID,
+		"same_academic_history": reflect.DeepEqual(fromHistory, toHistory),
+		"same_engine_version":   fromRun.EngineVersion == toRun.EngineVersion,
+		"diff":                  diff,
+		"summary": gin.H{
+			"status_changes":      len(diff.StatusChanges),
+			"added_subjects":      len(diff.AddedSubjects),
+			"removed_subjects":    len(diff.RemovedSubjects),
+			"equivalence_changes": len(diff.EquivalenceChanges),
+			"completed_delta":     toResult.CreditsSummary.Total.Completed - fromResult.CreditsSummary.Total.Completed,
+		},
+	})
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"strconv"
+)
+
+// BatchWorkers obtiene el número de workers para las comparaciones en lote (BATCH_WORKERS, por defecto 4)
+func BatchWorkers() int {
+	workers, err := strconv.Atoi(getEnv("BATCH_WORKERS", "4"))
+	if err != nil || workers <= 0 {
+		return 4
+	}
+	return workers
+}
+
+// JobWorkers obtiene el número de workers de la cola de trabajos asíncronos (JOB_WORKERS, por defecto 2)
+func JobWorkers() int {
+	workers, err := strconv.Atoi(getEnv("JOB_WORKERS", "2"))
+	if err != nil || workers <= 0 {
+		return 2
+	}
+	return workers
+}
@@ -2,24 +2,54 @@ package database
 
 import (
 	"log"
+	"time"
 
 	"gorm.io/gorm"
+	"olimpo-vicedecanatura/functions"
 	"olimpo-vicedecanatura/models"
 )
 
 // RunMigrations ejecuta las migraciones de la base de datos
 func RunMigrations(db *gorm.DB) {
+	// La asociación plan-materia guarda la tipología y los créditos propios de cada plan
+	if err := db.SetupJoinTable(&models.StudyPlan{}, "Subjects", &models.StudyPlanSubject{}); err != nil {
+		log.Fatalf("Error configurando la tabla study_plan_subjects: %v", err)
+	}
+	if err := db.SetupJoinTable(&models.Subject{}, "StudyPlans", &models.StudyPlanSubject{}); err != nil {
+		log.Fatalf("Error configurando la tabla study_plan_subjects: %v", err)
+	}
+
+	// Se consulta antes de crear las tablas para saber si la copia de prerrequisitos ya se hacía al arrancar
+	copyDone := db.Migrator().HasTable(&models.PlanPrerequisite{})
+
 	// Auto-migrar los modelos
 	err := db.AutoMigrate(
+		&models.Sede{},
+		&models.Faculty{},
 		&models.Career{},
 		&models.StudyPlan{},
 		&models.Subject{},
 		&models.Equivalence{},
+		&models.PlanGroup{},
+		&models.PlanPrerequisite{},
+		&models.SubjectAlias{},
+		&models.AuditLog{},
+		&models.ComparisonRun{},
+		&models.Job{},
+		&models.SchemaMigration{},
 	)
 	if err != nil {
 		log.Fatalf("Error ejecutando migraciones: %v", err)
 	}
 
+	// La auditoría del catálogo cubre desde su primer registro (o desde ahora, si aún no tiene): el catálogo
+	// de una fecha anterior no puede reconstruirse
+	if err := db.Exec(`INSERT INTO schema_migrations (name, applied_at)
+		SELECT ?, COALESCE(MIN(created_at), NOW()) FROM audit_logs
+		ON CONFLICT DO NOTHING;`, functions.AuditCoverageMigration).Error; err != nil {
+		log.Printf("Error registrando el inicio de la auditoría: %v", err)
+	}
+
 	// Crear índices adicionales si son necesarios
 	// Por ejemplo, para búsquedas frecuentes por código de materia
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_subjects_code ON subjects(code);").Error; err != nil {
@@ -29,13 +59,99 @@ func RunMigrations(db *gorm.DB) {
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_careers_code ON careers(code);").Error; err != nil {
 		log.Printf("Error creando índice: %v", err)
 	}
+
+	// is_active dejó de tener valor por defecto true: los planes se crean inactivos
+	if err := db.Exec("UPDATE study_plans SET is_active = false WHERE is_active IS NULL;").Error; err != nil {
+		log.Printf("Error completando el estado de los planes: %v", err)
+	}
+
+	// Solo puede haber un plan activo por carrera en cada sede (los planes sin sede rigen en todas):
+	// si hay varios se conserva el de menor ID, que es el que usaban las comparaciones
+	deactivateDuplicateActivePlans(db)
+	if err := db.Exec("DROP INDEX IF EXISTS idx_study_plans_one_active;").Error; err != nil {
+		log.Printf("Error eliminando índice: %v", err)
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_study_plans_one_active_sede ON study_plans(career_id, COALESCE(sede_id, 0)) WHERE is_active;").Error; err != nil {
+		log.Printf("Error creando índice: %v", err)
+	}
+
+	// Los prerrequisitos generales de subject_prerequisites se copian una sola vez a los planes. Si la tabla
+	// plan_prerequisites ya existía, la copia ya se hizo en arranques anteriores y no se repite: los
+	// prerrequisitos que se hayan quitado de un plan no deben volver.
+	runOnce(db, "copy_subject_prerequisites_to_plans", func(tx *gorm.DB) error {
+		if copyDone || !tx.Migrator().HasTable("subject_prerequisites") {
+			return nil
+		}
+		who := functions.AuditActor{Actor: "sistema", Reason: "Copia de los prerrequisitos generales a los planes"}
+		copied, skipped, err := functions.CopyLegacyPrerequisites(tx, who)
+		if err != nil {
+			return err
+		}
+		for _, description := range skipped {
+			log.Printf("Prerrequisito general omitido: %s", description)
+		}
+		log.Printf("Se copiaron %d prerrequisitos generales a los planes", copied)
+		return nil
+	})
+}
+
+// deactivateDuplicateActivePlans desactiva los planes activos que sobran en cada carrera y sede, dejando el
+// cambio en la auditoría para que el catálogo de fechas anteriores siga reconstruyéndose bien
+func deactivateDuplicateActivePlans(db *gorm.DB) {
+	var duplicates []uint
+	if err := db.Raw(`SELECT sp.id FROM study_plans sp
+		WHERE sp.is_active AND EXISTS (
+			SELECT 1 FROM study_plans other
+			WHERE other.career_id = sp.career_id AND COALESCE(other.sede_id, 0) = COALESCE(sp.sede_id, 0)
+				AND other.is_active AND other.id < sp.id
+		)
+		ORDER BY sp.id;`).Scan(&duplicates).Error; err != nil {
+		log.Printf("Error buscando planes activos duplicados: %v", err)
+		return
+	}
+
+	who := functions.AuditActor{Actor: "sistema", Reason: "La carrera tenía otro plan activo en la misma sede"}
+	for _, studyPlanID := range duplicates {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return functions.RecordStudyPlanChange(tx, who, functions.AuditActionUpdate, studyPlanID, func() error {
+				return tx.Model(&models.StudyPlan{}).Where("id = ?", studyPlanID).Update("is_active", false).Error
+			})
+		})
+		if err != nil {
+			log.Printf("Error desactivando el plan de estudio %d: %v", studyPlanID, err)
+		}
+	}
+	if len(duplicates) > 0 {
+		log.Printf("Se desactivaron %d planes de estudio porque su carrera tenía otro plan activo", len(duplicates))
+	}
+}
+
+// runOnce ejecuta una migración de datos si no se ha aplicado antes. La migración y su registro se guardan
+// en la misma transacción: si falla, se intentará de nuevo en el próximo arranque.
+func runOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.SchemaMigration{}).Where("name = ?", name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		if err := migrate(tx); err != nil {
+			return err
+		}
+		return tx.Create(&models.SchemaMigration{Name: name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		log.Printf("Error en la migración %s: %v", name, err)
+	}
 }
 
 // SeedInitialData inserta datos iniciales en la base de datos
 func SeedInitialData(db *gorm.DB) {
 	// Verificar si ya existen datos
 	var count int64
-	db.Model(&models.Career{}).Count(&count)
+	db.Unscoped().Model(&models.Career{}).Count(&count)
 	if count > 0 {
 		log.Println("La base de datos ya contiene datos iniciales")
 		return
@@ -56,9 +172,16 @@ func SeedInitialData(db *gorm.DB) {
 		// Agregar más carreras según sea necesario
 	}
 
-	// Insertar carreras
+	// Insertar carreras, auditadas como cualquier otro cambio para que el catálogo de una fecha pasada las incluya
+	who := functions.AuditActor{Actor: "sistema", Reason: "Datos iniciales"}
 	for _, career := range careers {
-		if err := db.Create(&career).Error; err != nil {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&career).Error; err != nil {
+				return err
+			}
+			return functions.RecordAudit(tx, who, functions.AuditActionCreate, functions.AuditEntityCareer, career.ID, career.Code, nil, career)
+		})
+		if err != nil {
 			log.Printf("Error creando carrera %s: %v", career.Name, err)
 		}
 	}
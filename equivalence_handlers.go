@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/config"
+	"olimpo-vicedecanatura/functions"
+	"olimpo-vicedecanatura/models"
+)
+
+// ProposeEquivalenceRequest estructura para proponer una equivalencia
+type ProposeEquivalenceRequest struct {
+	SourceSubjectCode string `json:"source_subject_code" binding:"required"`
+	TargetSubjectCode string `json:"target_subject_code" binding:"required"`
+	StudyPlanID       uint   `json:"study_plan_id" binding:"required"`
+	Type              string `json:"type"` // TOTAL (por defecto) o PARCIAL
+	Notes             string `json:"notes"`
+}
+
+// EquivalenceDecisionRequest estructura para las decisiones del comité sobre una equivalencia
+type EquivalenceDecisionRequest struct {
+	ResolutionNumber string `json:"resolution_number"`
+	ResolutionDate   string `json:"resolution_date"` // Formato YYYY-MM-DD
+	Notes            string `json:"notes"`
+}
+
+// getEquivalences lista equivalencias con filtros opcionales por status, study_plan_id y subject_code
+func getEquivalences(c *gin.Context) {
+	query := config.DB.Preload("SourceSubject", withDeleted).Preload("TargetSubject", withDeleted)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if studyPlanParam := c.Query("study_plan_id"); studyPlanParam != "" {
+		studyPlanID, err := strconv.ParseUint(studyPlanParam, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de plan de estudio inválido"})
+			return
+		}
+		query = query.Where("study_plan_id = ?", uint(studyPlanID))
+	}
+	if subjectCode := c.Query("subject_code"); subjectCode != "" {
+		query = query.Where("(source_subject_id IN (SELECT id FROM subjects WHERE code = @code) OR target_subject_id IN (SELECT id FROM subjects WHERE code = @code))",
+			map[string]interface{}{"code": subjectCode})
+	}
+
+	var equivalences []models.Equivalence
+	if err := query.Order("id").Find(&equivalences).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo equivalencias"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"equivalences": equivalences,
+	})
+}
+
+// getEquivalence obtiene una equivalencia con sus materias
+func getEquivalence(c *gin.Context) {
+	equivalenceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de equivalencia inválido"})
+		return
+	}
+
+	var equivalence models.Equivalence
+	if err := config.DB.Preload("SourceSubject", withDeleted).Preload("TargetSubject", withDeleted).First(&equivalence, uint(equivalenceID)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Equivalencia no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"equivalence": equivalence,
+	})
+}
+
+// proposeEquivalence registra una equivalencia propuesta, pendiente de decisión del comité
+func proposeEquivalence(c *gin.Context) {
+	var req ProposeEquivalenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+
+	equivalenceType := strings.ToUpper(strings.TrimSpace(req.Type))
+	if equivalenceType == "" {
+		equivalenceType = "TOTAL"
+	}
+	if equivalenceType != "TOTAL" && equivalenceType != "PARCIAL" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo de equivalencia inválido: debe ser TOTAL o PARCIAL"})
+		return
+	}
+
+	var source, target models.Subject
+	if err := config.DB.Where("code = ?", req.SourceSubjectCode).First(&source).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Materia origen no encontrada: " + req.SourceSubjectCode})
+		return
+	}
+	if err := config.DB.Where("code = ?", req.TargetSubjectCode).First(&target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Materia destino no encontrada: " + req.TargetSubjectCode})
+		return
+	}
+	var studyPlan models.StudyPlan
+	if err := config.DB.First(&studyPlan, req.StudyPlanID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plan de estudio no encontrado"})
+		return
+	}
+
+	equivalence := models.Equivalence{
+		SourceSubjectID: source.ID,
+		TargetSubjectID: target.ID,
+		Type:            equivalenceType,
+		Notes:           req.Notes,
+		StudyPlanID:     studyPlan.ID,
+		ProposedBy:      requestActor(c),
+	}
+	if err := functions.ProposeEquivalence(config.DB, &equivalence, requestAuditActor(c)); err != nil {
+		switch {
+		case errors.Is(err, functions.ErrEquivalenceExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, functions.ErrEquivalenceSameSubject):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, functions.ErrStudyPlanNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	equivalence.SourceSubject = source
+	equivalence.TargetSubject = target
+
+	c.JSON(http.StatusCreated, gin.H{
+		"equivalence": equivalence,
+	})
+}
+
+// decideEquivalence retorna el handler de una acción del flujo de aprobación (review, approve, reject, retire)
+func decideEquivalence(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		equivalenceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de equivalencia inválido"})
+			return
+		}
+
+		// El cuerpo es opcional para pasar a revisión
+		var req EquivalenceDecisionRequest
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+			return
+		}
+
+		decision := functions.EquivalenceDecision{
+			ResolutionNumber: req.ResolutionNumber,
+			Notes:            req.Notes,
+			ReviewedBy:       requestActor(c),
+		}
+		if req.ResolutionDate != "" {
+			resolutionDate, err := time.Parse("2006-01-02", req.ResolutionDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Fecha de resolución inválida, use el formato YYYY-MM-DD"})
+				return
+			}
+			decision.ResolutionDate = &resolutionDate
+		}
+
+		equivalence, err := functions.DecideEquivalence(config.DB, uint(equivalenceID), action, decision, requestAuditActor(c))
+		var decisionErr *functions.EquivalenceDecisionError
+		var stateErr *functions.EquivalenceStateError
+		switch {
+		case errors.Is(err, functions.ErrEquivalenceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case errors.As(err, &decisionErr):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case errors.As(err, &stateErr):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"equivalence": equivalence,
+		})
+	}
+}
+
+// deleteEquivalence elimina una equivalencia que no esté aprobada
+func deleteEquivalence(c *gin.Context) {
+	equivalenceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de equivalencia inválido"})
+		return
+	}
+
+	err = functions.DeleteEquivalence(config.DB, uint(equivalenceID), requestAuditActor(c))
+	var stateErr *functions.EquivalenceStateError
+	switch {
+	case errors.Is(err, functions.ErrEquivalenceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.As(err, &stateErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Equivalencia eliminada",
+	})
+}
+
+// withDeleted incluye en una precarga las filas con borrado lógico, como las materias eliminadas de las
+// equivalencias ya rechazadas o retiradas
+func withDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// SuggestEquivalencesRequest estructura para registrar como propuestas las equivalencias sugeridas entre dos planes
+type SuggestEquivalencesRequest struct {
+	SourceStudyPlanID uint    `json:"source_study_plan_id" binding:"required"`
+	TargetStudyPlanID uint    `json:"target_study_plan_id" binding:"required"`
+	MinScore          float64 `json:"min_score"` // Entre 0 y 1; por defecto functions.DefaultSuggestionMinScore
+}
+
+// getEquivalenceSuggestions sugiere equivalencias entre dos planes por similitud de nombre y créditos,
+// sin registrarlas
+func getEquivalenceSuggestions(c *gin.Context) {
+	sourceStudyPlanID, err := strconv.ParseUint(c.Query("source_plan_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de plan de origen inválido"})
+		return
+	}
+	targetStudyPlanID, err := strconv.ParseUint(c.Query("target_plan_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de plan destino inválido"})
+		return
+	}
+	minScore := 0.0
+	if minScoreParam := c.Query("min_score"); minScoreParam != "" {
+		minScore, err = strconv.ParseFloat(minScoreParam, 64)
+		if err != nil || minScore < 0 || minScore > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Puntaje mínimo inválido: debe estar entre 0 y 1"})
+			return
+		}
+	}
+
+	suggestions, err := functions.SuggestPlanEquivalences(config.DB, uint(sourceStudyPlanID), uint(targetStudyPlanID), minScore)
+	if err != nil {
+		respondSuggestionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": suggestions,
+	})
+}
+
+// proposeEquivalenceSuggestions registra como propuestas las equivalencias sugeridas entre dos planes. Quedan
+// pendientes de decisión del comité como cualquier otra propuesta.
+func proposeEquivalenceSuggestions(c *gin.Context) {
+	var req SuggestEquivalencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+	if req.MinScore < 0 || req.MinScore > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Puntaje mínimo inválido: debe estar entre 0 y 1"})
+		return
+	}
+
+	proposed, skipped, err := functions.ProposeSuggestedEquivalences(config.DB, req.SourceStudyPlanID, req.TargetStudyPlanID, req.MinScore, requestAuditActor(c))
+	if err != nil {
+		respondSuggestionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"equivalences": proposed,
+		"skipped":      skipped,
+	})
+}
+
+// respondSuggestionError responde los errores de las sugerencias de equivalencias: planes inválidos o
+// inexistentes son errores de la solicitud y el resto son fallas internas
+func respondSuggestionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, functions.ErrSuggestionPlans):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, functions.ErrStudyPlanNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
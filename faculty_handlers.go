@@ -0,0 +1,222 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/config"
+	"olimpo-vicedecanatura/functions"
+	"olimpo-vicedecanatura/models"
+)
+
+// FacultyRequest estructura para la creación o actualización de una facultad
+type FacultyRequest struct {
+	Code     string `json:"code" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	SedeCode string `json:"sede_code" binding:"required"`
+}
+
+// getFaculties lista las facultades, con filtro opcional por sede (código)
+func getFaculties(c *gin.Context) {
+	query := config.DB.Preload("Sede").Order("code")
+	if sede := c.Query("sede"); sede != "" {
+		query = query.Where("sede_id IN (SELECT id FROM sedes WHERE code = ?)", strings.ToUpper(sede))
+	}
+
+	var faculties []models.Faculty
+	if err := query.Find(&faculties).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo facultades"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"faculties": faculties,
+	})
+}
+
+// getFacultyByCode obtiene una facultad por su código, con su sede y sus carreras
+func getFacultyByCode(c *gin.Context) {
+	faculty, ok := findFaculty(c)
+	if !ok {
+		return
+	}
+
+	var careers []models.Career
+	if err := config.DB.Where("faculty_id = ?", faculty.ID).Order("code").Find(&careers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo las carreras de la facultad"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"faculty": faculty,
+		"careers": careers,
+	})
+}
+
+// createFaculty registra una nueva facultad en una sede
+func createFaculty(c *gin.Context) {
+	var req FacultyRequest
+	if !bindFacultyRequest(c, &req) {
+		return
+	}
+	if facultyCodeTaken(c, req.Code, 0) {
+		return
+	}
+	sede, ok := findSedeByCode(c, req.SedeCode)
+	if !ok {
+		return
+	}
+
+	faculty := models.Faculty{
+		Code:   req.Code,
+		Name:   req.Name,
+		SedeID: sede.ID,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&faculty).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), functions.AuditActionCreate, functions.AuditEntityFaculty, faculty.ID, faculty.Code, nil, faculty)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando la facultad"})
+		return
+	}
+	faculty.Sede = *sede
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"faculty": faculty,
+	})
+}
+
+// updateFaculty actualiza el código, el nombre y la sede de una facultad
+func updateFaculty(c *gin.Context) {
+	faculty, ok := findFaculty(c)
+	if !ok {
+		return
+	}
+
+	var req FacultyRequest
+	if !bindFacultyRequest(c, &req) {
+		return
+	}
+	if facultyCodeTaken(c, req.Code, faculty.ID) {
+		return
+	}
+	sede, ok := findSedeByCode(c, req.SedeCode)
+	if !ok {
+		return
+	}
+
+	// La auditoría guarda la facultad sin su sede, que tiene su propio historial
+	before := *faculty
+	before.Sede = models.Sede{}
+	faculty.Code = req.Code
+	faculty.Name = req.Name
+	faculty.SedeID = sede.ID
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Sede").Save(faculty).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), functions.AuditActionUpdate, functions.AuditEntityFaculty, faculty.ID, faculty.Code, before, faculty)
+	})
+	faculty.Sede = *sede
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando la facultad"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusOK, gin.H{
+		"faculty": faculty,
+	})
+}
+
+// deleteFaculty elimina una facultad que no tenga carreras
+func deleteFaculty(c *gin.Context) {
+	faculty, ok := findFaculty(c)
+	if !ok {
+		return
+	}
+
+	// Las carreras con borrado lógico siguen apuntando a la facultad y la llave foránea impide eliminarla
+	var careerCount int64
+	if err := config.DB.Unscoped().Model(&models.Career{}).Where("faculty_id = ?", faculty.ID).Count(&careerCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando las carreras de la facultad"})
+		return
+	}
+	if careerCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "No se puede eliminar la facultad porque tiene carreras asociadas"})
+		return
+	}
+
+	before := *faculty
+	before.Sede = models.Sede{}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(faculty).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), functions.AuditActionDelete, functions.AuditEntityFaculty, faculty.ID, faculty.Code, before, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando la facultad"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Facultad eliminada",
+	})
+}
+
+// findFaculty obtiene la facultad indicada en la ruta, con su sede, o responde con el error correspondiente
+func findFaculty(c *gin.Context) (*models.Faculty, bool) {
+	return findFacultyByCode(c, c.Param("code"))
+}
+
+// findFacultyByCode obtiene una facultad por su código, con su sede, o responde que no existe
+func findFacultyByCode(c *gin.Context, code string) (*models.Faculty, bool) {
+	var faculty models.Faculty
+	if err := config.DB.Preload("Sede").Where("code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&faculty).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Facultad no encontrada: " + code})
+		return nil, false
+	}
+	return &faculty, true
+}
+
+// bindFacultyRequest lee y normaliza los datos de una facultad, validando el formato del código
+func bindFacultyRequest(c *gin.Context, req *FacultyRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return false
+	}
+
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	req.Name = strings.TrimSpace(req.Name)
+	if !models.ValidarCodigoCarrera(req.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código de facultad inválido: debe tener entre 2 y 20 letras mayúsculas o dígitos, separados opcionalmente por guiones"})
+		return false
+	}
+	if req.Name == "" || len(req.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El nombre de la facultad debe tener entre 1 y 100 caracteres"})
+		return false
+	}
+	return true
+}
+
+// facultyCodeTaken verifica si el código ya lo usa otra facultad y, de ser así, responde con un conflicto
+func facultyCodeTaken(c *gin.Context, code string, exceptID uint) bool {
+	var count int64
+	if err := config.DB.Model(&models.Faculty{}).Where("code = ? AND id <> ?", code, exceptID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando el código de la facultad"})
+		return true
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ya existe una facultad con el código " + code})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package functions
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// Entidades del catálogo que registra la auditoría
+const (
+	AuditEntityCareer      = "career"
+	AuditEntityStudyPlan   = "study_plan"
+	AuditEntitySubject     = "subject"
+	AuditEntityEquivalence = "equivalence"
+	AuditEntitySede        = "sede"
+	AuditEntityFaculty     = "faculty"
+)
+
+// Acciones generales de la auditoría. Los cambios sobre la composición de un plan y las decisiones del
+// comité sobre equivalencias usan acciones específicas (ATTACH_SUBJECT, APPROVE...).
+const (
+	AuditActionCreate             = "CREATE"
+	AuditActionUpdate             = "UPDATE"
+	AuditActionDelete             = "DELETE"
+	AuditActionRestore            = "RESTORE" // Se vuelve a crear una carrera o materia eliminada
+	AuditActionAttachSubject      = "ATTACH_SUBJECT"
+	AuditActionDetachSubject      = "DETACH_SUBJECT"
+	AuditActionAddGroup           = "ADD_GROUP"
+	AuditActionUpdateGroup        = "UPDATE_GROUP"
+	AuditActionRemoveGroup        = "REMOVE_GROUP"
+	AuditActionAddPrerequisite    = "ADD_PREREQUISITE"
+	AuditActionRemovePrerequisite = "REMOVE_PREREQUISITE"
+	AuditActionAddAlias           = "ADD_ALIAS"
+	AuditActionRemoveAlias        = "REMOVE_ALIAS"
+)
+
+// AuditActor identifica a quien hace un cambio y la justificación que da
+type AuditActor struct {
+	Actor  string
+	Reason string
+}
+
+// AuditFilter son los filtros de la consulta de la auditoría; los campos vacíos no filtran
+type AuditFilter struct {
+	EntityType string
+	EntityID   uint
+	EntityKey  string
+	Actor      string
+	From       *time.Time // Desde este instante, inclusive
+	To         *time.Time // Hasta este instante, exclusive
+	Limit      int
+	Offset     int
+}
+
+// RecordAudit registra un cambio en la auditoría. before y after se guardan como JSON; nil indica que la
+// entidad no existía antes (creación) o dejó de existir (eliminación). Debe llamarse con la misma
+// transacción del cambio para que ambos se guarden o se descarten juntos.
+func RecordAudit(tx *gorm.DB, who AuditActor, action, entityType string, entityID uint, entityKey string, before, after interface{}) error {
+	entry := models.AuditLog{
+		Actor:      who.Actor,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		EntityKey:  entityKey,
+		Reason:     strings.TrimSpace(who.Reason),
+	}
+	var err error
+	if entry.Before, err = auditJSON(before); err != nil {
+		return err
+	}
+	if entry.After, err = auditJSON(after); err != nil {
+		return err
+	}
+	if entry.Actor == "" {
+		entry.Actor = "anonimo"
+	}
+
+	if err := tx.Create(&entry).Error; err != nil {
+		return errors.New("error registrando el cambio en la auditoría")
+	}
+	return nil
+}
+
+// auditJSON serializa el estado de una entidad para la auditoría
+func auditJSON(state interface{}) (*string, error) {
+	if state == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.New("error serializando el estado para la auditoría")
+	}
+	text := string(data)
+	return &text, nil
+}
+
+// ListAuditLogs consulta la auditoría, del cambio más reciente al más antiguo. Retorna también el total
+// de registros que cumplen los filtros.
+func ListAuditLogs(db *gorm.DB, filter AuditFilter) ([]models.AuditLog, int64, error) {
+	query := db.Model(&models.AuditLog{})
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.EntityKey != "" {
+		query = query.Where("entity_key = ?", filter.EntityKey)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("error consultando la auditoría")
+	}
+	var entries []models.AuditLog
+	if err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&entries).Error; err != nil {
+		return nil, 0, errors.New("error consultando la auditoría")
+	}
+	return entries, total, nil
+}
+
+// StudyPlanAuditState es el estado de un plan que se guarda en la auditoría: sus datos, las materias con
+// la tipología y los créditos que tienen en él, las agrupaciones y los prerrequisitos
+type StudyPlanAuditState struct {
+	CareerCode    string                  `json:"career_code"`
+	Version       string                  `json:"version"`
+	IsActive      bool                    `json:"is_active"`
+	SedeCode      string                  `json:"sede_code,omitempty"` // Sede en la que rige el plan; vacía si rige en todas
+	EffectiveFrom string                  `json:"effective_from"`
+	EffectiveTo   string                  `json:"effective_to"`
+	Requirements  PlanRequirements        `json:"requirements"`
+	Subjects      []PlanSubjectAuditState `json:"subjects"`
+	Groups        []PlanGroupAuditState   `json:"groups"`
+	Prerequisites map[string][]string     `json:"prerequisites"` // Materia -> materias que requiere
+}
+
+// PlanSubjectAuditState es una materia del plan en el estado de auditoría, con la tipología y los créditos
+// que define el plan para ella (study_plan_subjects). Vacíos indican que se usan los de la materia.
+type PlanSubjectAuditState struct {
+	Code    string `json:"code"`
+	Type    string `json:"type,omitempty"`
+	Credits int    `json:"credits,omitempty"`
+}
+
+// PlanGroupAuditState es una agrupación del plan en el estado de auditoría
+type PlanGroupAuditState struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	MinCredits int      `json:"min_credits"`
+	Subjects   []string `json:"subjects"`
+}
+
+// StudyPlanAuditKey es la clave legible de un plan en la auditoría ("ISIS 2023-1")
+func StudyPlanAuditKey(state *StudyPlanAuditState) string {
+	return state.CareerCode + " " + state.Version
+}
+
+// LoadStudyPlanAuditState lee el estado actual de un plan para la auditoría
+func LoadStudyPlanAuditState(db *gorm.DB, studyPlanID uint) (*StudyPlanAuditState, error) {
+	studyPlan, err := LoadStudyPlanForValidation(db, studyPlanID)
+	if err != nil {
+		return nil, err
+	}
+	state := &StudyPlanAuditState{
+		CareerCode:    studyPlan.Career.Code,
+		Version:       studyPlan.Version,
+		IsActive:      studyPlan.IsActive,
+		EffectiveFrom: studyPlan.EffectiveFrom,
+		EffectiveTo:   studyPlan.EffectiveTo,
+		Requirements: PlanRequirements{
+			TotalCredits:           studyPlan.TotalCredits,
+			FundObligatoriaCredits: studyPlan.FundObligatoriaCredits,
+			FundOptativaCredits:    studyPlan.FundOptativaCredits,
+			DisObligatoriaCredits:  studyPlan.DisObligatoriaCredits,
+			DisOptativaCredits:     studyPlan.DisOptativaCredits,
+			LibreCredits:           studyPlan.LibreCredits,
+		},
+		Subjects:      []PlanSubjectAuditState{},
+		Groups:        []PlanGroupAuditState{},
+		Prerequisites: make(map[string][]string),
+	}
+	if studyPlan.Sede != nil {
+		state.SedeCode = studyPlan.Sede.Code
+	}
+	var links []models.StudyPlanSubject
+	if err := db.Where("study_plan_id = ?", studyPlanID).Find(&links).Error; err != nil {
+		return nil, errors.New("error obteniendo las materias del plan")
+	}
+	linksBySubject := make(map[uint]models.StudyPlanSubject)
+	for _, link := range links {
+		linksBySubject[link.SubjectID] = link
+	}
+	for _, subject := range studyPlan.Subjects {
+		link := linksBySubject[subject.ID]
+		state.Subjects = append(state.Subjects, PlanSubjectAuditState{Code: subject.Code, Type: string(link.Type), Credits: link.Credits})
+	}
+	sort.Slice(state.Subjects, func(i, j int) bool { return state.Subjects[i].Code < state.Subjects[j].Code })
+
+	var groups []models.PlanGroup
+	if err := db.Preload("Subjects").Where("study_plan_id = ?", studyPlanID).Order("name").Find(&groups).Error; err != nil {
+		return nil, errors.New("error obteniendo las agrupaciones del plan")
+	}
+	for _, group := range groups {
+		groupState := PlanGroupAuditState{Name: group.Name, Type: string(group.Type), MinCredits: group.MinCredits, Subjects: []string{}}
+		for _, subject := range group.Subjects {
+			groupState.Subjects = append(groupState.Subjects, subject.Code)
+		}
+		sort.Strings(groupState.Subjects)
+		state.Groups = append(state.Groups, groupState)
+	}
+
+	var prerequisites []models.PlanPrerequisite
+	if err := db.Preload("Subject").Preload("Prerequisite").Where("study_plan_id = ?", studyPlanID).Find(&prerequisites).Error; err != nil {
+		return nil, errors.New("error obteniendo los prerrequisitos del plan")
+	}
+	for _, row := range prerequisites {
+		state.Prerequisites[row.Subject.Code] = append(state.Prerequisites[row.Subject.Code], row.Prerequisite.Code)
+	}
+	for code := range state.Prerequisites {
+		sort.Strings(state.Prerequisites[code])
+	}
+	return state, nil
+}
+
+// RecordStudyPlanChange aplica un cambio sobre un plan y registra en la auditoría el estado del plan antes
+// y después. Debe llamarse dentro de la transacción del cambio.
+func RecordStudyPlanChange(tx *gorm.DB, who AuditActor, action string, studyPlanID uint, change func() error) error {
+	before, err := LoadStudyPlanAuditState(tx, studyPlanID)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	after, err := LoadStudyPlanAuditState(tx, studyPlanID)
+	if err != nil {
+		return err
+	}
+	return RecordAudit(tx, who, action, AuditEntityStudyPlan, studyPlanID, StudyPlanAuditKey(after), before, after)
+}
+
+// EquivalenceAuditKey es la clave legible de una equivalencia en la auditoría ("2015734 → 1000004")
+func EquivalenceAuditKey(db *gorm.DB, equivalence models.Equivalence) string {
+	codes := make(map[uint]string)
+	var subjects []models.Subject
+	db.Unscoped().Select("id", "code").Where("id IN ?", []uint{equivalence.SourceSubjectID, equivalence.TargetSubjectID}).Find(&subjects)
+	for _, subject := range subjects {
+		codes[subject.ID] = subject.Code
+	}
+	return codes[equivalence.SourceSubjectID] + " → " + codes[equivalence.TargetSubjectID]
+}
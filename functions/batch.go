@@ -0,0 +1,95 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// CompareBatch compara muchas historias académicas contra un mismo plan de estudio.
+// El plan y sus equivalencias se cargan una sola vez y las comparaciones se reparten entre
+// un número acotado de workers. progress, si no es nil, se llama cada vez que termina un elemento.
+// Si el contexto se cancela no se inician más comparaciones y se retorna un error.
+func CompareBatch(ctx context.Context, db *gorm.DB, studyPlanID uint, items []models.BatchItem, workers int, opts CompareOptions, progress func(done, total int)) ([]models.BatchItemResult, error) {
+	if len(items) == 0 {
+		return nil, errors.New("el lote no contiene historias académicas")
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	snapshot, err := CatalogAt(db, opts.AsOf)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := snapshot.plan(studyPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.BatchItemResult, len(items))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for w := 0; w < workers && w < len(items); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i] = pc.compareBatchItem(items[i], opts)
+
+				if progress != nil {
+					mu.Lock()
+					done++
+					progress(done, len(items))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range items {
+		select {
+		case queue <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, errors.New("comparación en lote cancelada")
+	}
+	return results, nil
+}
+
+// compareBatchItem compara un elemento del lote; el planContext es de solo lectura y puede compartirse
+func (pc *planContext) compareBatchItem(item models.BatchItem, opts CompareOptions) (result models.BatchItemResult) {
+	result.ID = item.ID
+	if item.Error != "" {
+		result.Error = item.Error
+		return result
+	}
+	if len(item.AcademicHistory.Subjects) == 0 {
+		result.Error = "la historia académica no contiene materias"
+		return result
+	}
+
+	// Un error inesperado en un elemento no debe detener el resto del lote
+	defer func() {
+		if r := recover(); r != nil {
+			result.Result = nil
+			result.Error = "error interno comparando la historia académica"
+		}
+	}()
+
+	result.Result = pc.compareHistory(item.AcademicHistory, opts)
+	return result
+}
@@ -0,0 +1,348 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// catalogMaxAge es la antigüedad máxima de la copia en memoria del catálogo. Las escrituras hechas por la
+// API invalidan la copia de inmediato; este límite cubre los cambios hechos directamente en la base de datos.
+const catalogMaxAge = 10 * time.Minute
+
+// CatalogSnapshot es una copia en memoria, de solo lectura, del catálogo académico: carreras, planes
+// (con la tipología de cada materia en el plan, sus agrupaciones y sus equivalencias), materias y prerrequisitos.
+// Se comparte entre solicitudes concurrentes, por lo que nunca debe modificarse después de construida.
+type CatalogSnapshot struct {
+	BuiltAt        time.Time
+	Careers        map[string]*models.Career  // Por código de carrera
+	Subjects       map[uint]*models.Subject   // Por ID
+	SubjectsByCode map[string]*models.Subject // Por código de materia
+	Prerequisites  map[uint]map[uint][]uint   // Plan -> materia -> materias prerrequisito
+	Equivalences   []models.Equivalence
+	plans          map[uint]*planContext
+	activePlans    map[string]uint   // Carrera y sede del plan (planScopeKey) -> plan activo
+	careerPlans    map[string][]uint // Código de carrera -> planes ordenados por inicio de vigencia
+	sedeCodes      map[uint]string   // ID de la sede -> código
+	resolver       *codeResolver     // Traduce los códigos de las historias a los del catálogo
+	AsOf           *time.Time        // Instante reconstruido; nulo en la copia del catálogo vigente
+	generation     uint64
+}
+
+// catalogCache guarda la copia vigente del catálogo y la generación de escrituras que refleja
+var catalogCache struct {
+	mu         sync.RWMutex
+	build      sync.Mutex
+	snapshot   *CatalogSnapshot
+	generation uint64
+}
+
+// LoadCatalog construye la copia en memoria del catálogo; se llama al iniciar el servidor
+func LoadCatalog(db *gorm.DB) error {
+	InvalidateCatalog()
+	_, err := CurrentCatalog(db)
+	return err
+}
+
+// InvalidateCatalog marca la copia en memoria como desactualizada. Debe llamarse después de
+// confirmar cualquier escritura sobre carreras, planes, materias, prerrequisitos o equivalencias.
+func InvalidateCatalog() {
+	catalogCache.mu.Lock()
+	catalogCache.generation++
+	catalogCache.mu.Unlock()
+}
+
+// catalogGeneration retorna la generación de escrituras sobre el catálogo
+func catalogGeneration() uint64 {
+	catalogCache.mu.RLock()
+	defer catalogCache.mu.RUnlock()
+	return catalogCache.generation
+}
+
+// CurrentCatalog retorna la copia vigente del catálogo, reconstruyéndola si fue invalidada o expiró
+func CurrentCatalog(db *gorm.DB) (*CatalogSnapshot, error) {
+	if snapshot := freshCatalog(); snapshot != nil {
+		return snapshot, nil
+	}
+
+	// Solo una solicitud reconstruye la copia; las demás esperan y reutilizan el resultado
+	catalogCache.build.Lock()
+	defer catalogCache.build.Unlock()
+	if snapshot := freshCatalog(); snapshot != nil {
+		return snapshot, nil
+	}
+
+	generation := catalogGeneration()
+	snapshot, err := BuildCatalogSnapshot(db)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.generation = generation
+
+	catalogCache.mu.Lock()
+	catalogCache.snapshot = snapshot
+	catalogCache.mu.Unlock()
+	return snapshot, nil
+}
+
+// freshCatalog retorna la copia en memoria si sigue vigente
+func freshCatalog() *CatalogSnapshot {
+	catalogCache.mu.RLock()
+	defer catalogCache.mu.RUnlock()
+	snapshot := catalogCache.snapshot
+	if snapshot == nil || snapshot.generation != catalogCache.generation || time.Since(snapshot.BuiltAt) > catalogMaxAge {
+		return nil
+	}
+	return snapshot
+}
+
+// BuildCatalogSnapshot lee el catálogo completo de la base de datos
+func BuildCatalogSnapshot(db *gorm.DB) (*CatalogSnapshot, error) {
+	var careers []models.Career
+	if err := db.Preload("Faculty.Sede").Find(&careers).Error; err != nil {
+		return nil, errors.New("error cargando las carreras del catálogo")
+	}
+
+	var subjects []models.Subject
+	if err := db.Find(&subjects).Error; err != nil {
+		return nil, errors.New("error cargando las materias del catálogo")
+	}
+
+	var studyPlans []models.StudyPlan
+	if err := db.Preload("Subjects").Preload("Career.Faculty.Sede").Preload("Groups.Subjects").Order("id").Find(&studyPlans).Error; err != nil {
+		return nil, errors.New("error cargando los planes de estudio del catálogo")
+	}
+
+	var links []models.StudyPlanSubject
+	if err := db.Find(&links).Error; err != nil {
+		return nil, errors.New("error cargando la tipología de las materias por plan")
+	}
+
+	// Solo las equivalencias aprobadas por el comité participan en las comparaciones
+	var equivalences []models.Equivalence
+	if err := db.Preload("SourceSubject").Preload("TargetSubject").
+		Where("status = ?", models.EquivalenceStatusApproved).
+		Find(&equivalences).Error; err != nil {
+		return nil, errors.New("error cargando las equivalencias del catálogo")
+	}
+
+	var prerequisites []models.PlanPrerequisite
+	if err := db.Order("study_plan_id, subject_id, prerequisite_id").Find(&prerequisites).Error; err != nil {
+		return nil, errors.New("error cargando los prerrequisitos del catálogo")
+	}
+
+	var aliases []models.SubjectAlias
+	if err := db.Find(&aliases).Error; err != nil {
+		return nil, errors.New("error cargando los alias de las materias")
+	}
+
+	var sedes []models.Sede
+	if err := db.Find(&sedes).Error; err != nil {
+		return nil, errors.New("error cargando las sedes del catálogo")
+	}
+
+	return newCatalogSnapshot(careers, subjects, studyPlans, links, equivalences, prerequisites, aliases, sedes), nil
+}
+
+// newCatalogSnapshot arma los índices de la copia en memoria a partir de las filas leídas
+func newCatalogSnapshot(careers []models.Career, subjects []models.Subject, studyPlans []models.StudyPlan, links []models.StudyPlanSubject, equivalences []models.Equivalence, prerequisites []models.PlanPrerequisite, aliases []models.SubjectAlias, sedes []models.Sede) *CatalogSnapshot {
+	snapshot := &CatalogSnapshot{
+		BuiltAt:        time.Now(),
+		Careers:        make(map[string]*models.Career),
+		Subjects:       make(map[uint]*models.Subject),
+		SubjectsByCode: make(map[string]*models.Subject),
+		Prerequisites:  make(map[uint]map[uint][]uint),
+		Equivalences:   equivalences,
+		plans:          make(map[uint]*planContext),
+		activePlans:    make(map[string]uint),
+		careerPlans:    make(map[string][]uint),
+		sedeCodes:      make(map[uint]string),
+		resolver:       newCodeResolver(subjects, aliases, sedes),
+	}
+
+	for _, sede := range sedes {
+		snapshot.sedeCodes[sede.ID] = sede.Code
+	}
+
+	for i := range careers {
+		snapshot.Careers[careers[i].Code] = &careers[i]
+	}
+	for i := range subjects {
+		snapshot.Subjects[subjects[i].ID] = &subjects[i]
+		snapshot.SubjectsByCode[subjects[i].Code] = &subjects[i]
+	}
+	for _, row := range prerequisites {
+		if snapshot.Prerequisites[row.StudyPlanID] == nil {
+			snapshot.Prerequisites[row.StudyPlanID] = make(map[uint][]uint)
+		}
+		planPrerequisites := snapshot.Prerequisites[row.StudyPlanID]
+		planPrerequisites[row.SubjectID] = append(planPrerequisites[row.SubjectID], row.PrerequisiteID)
+	}
+
+	linksByPlan := make(map[uint][]models.StudyPlanSubject)
+	for _, link := range links {
+		linksByPlan[link.StudyPlanID] = append(linksByPlan[link.StudyPlanID], link)
+	}
+
+	for i := range studyPlans {
+		studyPlan := studyPlans[i]
+		applyPlanSubjectLinks(&studyPlan, linksByPlan[studyPlan.ID])
+
+		// Solo las equivalencias en las que participa alguna materia del plan
+		inPlan := make(map[uint]bool)
+		for _, subject := range studyPlan.Subjects {
+			inPlan[subject.ID] = true
+		}
+		var planEquivalences []models.Equivalence
+		for _, equiv := range equivalences {
+			if inPlan[equiv.SourceSubjectID] || inPlan[equiv.TargetSubjectID] {
+				planEquivalences = append(planEquivalences, equiv)
+			}
+		}
+
+		pc := newPlanContext(studyPlan, planEquivalences)
+		pc.resolver = snapshot.resolver
+		snapshot.plans[studyPlan.ID] = pc
+
+		// Los planes se recorren por ID: el primer plan activo de cada carrera en cada sede es el vigente
+		scope := planScopeKey(studyPlan.Career.Code, snapshot.planSede(studyPlan))
+		if _, exists := snapshot.activePlans[scope]; studyPlan.IsActive && !exists {
+			snapshot.activePlans[scope] = studyPlan.ID
+		}
+		snapshot.careerPlans[studyPlan.Career.Code] = append(snapshot.careerPlans[studyPlan.Career.Code], studyPlan.ID)
+	}
+
+	for _, planIDs := range snapshot.careerPlans {
+		sort.SliceStable(planIDs, func(i, j int) bool {
+			return snapshot.plans[planIDs[i]].StudyPlan.EffectiveFrom < snapshot.plans[planIDs[j]].StudyPlan.EffectiveFrom
+		})
+	}
+
+	return snapshot
+}
+
+// plan obtiene un plan de la copia en memoria
+func (s *CatalogSnapshot) plan(studyPlanID uint) (*planContext, error) {
+	pc, exists := s.plans[studyPlanID]
+	if !exists {
+		return nil, ErrStudyPlanNotFound
+	}
+	return pc, nil
+}
+
+// planScopeKey es la clave de los planes de una carrera en una sede; la sede vacía agrupa los planes que
+// rigen en todas las sedes
+func planScopeKey(careerCode, sede string) string {
+	return careerCode + "|" + sede
+}
+
+// planScopes retorna, en orden de prioridad, las sedes de los planes que pueden aplicar en una sede:
+// primero los propios de la sede y después los que rigen en todas
+func planScopes(sede string) []string {
+	if sede == "" {
+		return []string{""}
+	}
+	return []string{sede, ""}
+}
+
+// planSede retorna el código de la sede en la que rige un plan; vacío si rige en todas las sedes
+func (s *CatalogSnapshot) planSede(studyPlan models.StudyPlan) string {
+	if studyPlan.SedeID == nil {
+		return ""
+	}
+	return s.sedeCodes[*studyPlan.SedeID]
+}
+
+// careerSede retorna la sede con la que se elige el plan de una carrera: la indicada o, si no se indicó,
+// la de la facultad de la carrera
+func (s *CatalogSnapshot) careerSede(careerCode, sede string) string {
+	if sede = strings.ToUpper(strings.TrimSpace(sede)); sede != "" {
+		return sede
+	}
+	if career, exists := s.Careers[careerCode]; exists && career.Faculty != nil {
+		return career.Faculty.Sede.Code
+	}
+	return ""
+}
+
+// activePlan obtiene el plan activo de una carrera en una sede: el propio de la sede o, si no tiene, el
+// que rige en todas las sedes
+func (s *CatalogSnapshot) activePlan(careerCode, sede string) (*planContext, error) {
+	for _, scope := range planScopes(sede) {
+		if studyPlanID, exists := s.activePlans[planScopeKey(careerCode, scope)]; exists {
+			return s.plan(studyPlanID)
+		}
+	}
+	return nil, fmt.Errorf("%w: la carrera %s no tiene un plan activo", ErrStudyPlanNotFound, careerCode)
+}
+
+// planForPeriod obtiene el plan de una carrera vigente en un periodo académico para la sede indicada (o la
+// de la facultad de la carrera). Los planes propios de la sede tienen prioridad sobre los que rigen en todas.
+// Si ningún plan tiene una vigencia que cubra el periodo se usa el plan activo de la carrera.
+func (s *CatalogSnapshot) planForPeriod(careerCode, period, sede string) (*planContext, error) {
+	sede = s.careerSede(careerCode, sede)
+	if period == "" {
+		return s.activePlan(careerCode, sede)
+	}
+	if !models.ValidarPeriodo(period) {
+		return nil, errors.New("periodo de admisión inválido, use el formato AAAA-S (por ejemplo 2023-1): " + period)
+	}
+
+	// Los planes están ordenados por inicio de vigencia: si varios cubren el periodo gana el más reciente
+	for _, scope := range planScopes(sede) {
+		var match *planContext
+		for _, studyPlanID := range s.careerPlans[careerCode] {
+			pc := s.plans[studyPlanID]
+			if s.planSede(pc.StudyPlan) == scope && periodInRange(period, pc.StudyPlan.EffectiveFrom, pc.StudyPlan.EffectiveTo) {
+				match = pc
+			}
+		}
+		if match != nil {
+			return match, nil
+		}
+	}
+	return s.activePlan(careerCode, sede)
+}
+
+// periodInRange indica si un periodo está dentro de una vigencia. Una vigencia sin inicio no cubre
+// ningún periodo y una sin fin sigue abierta. Los periodos AAAA-S se ordenan como texto.
+func periodInRange(period, from, to string) bool {
+	if from == "" || period < from {
+		return false
+	}
+	return to == "" || period <= to
+}
+
+// Stats resume el contenido de la copia en memoria del catálogo
+func (s *CatalogSnapshot) Stats() map[string]interface{} {
+	planIDs := make([]uint, 0, len(s.plans))
+	for id := range s.plans {
+		planIDs = append(planIDs, id)
+	}
+	sort.Slice(planIDs, func(i, j int) bool { return planIDs[i] < planIDs[j] })
+
+	prerequisites := 0
+	for _, planPrerequisites := range s.Prerequisites {
+		for _, ids := range planPrerequisites {
+			prerequisites += len(ids)
+		}
+	}
+
+	return map[string]interface{}{
+		"built_at":        s.BuiltAt,
+		"careers":         len(s.Careers),
+		"study_plans":     len(s.plans),
+		"study_plan_ids":  planIDs,
+		"subjects":        len(s.Subjects),
+		"equivalences":    len(s.Equivalences),
+		"prerequisites":   prerequisites,
+		"subject_aliases": len(s.resolver.aliases),
+		"as_of":           s.AsOf,
+	}
+}
@@ -0,0 +1,639 @@
+package functions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// El catálogo de una fecha pasada se reconstruye con la auditoría: cada registro guarda la versión de la
+// entidad antes y después del cambio, así que la versión vigente en un instante es la anterior al primer
+// cambio posterior a ese instante, o la actual si la entidad no cambió después. Las carreras, materias y
+// equivalencias eliminadas se conservan con borrado lógico para que sus referencias sigan siendo válidas;
+// las sedes y facultades eliminadas se recuperan de la versión que guarda la auditoría.
+
+// AuditCoverageMigration es la marca de schema_migrations cuya fecha es el inicio de la auditoría del
+// catálogo. Antes de esa fecha no hay versiones con qué reconstruirlo.
+const AuditCoverageMigration = "audit_coverage_start"
+
+// AsOfBeforeAuditError se retorna cuando se pide el catálogo de un instante anterior al inicio de la auditoría
+type AsOfBeforeAuditError struct {
+	Start time.Time
+}
+
+func (e *AsOfBeforeAuditError) Error() string {
+	return "la auditoría del catálogo comienza el " + e.Start.Format(time.RFC3339) +
+		": no es posible reconstruir el catálogo de una fecha anterior"
+}
+
+// CheckAsOfCoverage verifica que la auditoría cubra el instante consultado; asOf nulo (el catálogo
+// vigente) siempre está cubierto
+func CheckAsOfCoverage(db *gorm.DB, asOf *time.Time) error {
+	if asOf == nil {
+		return nil
+	}
+	var marker models.SchemaMigration
+	if err := db.Where("name = ?", AuditCoverageMigration).First(&marker).Error; err != nil {
+		return errors.New("error consultando el inicio de la auditoría del catálogo")
+	}
+	if asOf.Before(marker.AppliedAt) {
+		return &AsOfBeforeAuditError{Start: marker.AppliedAt}
+	}
+	return nil
+}
+
+// ParseAsOf interpreta la fecha de corte de una consulta del catálogo: una fecha AAAA-MM-DD (el catálogo al
+// final de ese día) o un instante RFC 3339. Sin valor retorna nil, que indica el catálogo vigente. Un corte
+// en el futuro se ajusta al momento actual para que la fecha guardada reproduzca la consulta.
+func ParseAsOf(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		day, dayErr := time.ParseInLocation("2006-01-02", value, time.Local)
+		if dayErr != nil {
+			return nil, errors.New("fecha as_of inválida, use el formato AAAA-MM-DD o RFC 3339")
+		}
+		asOf = day.AddDate(0, 0, 1)
+	}
+	if now := time.Now(); asOf.After(now) {
+		asOf = now
+	}
+	return &asOf, nil
+}
+
+// CatalogAt retorna la copia vigente del catálogo o, si asOf no es nil, el catálogo como estaba en ese
+// instante: con los cambios hechos antes de él y sin los posteriores
+func CatalogAt(db *gorm.DB, asOf *time.Time) (*CatalogSnapshot, error) {
+	if asOf == nil {
+		return CurrentCatalog(db)
+	}
+	if snapshot := cachedCatalogAsOf(*asOf); snapshot != nil {
+		return snapshot, nil
+	}
+
+	// Solo una solicitud reconstruye a la vez; las que piden el mismo instante reutilizan el resultado
+	asOfCache.build.Lock()
+	defer asOfCache.build.Unlock()
+	if snapshot := cachedCatalogAsOf(*asOf); snapshot != nil {
+		return snapshot, nil
+	}
+
+	generation := catalogGeneration()
+	snapshot, err := BuildCatalogSnapshotAsOf(db, *asOf)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.generation = generation
+
+	asOfCache.mu.Lock()
+	defer asOfCache.mu.Unlock()
+	key := asOf.UnixNano()
+	if asOfCache.snapshots == nil {
+		asOfCache.snapshots = make(map[int64]*CatalogSnapshot)
+	}
+	if _, exists := asOfCache.snapshots[key]; !exists {
+		asOfCache.order = append(asOfCache.order, key)
+	}
+	asOfCache.snapshots[key] = snapshot
+	for len(asOfCache.order) > asOfCacheSize {
+		delete(asOfCache.snapshots, asOfCache.order[0])
+		asOfCache.order = asOfCache.order[1:]
+	}
+	return snapshot, nil
+}
+
+// asOfCacheSize es cuántos catálogos reconstruidos se conservan en memoria
+const asOfCacheSize = 8
+
+// asOfCache guarda los últimos catálogos reconstruidos, por instante. Como la copia vigente, se descartan
+// cuando se escribe en el catálogo o cuando expiran.
+var asOfCache struct {
+	mu        sync.Mutex
+	build     sync.Mutex
+	snapshots map[int64]*CatalogSnapshot
+	order     []int64 // Instantes en el orden en que se guardaron, para descartar el más antiguo
+}
+
+// cachedCatalogAsOf retorna el catálogo reconstruido de un instante si sigue vigente
+func cachedCatalogAsOf(asOf time.Time) *CatalogSnapshot {
+	generation := catalogGeneration()
+	asOfCache.mu.Lock()
+	defer asOfCache.mu.Unlock()
+	snapshot := asOfCache.snapshots[asOf.UnixNano()]
+	if snapshot == nil || snapshot.generation != generation || time.Since(snapshot.BuiltAt) > catalogMaxAge {
+		return nil
+	}
+	return snapshot
+}
+
+// catalogVersions guarda la versión vigente en el instante consultado de cada entidad que cambió después
+// de él. Una versión nula indica que la entidad no existía.
+type catalogVersions map[string]*string
+
+// versionKey es la clave de una entidad en las versiones; los alias se identifican por su código
+func versionKey(entityType string, id interface{}) string {
+	return fmt.Sprintf("%s:%v", entityType, id)
+}
+
+// loadCatalogVersions lee de la auditoría los cambios desde asOf y conserva el estado anterior al primero
+// de cada entidad
+func loadCatalogVersions(db *gorm.DB, asOf time.Time) (catalogVersions, error) {
+	var entries []models.AuditLog
+	if err := db.Where("created_at >= ?", asOf).Order("created_at, id").Find(&entries).Error; err != nil {
+		return nil, errors.New("error consultando la auditoría del catálogo")
+	}
+
+	versions := make(catalogVersions)
+	for _, entry := range entries {
+		key := versionKey(entry.EntityType, entry.EntityID)
+		if entry.Action == AuditActionAddAlias || entry.Action == AuditActionRemoveAlias {
+			// Los alias se registran sobre la materia, pero tienen sus propias versiones
+			state := entry.After
+			if state == nil {
+				state = entry.Before
+			}
+			var alias models.SubjectAlias
+			if state == nil || json.Unmarshal([]byte(*state), &alias) != nil {
+				continue
+			}
+			key = versionKey("alias", alias.Alias)
+		}
+		if _, seen := versions[key]; !seen {
+			versions[key] = entry.Before
+		}
+	}
+	return versions, nil
+}
+
+// deletedIDs retorna, ordenados, los IDs de las entidades de un tipo que cambiaron después del instante
+// consultado y ya no están en la base de datos (las sedes y facultades se eliminan físicamente)
+func (v catalogVersions) deletedIDs(entityType string, currentIDs map[uint]bool) []uint {
+	var ids []uint
+	prefix := entityType + ":"
+	for key := range v {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		var id uint
+		if _, err := fmt.Sscan(strings.TrimPrefix(key, prefix), &id); err != nil || currentIDs[id] {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// version decodifica en target la versión de una entidad en el instante consultado. changed indica si la
+// entidad cambió después del instante; exists, si en ese instante existía.
+func (v catalogVersions) version(key string, target interface{}) (exists, changed bool, err error) {
+	state, changed := v[key]
+	if !changed {
+		return false, false, nil
+	}
+	if state == nil {
+		return false, true, nil
+	}
+	if err := json.Unmarshal([]byte(*state), target); err != nil {
+		return false, true, errors.New("la auditoría tiene una versión inválida de " + key)
+	}
+	return true, true, nil
+}
+
+// BuildCatalogSnapshotAsOf reconstruye el catálogo como estaba en un instante. Falla si el instante es
+// anterior al inicio de la auditoría.
+func BuildCatalogSnapshotAsOf(db *gorm.DB, asOf time.Time) (*CatalogSnapshot, error) {
+	if err := CheckAsOfCoverage(db, &asOf); err != nil {
+		return nil, err
+	}
+	versions, err := loadCatalogVersions(db, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	sedes, err := sedesAsOf(db, versions)
+	if err != nil {
+		return nil, err
+	}
+	faculties, err := facultiesAsOf(db, versions, sedes)
+	if err != nil {
+		return nil, err
+	}
+	careers, err := careersAsOf(db, versions, faculties)
+	if err != nil {
+		return nil, err
+	}
+	subjects, err := subjectsAsOf(db, versions)
+	if err != nil {
+		return nil, err
+	}
+	subjectsByID := make(map[uint]*models.Subject)
+	subjectsByCode := make(map[string]*models.Subject)
+	for i := range subjects {
+		subjectsByID[subjects[i].ID] = &subjects[i]
+		subjectsByCode[subjects[i].Code] = &subjects[i]
+	}
+
+	studyPlans, links, prerequisites, err := studyPlansAsOf(db, versions, careers, sedes, subjectsByID, subjectsByCode)
+	if err != nil {
+		return nil, err
+	}
+	equivalences, err := equivalencesAsOf(db, versions, subjectsByID)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := aliasesAsOf(db, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := newCatalogSnapshot(careers, subjects, studyPlans, links, equivalences, prerequisites, aliases, sedes)
+	snapshot.AsOf = &asOf
+	return snapshot, nil
+}
+
+// sedesAsOf obtiene las sedes que existían en el instante consultado, con sus datos de entonces
+func sedesAsOf(db *gorm.DB, versions catalogVersions) ([]models.Sede, error) {
+	var current []models.Sede
+	if err := db.Order("id").Find(&current).Error; err != nil {
+		return nil, errors.New("error cargando las sedes del catálogo")
+	}
+
+	var sedes []models.Sede
+	currentIDs := make(map[uint]bool)
+	for _, sede := range current {
+		currentIDs[sede.ID] = true
+		var version models.Sede
+		exists, changed, err := versions.version(versionKey(AuditEntitySede, sede.ID), &version)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			if !exists {
+				continue
+			}
+			sede = version
+		}
+		sedes = append(sedes, sede)
+	}
+	for _, id := range versions.deletedIDs(AuditEntitySede, currentIDs) {
+		var version models.Sede
+		exists, _, err := versions.version(versionKey(AuditEntitySede, id), &version)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			sedes = append(sedes, version)
+		}
+	}
+	return sedes, nil
+}
+
+// facultiesAsOf obtiene las facultades que existían en el instante consultado, con su sede de entonces
+func facultiesAsOf(db *gorm.DB, versions catalogVersions, sedes []models.Sede) ([]models.Faculty, error) {
+	var current []models.Faculty
+	if err := db.Order("id").Find(&current).Error; err != nil {
+		return nil, errors.New("error cargando las facultades del catálogo")
+	}
+	sedesByID := make(map[uint]models.Sede)
+	for _, sede := range sedes {
+		sedesByID[sede.ID] = sede
+	}
+
+	var faculties []models.Faculty
+	currentIDs := make(map[uint]bool)
+	for _, faculty := range current {
+		currentIDs[faculty.ID] = true
+		var version models.Faculty
+		exists, changed, err := versions.version(versionKey(AuditEntityFaculty, faculty.ID), &version)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			if !exists {
+				continue
+			}
+			faculty = version
+		}
+		faculties = append(faculties, faculty)
+	}
+	for _, id := range versions.deletedIDs(AuditEntityFaculty, currentIDs) {
+		var version models.Faculty
+		exists, _, err := versions.version(versionKey(AuditEntityFaculty, id), &version)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			faculties = append(faculties, version)
+		}
+	}
+	for i := range faculties {
+		faculties[i].Sede = sedesByID[faculties[i].SedeID]
+	}
+	return faculties, nil
+}
+
+// careersAsOf obtiene las carreras que existían en el instante consultado, con su facultad de entonces
+func careersAsOf(db *gorm.DB, versions catalogVersions, faculties []models.Faculty) ([]models.Career, error) {
+	var current []models.Career
+	if err := db.Unscoped().Order("id").Find(&current).Error; err != nil {
+		return nil, errors.New("error cargando las carreras del catálogo")
+	}
+	facultiesByID := make(map[uint]*models.Faculty)
+	for i := range faculties {
+		facultiesByID[faculties[i].ID] = &faculties[i]
+	}
+
+	var careers []models.Career
+	for _, career := range current {
+		var version models.Career
+		exists, changed, err := versions.version(versionKey(AuditEntityCareer, career.ID), &version)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			if !exists {
+				continue
+			}
+			career = version
+		} else if career.DeletedAt.Valid {
+			continue
+		}
+		career.Faculty = nil
+		if career.FacultyID != nil {
+			career.Faculty = facultiesByID[*career.FacultyID]
+		}
+		careers = append(careers, career)
+	}
+	return careers, nil
+}
+
+// subjectsAsOf obtiene las materias que existían en el instante consultado, con sus datos de entonces
+func subjectsAsOf(db *gorm.DB, versions catalogVersions) ([]models.Subject, error) {
+	var current []models.Subject
+	if err := db.Unscoped().Order("id").Find(&current).Error; err != nil {
+		return nil, errors.New("error cargando las materias del catálogo")
+	}
+
+	var subjects []models.Subject
+	for _, subject := range current {
+		var version models.Subject
+		exists, changed, err := versions.version(versionKey(AuditEntitySubject, subject.ID), &version)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			if !exists {
+				continue
+			}
+			subject = version
+		} else if subject.DeletedAt.Valid {
+			continue
+		}
+		subject.Sede = nil
+		subject.Equivalences, subject.StudyPlans = nil, nil
+		subjects = append(subjects, subject)
+	}
+	return subjects, nil
+}
+
+// studyPlansAsOf obtiene los planes que existían en el instante consultado con sus materias, agrupaciones y
+// prerrequisitos de entonces. Los planes que no cambiaron después del instante se leen de sus tablas; los
+// demás, de su versión en la auditoría.
+func studyPlansAsOf(db *gorm.DB, versions catalogVersions, careers []models.Career, sedes []models.Sede, subjectsByID map[uint]*models.Subject, subjectsByCode map[string]*models.Subject) ([]models.StudyPlan, []models.StudyPlanSubject, []models.PlanPrerequisite, error) {
+	var current []models.StudyPlan
+	if err := db.Preload("Groups.Subjects").Order("id").Find(&current).Error; err != nil {
+		return nil, nil, nil, errors.New("error cargando los planes de estudio del catálogo")
+	}
+	var currentLinks []models.StudyPlanSubject
+	if err := db.Find(&currentLinks).Error; err != nil {
+		return nil, nil, nil, errors.New("error cargando la tipología de las materias por plan")
+	}
+	var currentPrerequisites []models.PlanPrerequisite
+	if err := db.Order("study_plan_id, subject_id, prerequisite_id").Find(&currentPrerequisites).Error; err != nil {
+		return nil, nil, nil, errors.New("error cargando los prerrequisitos del catálogo")
+	}
+	linksByPlan := make(map[uint][]models.StudyPlanSubject)
+	for _, link := range currentLinks {
+		linksByPlan[link.StudyPlanID] = append(linksByPlan[link.StudyPlanID], link)
+	}
+	prerequisitesByPlan := make(map[uint][]models.PlanPrerequisite)
+	for _, row := range currentPrerequisites {
+		prerequisitesByPlan[row.StudyPlanID] = append(prerequisitesByPlan[row.StudyPlanID], row)
+	}
+	sedeIDs := make(map[string]uint)
+	for _, sede := range sedes {
+		sedeIDs[sede.Code] = sede.ID
+	}
+	careersByID := make(map[uint]models.Career)
+	careersByCode := make(map[string]models.Career)
+	for _, career := range careers {
+		careersByID[career.ID] = career
+		careersByCode[career.Code] = career
+	}
+
+	var studyPlans []models.StudyPlan
+	var links []models.StudyPlanSubject
+	var prerequisites []models.PlanPrerequisite
+	for _, studyPlan := range current {
+		planLinks := linksByPlan[studyPlan.ID]
+		planPrerequisites := prerequisitesByPlan[studyPlan.ID]
+		career, careerExists := careersByID[studyPlan.CareerID]
+
+		var state StudyPlanAuditState
+		exists, changed, err := versions.version(versionKey(AuditEntityStudyPlan, studyPlan.ID), &state)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if changed {
+			if !exists {
+				continue
+			}
+			career, careerExists = careersByCode[state.CareerCode]
+			studyPlan.Version = state.Version
+			studyPlan.IsActive = state.IsActive
+			studyPlan.SedeID = nil
+			if sedeID, exists := sedeIDs[state.SedeCode]; exists {
+				studyPlan.SedeID = &sedeID
+			}
+			studyPlan.EffectiveFrom = state.EffectiveFrom
+			studyPlan.EffectiveTo = state.EffectiveTo
+			ApplyPlanRequirements(&studyPlan, state.Requirements)
+			planLinks, planPrerequisites, studyPlan.Groups = planStateRows(studyPlan, state, subjectsByCode)
+		}
+		if !careerExists {
+			continue
+		}
+		studyPlan.CareerID = career.ID
+		studyPlan.Career = career
+
+		// Las materias del plan, y las de sus agrupaciones, con sus datos en el instante consultado
+		studyPlan.Subjects = nil
+		for _, link := range planLinks {
+			if subject, exists := subjectsByID[link.SubjectID]; exists {
+				studyPlan.Subjects = append(studyPlan.Subjects, *subject)
+			}
+		}
+		for i := range studyPlan.Groups {
+			var groupSubjects []models.Subject
+			for _, subject := range studyPlan.Groups[i].Subjects {
+				if current, exists := subjectsByID[subject.ID]; exists {
+					groupSubjects = append(groupSubjects, *current)
+				}
+			}
+			studyPlan.Groups[i].Subjects = groupSubjects
+		}
+
+		studyPlans = append(studyPlans, studyPlan)
+		links = append(links, planLinks...)
+		prerequisites = append(prerequisites, planPrerequisites...)
+	}
+	return studyPlans, links, prerequisites, nil
+}
+
+// planStateRows arma las filas de un plan (materias, prerrequisitos y agrupaciones) a partir de su versión
+// en la auditoría. Las agrupaciones conservan el ID actual de la agrupación con el mismo nombre.
+func planStateRows(studyPlan models.StudyPlan, state StudyPlanAuditState, subjectsByCode map[string]*models.Subject) ([]models.StudyPlanSubject, []models.PlanPrerequisite, []models.PlanGroup) {
+	var links []models.StudyPlanSubject
+	for _, planSubject := range state.Subjects {
+		if subject, exists := subjectsByCode[planSubject.Code]; exists {
+			links = append(links, models.StudyPlanSubject{
+				StudyPlanID: studyPlan.ID,
+				SubjectID:   subject.ID,
+				Type:        models.TipologiaAsignatura(planSubject.Type),
+				Credits:     planSubject.Credits,
+			})
+		}
+	}
+
+	codes := make([]string, 0, len(state.Prerequisites))
+	for code := range state.Prerequisites {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	var prerequisites []models.PlanPrerequisite
+	for _, code := range codes {
+		subject, exists := subjectsByCode[code]
+		if !exists {
+			continue
+		}
+		for _, prerequisiteCode := range state.Prerequisites[code] {
+			if prerequisite, exists := subjectsByCode[prerequisiteCode]; exists {
+				prerequisites = append(prerequisites, models.PlanPrerequisite{StudyPlanID: studyPlan.ID, SubjectID: subject.ID, PrerequisiteID: prerequisite.ID})
+			}
+		}
+	}
+
+	groupIDs := make(map[string]uint)
+	for _, group := range studyPlan.Groups {
+		groupIDs[group.Name] = group.ID
+	}
+	var groups []models.PlanGroup
+	for _, groupState := range state.Groups {
+		group := models.PlanGroup{
+			ID:          groupIDs[groupState.Name],
+			StudyPlanID: studyPlan.ID,
+			Name:        groupState.Name,
+			Type:        models.TipologiaAsignatura(groupState.Type),
+			MinCredits:  groupState.MinCredits,
+		}
+		for _, code := range groupState.Subjects {
+			if subject, exists := subjectsByCode[code]; exists {
+				group.Subjects = append(group.Subjects, models.Subject{ID: subject.ID})
+			}
+		}
+		groups = append(groups, group)
+	}
+	return links, prerequisites, groups
+}
+
+// equivalencesAsOf obtiene las equivalencias aprobadas en el instante consultado, con sus materias de entonces
+func equivalencesAsOf(db *gorm.DB, versions catalogVersions, subjectsByID map[uint]*models.Subject) ([]models.Equivalence, error) {
+	var current []models.Equivalence
+	if err := db.Unscoped().Order("id").Find(&current).Error; err != nil {
+		return nil, errors.New("error cargando las equivalencias del catálogo")
+	}
+
+	var equivalences []models.Equivalence
+	for _, equivalence := range current {
+		var version models.Equivalence
+		exists, changed, err := versions.version(versionKey(AuditEntityEquivalence, equivalence.ID), &version)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			if !exists {
+				continue
+			}
+			equivalence = version
+		} else if equivalence.DeletedAt.Valid {
+			continue
+		}
+		if equivalence.Status != models.EquivalenceStatusApproved {
+			continue
+		}
+		source, sourceExists := subjectsByID[equivalence.SourceSubjectID]
+		target, targetExists := subjectsByID[equivalence.TargetSubjectID]
+		if !sourceExists || !targetExists {
+			continue
+		}
+		equivalence.SourceSubject, equivalence.TargetSubject = *source, *target
+		equivalence.StudyPlan = models.StudyPlan{}
+		equivalences = append(equivalences, equivalence)
+	}
+	return equivalences, nil
+}
+
+// aliasesAsOf obtiene los alias registrados en el instante consultado, incluidos los que se eliminaron después
+func aliasesAsOf(db *gorm.DB, versions catalogVersions) ([]models.SubjectAlias, error) {
+	var current []models.SubjectAlias
+	if err := db.Find(&current).Error; err != nil {
+		return nil, errors.New("error cargando los alias de las materias")
+	}
+
+	var aliases []models.SubjectAlias
+	present := make(map[string]bool)
+	for _, alias := range current {
+		key := versionKey("alias", alias.Alias)
+		present[key] = true
+		var version models.SubjectAlias
+		exists, changed, err := versions.version(key, &version)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			aliases = append(aliases, alias)
+		} else if exists {
+			aliases = append(aliases, version)
+		}
+	}
+
+	// Los alias que se eliminaron después del instante consultado
+	var removed []string
+	for key := range versions {
+		if strings.HasPrefix(key, "alias:") && !present[key] {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+	for _, key := range removed {
+		var version models.SubjectAlias
+		exists, _, err := versions.version(key, &version)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			aliases = append(aliases, version)
+		}
+	}
+	return aliases, nil
+}
@@ -0,0 +1,316 @@
+package functions
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// Formas en que un código de la historia académica se traduce al de una materia del catálogo
+const (
+	CodeResolutionNormalized = "NORMALIZACION" // Difiere solo en mayúsculas, espacios, guiones o ceros a la izquierda
+	CodeResolutionAlias      = "ALIAS"         // Está registrado como alias de la materia
+	CodeResolutionSuffix     = "SUFIJO"        // Coincide al ignorar el sufijo (por ejemplo 1000004 y 1000004-M)
+	CodeResolutionSede       = "SEDE"          // Es la variante de otra sede de una materia del plan
+)
+
+// ErrSubjectAliasNotFound se retorna cuando el alias solicitado no existe
+var ErrSubjectAliasNotFound = errors.New("alias no encontrado")
+
+// SubjectAliasError se retorna cuando un alias no es válido para la materia
+type SubjectAliasError struct {
+	Message string
+}
+
+func (e *SubjectAliasError) Error() string {
+	return e.Message
+}
+
+// SubjectAliasConflictError se retorna cuando el alias ya es el código o el alias de otra materia
+type SubjectAliasConflictError struct {
+	Message string
+}
+
+func (e *SubjectAliasConflictError) Error() string {
+	return e.Message
+}
+
+var (
+	codeSpaces   = regexp.MustCompile(`\s+`)
+	codeDashes   = strings.NewReplacer("–", "-", "—", "-", "‐", "-", "_", "-")
+	codeSuffix   = regexp.MustCompile(`-[A-Z]+$`)
+	leadingZeros = regexp.MustCompile(`^0+([0-9])`)
+)
+
+// NormalizeSubjectCode lleva un código de materia a su forma canónica: sin espacios, en mayúsculas,
+// con guiones simples y sin ceros a la izquierda ("  01000004 - m" → "1000004-M")
+func NormalizeSubjectCode(code string) string {
+	code = codeSpaces.ReplaceAllString(strings.ToUpper(codeDashes.Replace(code)), "")
+	return leadingZeros.ReplaceAllString(code, "$1")
+}
+
+// SubjectCodeSuffix retorna el sufijo de letras de un código, sin el guion ("1000004-M" → "M")
+func SubjectCodeSuffix(code string) string {
+	return strings.TrimPrefix(codeSuffix.FindString(NormalizeSubjectCode(code)), "-")
+}
+
+// SubjectCodeBase retorna el código normalizado sin el sufijo de letras ("1000004-M" → "1000004")
+func SubjectCodeBase(code string) string {
+	return codeSuffix.ReplaceAllString(NormalizeSubjectCode(code), "")
+}
+
+// codeResolver traduce los códigos de las historias académicas a los códigos de las materias del catálogo.
+// Hace parte de la copia en memoria del catálogo y es de solo lectura.
+type codeResolver struct {
+	exact      map[string]bool     // Códigos tal como están en el catálogo
+	normalized map[string]string   // Código normalizado -> código del catálogo
+	aliases    map[string]string   // Alias normalizado -> código del catálogo
+	bases      map[string][]string // Código sin sufijo -> códigos del catálogo
+	sedes      map[string]string   // Código del catálogo -> código de la sede que ofrece esa variante
+}
+
+// newCodeResolver arma los índices de búsqueda de códigos a partir de las materias, sus alias y las sedes.
+// La sede de una materia es la registrada en ella o, si no tiene, la que corresponde al sufijo de su código.
+func newCodeResolver(subjects []models.Subject, aliases []models.SubjectAlias, sedes []models.Sede) *codeResolver {
+	r := &codeResolver{
+		exact:      make(map[string]bool),
+		normalized: make(map[string]string),
+		aliases:    make(map[string]string),
+		bases:      make(map[string][]string),
+		sedes:      make(map[string]string),
+	}
+	sedeByID := make(map[uint]string)
+	sedeBySuffix := make(map[string]string)
+	for _, sede := range sedes {
+		sedeByID[sede.ID] = sede.Code
+		suffix := strings.ToUpper(sede.CodeSuffix)
+		if _, repeated := sedeBySuffix[suffix]; repeated {
+			sedeBySuffix[suffix] = "" // Sufijo ambiguo: no permite deducir la sede
+		} else {
+			sedeBySuffix[suffix] = sede.Code
+		}
+	}
+
+	codeByID := make(map[uint]string)
+	for _, subject := range subjects {
+		codeByID[subject.ID] = subject.Code
+		r.exact[subject.Code] = true
+		r.normalized[NormalizeSubjectCode(subject.Code)] = subject.Code
+		base := SubjectCodeBase(subject.Code)
+		r.bases[base] = append(r.bases[base], subject.Code)
+
+		if subject.SedeID != nil {
+			r.sedes[subject.Code] = sedeByID[*subject.SedeID]
+		} else if sede := sedeBySuffix[SubjectCodeSuffix(subject.Code)]; sede != "" {
+			r.sedes[subject.Code] = sede
+		}
+	}
+	for _, alias := range aliases {
+		if code, exists := codeByID[alias.SubjectID]; exists {
+			r.aliases[NormalizeSubjectCode(alias.Alias)] = code
+		}
+	}
+	for base := range r.bases {
+		sort.Strings(r.bases[base])
+	}
+	return r
+}
+
+// resolve busca la materia del catálogo que corresponde a un código. inPlan, si no es nil, indica las
+// materias que conoce el plan; sede es la sede donde se cursó la materia. Ambos se usan para elegir entre
+// variantes del código que solo difieren en el sufijo. Retorna el código encontrado y la forma en que se
+// resolvió; si el código ya es del catálogo la forma es vacía, y si no se encontró el código es vacío.
+func (r *codeResolver) resolve(code string, inPlan func(string) bool, sede string) (string, string) {
+	if r == nil {
+		return code, ""
+	}
+	if r.exact[code] {
+		// La variante de otra sede de una materia del plan cuenta como la materia del plan
+		if inPlan != nil && !inPlan(code) {
+			if variant := r.pickVariant(r.planVariants(SubjectCodeBase(code), inPlan), sede); variant != "" {
+				return variant, CodeResolutionSede
+			}
+		}
+		return code, ""
+	}
+
+	normalized := NormalizeSubjectCode(code)
+	if resolved, exists := r.normalized[normalized]; exists {
+		return resolved, CodeResolutionNormalized
+	}
+	if resolved, exists := r.aliases[normalized]; exists {
+		return resolved, CodeResolutionAlias
+	}
+
+	candidates := r.bases[SubjectCodeBase(normalized)]
+	if len(candidates) > 1 && inPlan != nil {
+		candidates = r.planVariants(SubjectCodeBase(normalized), inPlan)
+	}
+	if variant := r.pickVariant(candidates, sede); variant != "" {
+		return variant, CodeResolutionSuffix
+	}
+	return "", ""
+}
+
+// planVariants retorna las variantes de un código base que conoce el plan
+func (r *codeResolver) planVariants(base string, inPlan func(string) bool) []string {
+	var variants []string
+	for _, candidate := range r.bases[base] {
+		if inPlan(candidate) {
+			variants = append(variants, candidate)
+		}
+	}
+	return variants
+}
+
+// pickVariant elige una variante del código: la única que hay o, si hay varias, la única de la sede indicada
+func (r *codeResolver) pickVariant(candidates []string, sede string) string {
+	if len(candidates) > 1 && sede != "" {
+		var sedeCandidates []string
+		for _, candidate := range candidates {
+			if r.sedes[candidate] == sede {
+				sedeCandidates = append(sedeCandidates, candidate)
+			}
+		}
+		candidates = sedeCandidates
+	}
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	return ""
+}
+
+// historySede retorna la sede con la que se resuelven los códigos de una historia académica: la indicada
+// en la historia o, si no se indicó, la de la facultad de la carrera del plan
+func historySede(studyPlan models.StudyPlan, academicHistory models.AcademicHistoryInput) string {
+	if sede := strings.ToUpper(strings.TrimSpace(academicHistory.Sede)); sede != "" {
+		return sede
+	}
+	if studyPlan.Career.Faculty != nil {
+		return studyPlan.Career.Faculty.Sede.Code
+	}
+	return ""
+}
+
+// resolveHistory retorna una copia de la historia académica con los códigos traducidos a los del catálogo
+// y el diagnóstico de las traducciones hechas
+func (pc *planContext) resolveHistory(academicHistory models.AcademicHistoryInput) (models.AcademicHistoryInput, *models.ComparisonDiagnostics) {
+	diagnostics := &models.ComparisonDiagnostics{
+		CodeResolutions: []models.CodeResolution{},
+		UnresolvedCodes: []string{},
+	}
+	// El plan conoce sus materias y las que participan en sus equivalencias
+	inPlan := func(code string) bool {
+		_, exists := pc.subjectsByCode[code]
+		return exists || len(pc.equivalenceMap[code]) > 0
+	}
+	sede := historySede(pc.StudyPlan, academicHistory)
+
+	resolved := academicHistory
+	resolved.Subjects = make([]models.SubjectInput, len(academicHistory.Subjects))
+	seen := make(map[string]bool)
+	for i, historySubject := range academicHistory.Subjects {
+		code, method := pc.resolver.resolve(historySubject.Code, inPlan, sede)
+		switch {
+		case code == "":
+			if historySubject.Status == "APROBADA" && !seen[historySubject.Code] {
+				diagnostics.UnresolvedCodes = append(diagnostics.UnresolvedCodes, historySubject.Code)
+			}
+		case method != "":
+			if !seen[historySubject.Code] {
+				diagnostics.CodeResolutions = append(diagnostics.CodeResolutions, models.CodeResolution{
+					HistoryCode:  historySubject.Code,
+					ResolvedCode: code,
+					Method:       method,
+				})
+			}
+			historySubject.Code = code
+		}
+		seen[academicHistory.Subjects[i].Code] = true
+		resolved.Subjects[i] = historySubject
+	}
+	return resolved, diagnostics
+}
+
+// ListSubjectAliases obtiene los alias registrados para una materia
+func ListSubjectAliases(db *gorm.DB, subject models.Subject) ([]models.SubjectAlias, error) {
+	var aliases []models.SubjectAlias
+	if err := db.Where("subject_id = ?", subject.ID).Order("alias").Find(&aliases).Error; err != nil {
+		return nil, errors.New("error obteniendo los alias de la materia")
+	}
+	return aliases, nil
+}
+
+// AddSubjectAlias registra un alias para una materia. El alias se guarda normalizado y no puede
+// coincidir con el código de otra materia ni con un alias ya registrado.
+func AddSubjectAlias(db *gorm.DB, subject models.Subject, alias, notes string, who AuditActor) (*models.SubjectAlias, error) {
+	normalized := NormalizeSubjectCode(alias)
+	if !models.ValidarCodigoMateria(normalized) {
+		return nil, &SubjectAliasError{Message: "alias inválido: use letras mayúsculas, dígitos y guiones (máximo 20 caracteres)"}
+	}
+	if normalized == NormalizeSubjectCode(subject.Code) {
+		return nil, &SubjectAliasError{Message: "el alias es el mismo código de la materia"}
+	}
+
+	var subjects []models.Subject
+	if err := db.Select("id", "code").Find(&subjects).Error; err != nil {
+		return nil, errors.New("error verificando los códigos de las materias")
+	}
+	for _, other := range subjects {
+		if NormalizeSubjectCode(other.Code) == normalized {
+			return nil, &SubjectAliasConflictError{Message: "el alias " + normalized + " es el código de la materia " + other.Code}
+		}
+	}
+
+	var existing models.SubjectAlias
+	err := db.Preload("Subject").Where("alias = ?", normalized).First(&existing).Error
+	if err == nil {
+		return nil, &SubjectAliasConflictError{Message: "el alias " + normalized + " ya está registrado para la materia " + existing.Subject.Code}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("error verificando los alias registrados")
+	}
+
+	subjectAlias := models.SubjectAlias{
+		Alias:     normalized,
+		SubjectID: subject.ID,
+		Notes:     strings.TrimSpace(notes),
+		CreatedBy: who.Actor,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&subjectAlias).Error; err != nil {
+			return errors.New("error guardando el alias")
+		}
+		return RecordAudit(tx, who, AuditActionAddAlias, AuditEntitySubject, subject.ID, subject.Code, nil, subjectAlias)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	InvalidateCatalog()
+	return &subjectAlias, nil
+}
+
+// RemoveSubjectAlias elimina un alias de una materia
+func RemoveSubjectAlias(db *gorm.DB, subject models.Subject, alias string, who AuditActor) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing models.SubjectAlias
+		if err := tx.Where("subject_id = ? AND alias = ?", subject.ID, NormalizeSubjectCode(alias)).First(&existing).Error; err != nil {
+			return ErrSubjectAliasNotFound
+		}
+		if err := tx.Delete(&existing).Error; err != nil {
+			return errors.New("error eliminando el alias")
+		}
+		return RecordAudit(tx, who, AuditActionRemoveAlias, AuditEntitySubject, subject.ID, subject.Code, existing, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	InvalidateCatalog()
+	return nil
+}
@@ -0,0 +1,114 @@
+package functions
+
+import (
+	"testing"
+
+	"olimpo-vicedecanatura/models"
+)
+
+func TestNormalizeSubjectCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"1000004", "1000004"},
+		{"  01000004 - m", "1000004-M"},
+		{"001000004-M", "1000004-M"},
+		{"1000004–m", "1000004-M"},
+		{"abc_12", "ABC-12"},
+		{"000", "0"},
+		{"0A12", "0A12"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeSubjectCode(tt.code); got != tt.want {
+			t.Errorf("NormalizeSubjectCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestSubjectCodeSuffixAndBase(t *testing.T) {
+	tests := []struct {
+		code   string
+		suffix string
+		base   string
+	}{
+		{"1000004", "", "1000004"},
+		{"1000004-m", "M", "1000004"},
+		{"01000004 - MB", "MB", "1000004"},
+		{"ABC-12", "", "ABC-12"},
+	}
+
+	for _, tt := range tests {
+		if got := SubjectCodeSuffix(tt.code); got != tt.suffix {
+			t.Errorf("SubjectCodeSuffix(%q) = %q, want %q", tt.code, got, tt.suffix)
+		}
+		if got := SubjectCodeBase(tt.code); got != tt.base {
+			t.Errorf("SubjectCodeBase(%q) = %q, want %q", tt.code, got, tt.base)
+		}
+	}
+}
+
+func TestCodeResolverResolve(t *testing.T) {
+	sedes := []models.Sede{
+		{ID: 1, Code: "BOG", CodeSuffix: "B"},
+		{ID: 2, Code: "MED", CodeSuffix: "M"},
+		{ID: 3, Code: "MAN", CodeSuffix: "X"},
+		{ID: 4, Code: "ORI", CodeSuffix: "X"}, // Sufijo repetido: no permite deducir la sede
+	}
+	man := uint(3)
+	subjects := []models.Subject{
+		{ID: 1, Code: "1000004"},
+		{ID: 2, Code: "2015555-B"},
+		{ID: 3, Code: "2015555-M"},
+		{ID: 4, Code: "5000001-X"},
+		{ID: 5, Code: "5000001-Y", SedeID: &man},
+		{ID: 6, Code: "6000001-X"},
+		{ID: 7, Code: "6000001-Z"},
+	}
+	aliases := []models.SubjectAlias{{SubjectID: 1, Alias: "ANT-04"}}
+	resolver := newCodeResolver(subjects, aliases, sedes)
+
+	planWith := func(codes ...string) func(string) bool {
+		return func(code string) bool {
+			for _, planCode := range codes {
+				if planCode == code {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	tests := []struct {
+		name       string
+		code       string
+		inPlan     func(string) bool
+		sede       string
+		wantCode   string
+		wantMethod string
+	}{
+		{"código del catálogo", "1000004", nil, "", "1000004", ""},
+		{"ceros y espacios", " 01000004 ", nil, "", "1000004", CodeResolutionNormalized},
+		{"alias", "ant-04", nil, "", "1000004", CodeResolutionAlias},
+		{"sufijo de una materia sin variantes", "1000004-M", nil, "", "1000004", CodeResolutionSuffix},
+		{"variantes de sede sin sede", "2015555", nil, "", "", ""},
+		{"variante de la sede indicada", "2015555", nil, "MED", "2015555-M", CodeResolutionSuffix},
+		{"sufijo desconocido con sede", "2015555-C", nil, "BOG", "2015555-B", CodeResolutionSuffix},
+		{"sede registrada en la materia", "5000001", nil, "MAN", "5000001-Y", CodeResolutionSuffix},
+		{"sufijo de sede ambiguo", "5000001", nil, "ORI", "", ""},
+		{"variantes sin sede conocida", "6000001", nil, "BOG", "", ""},
+		{"variante de otra sede de una materia del plan", "2015555-M", planWith("2015555-B"), "", "2015555-B", CodeResolutionSede},
+		{"el plan elige entre variantes", "2015555", planWith("2015555-B"), "", "2015555-B", CodeResolutionSuffix},
+		{"código desconocido", "9999999", nil, "BOG", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, method := resolver.resolve(tt.code, tt.inPlan, tt.sede)
+			if code != tt.wantCode || method != tt.wantMethod {
+				t.Errorf("resolve(%q, sede %q) = (%q, %q), want (%q, %q)", tt.code, tt.sede, code, method, tt.wantCode, tt.wantMethod)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package functions
+
+import (
+	"olimpo-vicedecanatura/models"
+)
+
+// DiffComparisonResults compara dos resultados (del mismo estudiante en dos momentos, o de la misma
+// historia contra dos planes) y lista los cambios de estado, de créditos y de equivalencias
+func DiffComparisonResults(from, to *models.ComparisonResult) *models.ComparisonDiff {
+	diff := &models.ComparisonDiff{}
+
+	fromSubjects, fromOrder := indexSubjectResults(from)
+	toSubjects, toOrder := indexSubjectResults(to)
+
+	// 1. Materias que cambiaron de estado o de equivalencia
+	for _, code := range fromOrder {
+		fromSubject := fromSubjects[code]
+		toSubject, exists := toSubjects[code]
+		if !exists {
+			diff.RemovedSubjects = append(diff.RemovedSubjects, fromSubject)
+			continue
+		}
+		if fromSubject.Status != toSubject.Status {
+			diff.StatusChanges = append(diff.StatusChanges, models.SubjectStatusChange{
+				Code:       code,
+				Name:       toSubject.Name,
+				FromStatus: fromSubject.Status,
+				ToStatus:   toSubject.Status,
+			})
+		}
+		if !sameEquivalence(fromSubject.Equivalence, toSubject.Equivalence) {
+			diff.EquivalenceChanges = append(diff.EquivalenceChanges, models.EquivalenceChange{
+				Code: code,
+				Name: toSubject.Name,
+				From: fromSubject.Equivalence,
+				To:   toSubject.Equivalence,
+			})
+		}
+	}
+	for _, code := range toOrder {
+		if _, exists := fromSubjects[code]; !exists {
+			diff.AddedSubjects = append(diff.AddedSubjects, toSubjects[code])
+		}
+	}
+
+	// 2. Variación de créditos por tipología
+	typologies := []struct {
+		name     string
+		from, to models.CreditTypeInfo
+	}{
+		{"fund_obligatoria", from.CreditsSummary.FundObligatoria, to.CreditsSummary.FundObligatoria},
+		{"fund_optativa", from.CreditsSummary.FundOptativa, to.CreditsSummary.FundOptativa},
+		{"dis_obligatoria", from.CreditsSummary.DisObligatoria, to.CreditsSummary.DisObligatoria},
+		{"dis_optativa", from.CreditsSummary.DisOptativa, to.CreditsSummary.DisOptativa},
+		{"libre", from.CreditsSummary.Libre, to.CreditsSummary.Libre},
+		{"total", from.CreditsSummary.Total, to.CreditsSummary.Total},
+	}
+	for _, typology := range typologies {
+		if typology.from != typology.to {
+			diff.CreditDeltas = append(diff.CreditDeltas, newCreditDelta(typology.name, typology.from, typology.to))
+		}
+	}
+
+	// 3. Variación de créditos por agrupación
+	fromGroups := make(map[string]models.GroupProgress)
+	for _, group := range from.GroupsSummary {
+		fromGroups[group.Name] = group
+	}
+	seenGroups := make(map[string]bool)
+	for _, group := range to.GroupsSummary {
+		seenGroups[group.Name] = true
+		previous := fromGroups[group.Name]
+		fromInfo := models.CreditTypeInfo{Required: previous.Required, Completed: previous.Completed, Missing: previous.Missing}
+		toInfo := models.CreditTypeInfo{Required: group.Required, Completed: group.Completed, Missing: group.Missing}
+		if fromInfo != toInfo {
+			diff.GroupDeltas = append(diff.GroupDeltas, newCreditDelta(group.Name, fromInfo, toInfo))
+		}
+	}
+	for _, group := range from.GroupsSummary {
+		if !seenGroups[group.Name] {
+			fromInfo := models.CreditTypeInfo{Required: group.Required, Completed: group.Completed, Missing: group.Missing}
+			diff.GroupDeltas = append(diff.GroupDeltas, newCreditDelta(group.Name, fromInfo, models.CreditTypeInfo{}))
+		}
+	}
+
+	return diff
+}
+
+// indexSubjectResults indexa por código todas las materias de un resultado, conservando su orden
+func indexSubjectResults(result *models.ComparisonResult) (map[string]models.SubjectResult, []string) {
+	subjects := make(map[string]models.SubjectResult)
+	var order []string
+	for _, list := range [][]models.SubjectResult{result.EquivalentSubjects, result.MissingSubjects} {
+		for _, subject := range list {
+			if _, exists := subjects[subject.Code]; !exists {
+				order = append(order, subject.Code)
+			}
+			subjects[subject.Code] = subject
+		}
+	}
+	return subjects, order
+}
+
+// sameEquivalence indica si dos materias se resolvieron con la misma equivalencia
+func sameEquivalence(a, b *models.EquivalenceResult) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// newCreditDelta calcula la variación entre dos resúmenes de créditos
+func newCreditDelta(name string, from, to models.CreditTypeInfo) models.CreditDelta {
+	return models.CreditDelta{
+		Name:           name,
+		FromRequired:   from.Required,
+		ToRequired:     to.Required,
+		FromCompleted:  from.Completed,
+		ToCompleted:    to.Completed,
+		CompletedDelta: to.Completed - from.Completed,
+		FromMissing:    from.Missing,
+		ToMissing:      to.Missing,
+		MissingDelta:   to.Missing - from.Missing,
+	}
+}
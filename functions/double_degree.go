@@ -0,0 +1,182 @@
+package functions
+
+import (
+	"errors"
+	"sort"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// DefaultMaxSharedCreditsPercentage es el porcentaje de créditos del segundo plan que, por defecto,
+// puede cubrirse con materias aprobadas en el primer plan
+const DefaultMaxSharedCreditsPercentage = 50
+
+// typologyPriority define el orden en que se reconocen las materias compartidas:
+// primero las obligatorias, luego las optativas y por último libre elección
+var typologyPriority = map[string]int{
+	"fund.obligatoria": 0,
+	"dis.obligatoria":  1,
+	"fund.optativa":    2,
+	"dis.optativa":     3,
+	"libre":            4,
+}
+
+// sharedCandidate representa una materia aprobada que podría reconocerse en el segundo plan
+type sharedCandidate struct {
+	history       models.SubjectInput
+	targetSubject *models.Subject
+	bucket        string
+}
+
+// CompareDoubleDegree analiza qué materias aprobadas en el plan de origen pueden reconocerse
+// en un segundo plan, respetando el límite de créditos compartidos y los cupos por tipología.
+// Solo se comparten las materias de la historia que cuentan en el plan de origen, directamente o
+// por equivalencia; las demás no hacen parte de la primera carrera.
+func CompareDoubleDegree(db *gorm.DB, academicHistory models.AcademicHistoryInput, homeStudyPlanID, targetStudyPlanID uint, maxSharedPercentage int) (*models.DoubleDegreeResult, error) {
+	if homeStudyPlanID == targetStudyPlanID {
+		return nil, errors.New("el segundo plan de estudio debe ser distinto al plan de origen")
+	}
+	if maxSharedPercentage <= 0 || maxSharedPercentage > 100 {
+		return nil, errors.New("el porcentaje de créditos compartidos debe estar entre 1 y 100")
+	}
+
+	home, err := loadPlanContext(db, homeStudyPlanID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := loadPlanContext(db, targetStudyPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return analyzeDoubleDegree(home, target, academicHistory, maxSharedPercentage), nil
+}
+
+// analyzeDoubleDegree realiza el análisis de doble titulación sobre planes ya cargados
+func analyzeDoubleDegree(home, target *planContext, academicHistory models.AcademicHistoryInput, maxSharedPercentage int) *models.DoubleDegreeResult {
+	academicHistory, diagnostics := home.resolveHistory(academicHistory)
+	approvedSubjects := approvedCodes(academicHistory)
+	homeResult := home.compare(approvedSubjects)
+	homeResult.Diagnostics = diagnostics
+
+	// Materias aprobadas que cuentan en el plan de origen: son las únicas que pueden compartirse
+	homeApproved := make(map[string]bool)
+	for _, homeSubject := range home.StudyPlan.Subjects {
+		if matchedCode, _ := home.matchSubject(homeSubject, approvedSubjects); matchedCode != "" {
+			homeApproved[matchedCode] = true
+		}
+	}
+	historyByCode := make(map[string]models.SubjectInput)
+	for _, historySubject := range academicHistory.Subjects {
+		if homeApproved[historySubject.Code] {
+			historyByCode[historySubject.Code] = historySubject
+		}
+	}
+
+	// 1. Buscar las materias del segundo plan que se satisfacen con la historia del primero
+	used := make(map[string]bool)
+	var candidates []sharedCandidate
+	for i := range target.StudyPlan.Subjects {
+		targetSubject := &target.StudyPlan.Subjects[i]
+		matchedCode, _ := target.matchSubject(*targetSubject, homeApproved)
+		if matchedCode == "" || used[matchedCode] {
+			continue
+		}
+		used[matchedCode] = true
+		candidates = append(candidates, sharedCandidate{
+			history:       historyByCode[matchedCode],
+			targetSubject: targetSubject,
+			bucket:        typologyBucket(targetSubject.Type),
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return typologyPriority[candidates[i].bucket] < typologyPriority[candidates[j].bucket]
+	})
+
+	// Las materias del plan de origen que no están en el segundo plan solo pueden contar como libre elección
+	for _, historySubject := range academicHistory.Subjects {
+		if !homeApproved[historySubject.Code] || used[historySubject.Code] {
+			continue
+		}
+		used[historySubject.Code] = true
+		candidates = append(candidates, sharedCandidate{history: historySubject, bucket: "libre"})
+	}
+
+	// 2. Reconocer créditos respetando el cupo de cada tipología y el límite de créditos compartidos
+	remaining := map[string]int{
+		"fund.obligatoria": target.StudyPlan.FundObligatoriaCredits,
+		"fund.optativa":    target.StudyPlan.FundOptativaCredits,
+		"dis.obligatoria":  target.StudyPlan.DisObligatoriaCredits,
+		"dis.optativa":     target.StudyPlan.DisOptativaCredits,
+		"libre":            target.StudyPlan.LibreCredits,
+	}
+	maxSharedCredits := target.StudyPlan.TotalCredits * maxSharedPercentage / 100
+	sharedCredits := 0
+	creditsByType := make(map[string]int)
+	recognizedTargetCodes := make(map[string]bool)
+
+	result := &models.DoubleDegreeResult{MaxSharedCredits: maxSharedCredits}
+
+	for _, candidate := range candidates {
+		shared := models.SharedSubjectResult{
+			HistoryCode: candidate.history.Code,
+			HistoryName: candidate.history.Name,
+			Credits:     candidate.history.Credits,
+			Type:        models.TipologiaLibreEleccion,
+		}
+		if candidate.targetSubject != nil {
+			shared.TargetCode = candidate.targetSubject.Code
+			shared.TargetName = candidate.targetSubject.Name
+			shared.Credits = candidate.targetSubject.Credits
+			shared.Type = candidate.targetSubject.Type
+		}
+
+		bucket := candidate.bucket
+		if bucket == "" {
+			bucket = "libre"
+			shared.Type = models.TipologiaLibreEleccion
+		}
+		// Los créditos optativos que exceden el cupo de su tipología pasan a libre elección
+		if remaining[bucket] < shared.Credits && bucket != "libre" && typologyPriority[bucket] >= typologyPriority["fund.optativa"] {
+			bucket = "libre"
+			shared.Type = models.TipologiaLibreEleccion
+		}
+
+		switch {
+		case sharedCredits+shared.Credits > maxSharedCredits:
+			shared.Status = "NO RECONOCIDA"
+			shared.Reason = "Supera el límite de créditos compartidos entre los dos planes"
+		case remaining[bucket] < shared.Credits:
+			shared.Status = "NO RECONOCIDA"
+			shared.Reason = "El cupo de créditos de la tipología " + string(shared.Type) + " ya está completo"
+		default:
+			shared.Status = "RECONOCIDA"
+			shared.Reason = "Reconocida como " + string(shared.Type)
+			remaining[bucket] -= shared.Credits
+			sharedCredits += shared.Credits
+			creditsByType[bucket] += shared.Credits
+			if shared.TargetCode != "" {
+				recognizedTargetCodes[shared.TargetCode] = true
+			}
+		}
+
+		if shared.Status == "RECONOCIDA" {
+			result.SharedSubjects = append(result.SharedSubjects, shared)
+		} else {
+			result.ExcludedSubjects = append(result.ExcludedSubjects, shared)
+		}
+	}
+
+	// 3. Calcular la situación del estudiante en cada plan
+	targetResult := target.compare(recognizedTargetCodes)
+	target.summarize(targetResult, creditsByType)
+
+	result.HomePlan = *homeResult
+	result.TargetPlan = *targetResult
+	result.SharedCredits = sharedCredits
+	result.HomeCreditsOwed = homeResult.CreditsSummary.Total.Missing
+	result.TargetCreditsOwed = targetResult.CreditsSummary.Total.Missing
+
+	return result
+}
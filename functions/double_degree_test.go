@@ -0,0 +1,58 @@
+package functions
+
+import (
+	"sort"
+	"testing"
+
+	"olimpo-vicedecanatura/models"
+)
+
+func TestAnalyzeDoubleDegreeSharesOnlyHomePlanSubjects(t *testing.T) {
+	home := newPlanContext(models.StudyPlan{
+		ID:           1,
+		TotalCredits: 100,
+		Subjects: []models.Subject{
+			{Code: "H1", Name: "Propia del origen", Credits: 3, Type: models.TipologiaFundamentalObligatoria},
+			{Code: "S1", Name: "Común", Credits: 4, Type: models.TipologiaFundamentalObligatoria},
+		},
+	}, nil)
+	target := newPlanContext(models.StudyPlan{
+		ID:                    2,
+		TotalCredits:          100,
+		DisObligatoriaCredits: 20,
+		LibreCredits:          20,
+		Subjects: []models.Subject{
+			{Code: "S1", Name: "Común", Credits: 4, Type: models.TipologiaDisciplinarObligatoria},
+			{Code: "T1", Name: "Propia del destino", Credits: 3, Type: models.TipologiaDisciplinarObligatoria},
+		},
+	}, nil)
+
+	history := models.AcademicHistoryInput{Subjects: []models.SubjectInput{
+		{Code: "H1", Name: "Propia del origen", Credits: 3, Status: "APROBADA"},
+		{Code: "S1", Name: "Común", Credits: 4, Status: "APROBADA"},
+		{Code: "T1", Name: "Propia del destino", Credits: 3, Status: "APROBADA"}, // No es del plan de origen
+		{Code: "X1", Name: "Fuera de ambos planes", Credits: 2, Status: "APROBADA"},
+	}}
+
+	result := analyzeDoubleDegree(home, target, history, DefaultMaxSharedCreditsPercentage)
+
+	var shared []string
+	for _, subject := range append(result.SharedSubjects, result.ExcludedSubjects...) {
+		shared = append(shared, subject.HistoryCode)
+	}
+	sort.Strings(shared)
+	if len(shared) != 2 || shared[0] != "H1" || shared[1] != "S1" {
+		t.Fatalf("materias consideradas = %v, want [H1 S1]", shared)
+	}
+	if result.SharedCredits != 7 {
+		t.Errorf("SharedCredits = %d, want 7", result.SharedCredits)
+	}
+	for _, subject := range result.SharedSubjects {
+		if subject.HistoryCode == "S1" && subject.Type != models.TipologiaDisciplinarObligatoria {
+			t.Errorf("S1 se reconoció como %s, want %s", subject.Type, models.TipologiaDisciplinarObligatoria)
+		}
+		if subject.HistoryCode == "H1" && subject.Type != models.TipologiaLibreEleccion {
+			t.Errorf("H1 se reconoció como %s, want %s", subject.Type, models.TipologiaLibreEleccion)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package functions
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"olimpo-vicedecanatura/models"
+)
+
+// Acciones del flujo de aprobación de equivalencias
+const (
+	EquivalenceActionReview  = "review"
+	EquivalenceActionApprove = "approve"
+	EquivalenceActionReject  = "reject"
+	EquivalenceActionRetire  = "retire"
+)
+
+// ErrEquivalenceNotFound se retorna cuando la equivalencia solicitada no existe
+var ErrEquivalenceNotFound = errors.New("equivalencia no encontrada")
+
+// ErrEquivalenceSameSubject se retorna al proponer una equivalencia de una materia consigo misma
+var ErrEquivalenceSameSubject = errors.New("una materia no puede ser equivalente a sí misma")
+
+// ErrEquivalenceExists se retorna al proponer una equivalencia para un par de materias que ya tiene una
+// aprobada o en trámite en el plan
+var ErrEquivalenceExists = errors.New("ya existe una equivalencia aprobada o en trámite para estas materias en el plan")
+
+// EquivalenceDecisionError se retorna cuando a una decisión le faltan datos que exige la acción
+type EquivalenceDecisionError struct {
+	Message string
+}
+
+func (e *EquivalenceDecisionError) Error() string {
+	return e.Message
+}
+
+// EquivalenceStateError se retorna cuando el estado de la equivalencia no permite la acción solicitada
+type EquivalenceStateError struct {
+	Message string
+}
+
+func (e *EquivalenceStateError) Error() string {
+	return e.Message
+}
+
+// ActiveEquivalenceStatuses son los estados de una equivalencia vigente o en trámite
+var ActiveEquivalenceStatuses = []string{models.EquivalenceStatusProposed, models.EquivalenceStatusInReview, models.EquivalenceStatusApproved}
+
+// equivalenceTransitions indica, para cada acción, desde qué estados puede tomarse y a qué estado lleva
+var equivalenceTransitions = map[string]struct {
+	from []string
+	to   string
+}{
+	EquivalenceActionReview:  {[]string{models.EquivalenceStatusProposed}, models.EquivalenceStatusInReview},
+	EquivalenceActionApprove: {[]string{models.EquivalenceStatusProposed, models.EquivalenceStatusInReview}, models.EquivalenceStatusApproved},
+	EquivalenceActionReject:  {[]string{models.EquivalenceStatusProposed, models.EquivalenceStatusInReview}, models.EquivalenceStatusRejected},
+	EquivalenceActionRetire:  {[]string{models.EquivalenceStatusApproved}, models.EquivalenceStatusRetired},
+}
+
+// EquivalenceDecision son los datos de una decisión del comité sobre una equivalencia
+type EquivalenceDecision struct {
+	ResolutionNumber string
+	ResolutionDate   *time.Time
+	Notes            string
+	ReviewedBy       string
+}
+
+// ProposeEquivalence registra una equivalencia propuesta entre dos materias para un plan de estudio
+func ProposeEquivalence(db *gorm.DB, equivalence *models.Equivalence, who AuditActor) error {
+	if equivalence.SourceSubjectID == equivalence.TargetSubjectID {
+		return ErrEquivalenceSameSubject
+	}
+
+	equivalence.Status = models.EquivalenceStatusProposed
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Se bloquea el plan para que dos propuestas simultáneas del mismo par no pasen ambas la verificación
+		var studyPlan models.StudyPlan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&studyPlan, equivalence.StudyPlanID).Error; err != nil {
+			return ErrStudyPlanNotFound
+		}
+
+		// No se permiten dos equivalencias vigentes o en trámite para el mismo par de materias en el mismo plan
+		var count int64
+		if err := tx.Model(&models.Equivalence{}).
+			Where("source_subject_id = ? AND target_subject_id = ? AND study_plan_id = ? AND status IN ?",
+				equivalence.SourceSubjectID, equivalence.TargetSubjectID, equivalence.StudyPlanID, ActiveEquivalenceStatuses).
+			Count(&count).Error; err != nil {
+			return errors.New("error verificando las equivalencias existentes")
+		}
+		if count > 0 {
+			return ErrEquivalenceExists
+		}
+
+		if err := tx.Create(equivalence).Error; err != nil {
+			return errors.New("error guardando la equivalencia")
+		}
+		return RecordAudit(tx, who, AuditActionCreate, AuditEntityEquivalence, equivalence.ID, EquivalenceAuditKey(tx, *equivalence), nil, equivalence)
+	})
+}
+
+// DecideEquivalence aplica una acción del flujo de aprobación. Aprobar exige el número y la fecha de la
+// resolución; rechazar y retirar exigen una justificación. La decisión queda en la auditoría; si no se da
+// otra justificación se usan las notas de la decisión.
+func DecideEquivalence(db *gorm.DB, equivalenceID uint, action string, decision EquivalenceDecision, who AuditActor) (*models.Equivalence, error) {
+	transition, exists := equivalenceTransitions[action]
+	if !exists {
+		return nil, &EquivalenceDecisionError{Message: "acción desconocida: " + action}
+	}
+
+	decision.ResolutionNumber = strings.TrimSpace(decision.ResolutionNumber)
+	decision.Notes = strings.TrimSpace(decision.Notes)
+	switch action {
+	case EquivalenceActionApprove:
+		if decision.ResolutionNumber == "" || decision.ResolutionDate == nil {
+			return nil, &EquivalenceDecisionError{Message: "para aprobar una equivalencia se requieren el número y la fecha de la resolución"}
+		}
+	case EquivalenceActionReject, EquivalenceActionRetire:
+		if decision.Notes == "" {
+			return nil, &EquivalenceDecisionError{Message: "se requiere una justificación en notes"}
+		}
+	}
+
+	var equivalence models.Equivalence
+	if err := db.First(&equivalence, equivalenceID).Error; err != nil {
+		return nil, ErrEquivalenceNotFound
+	}
+
+	allowed := false
+	for _, status := range transition.from {
+		if equivalence.Status == status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, &EquivalenceStateError{Message: "no se puede aplicar la acción " + action + " a una equivalencia en estado " + equivalence.Status}
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":       transition.to,
+		"reviewed_by":  decision.ReviewedBy,
+		"review_notes": decision.Notes,
+		"reviewed_at":  now,
+	}
+	if action == EquivalenceActionApprove {
+		updates["resolution_number"] = decision.ResolutionNumber
+		updates["resolution_date"] = *decision.ResolutionDate
+	}
+
+	if who.Reason == "" {
+		who.Reason = decision.Notes
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// El estado se vuelve a verificar al actualizar por si otra decisión se tomó al mismo tiempo
+		result := tx.Model(&models.Equivalence{}).
+			Where("id = ? AND status = ?", equivalence.ID, equivalence.Status).
+			Updates(updates)
+		if result.Error != nil {
+			return errors.New("error actualizando la equivalencia")
+		}
+		if result.RowsAffected == 0 {
+			return &EquivalenceStateError{Message: "la equivalencia cambió de estado mientras se procesaba la decisión"}
+		}
+
+		var after models.Equivalence
+		if err := tx.First(&after, equivalence.ID).Error; err != nil {
+			return ErrEquivalenceNotFound
+		}
+		return RecordAudit(tx, who, strings.ToUpper(action), AuditEntityEquivalence, equivalence.ID, EquivalenceAuditKey(tx, equivalence), equivalence, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Aprobar o retirar cambia las equivalencias que usa el motor de comparación
+	if action == EquivalenceActionApprove || action == EquivalenceActionRetire {
+		InvalidateCatalog()
+	}
+
+	if err := db.Preload("SourceSubject").Preload("TargetSubject").First(&equivalence, equivalence.ID).Error; err != nil {
+		return nil, ErrEquivalenceNotFound
+	}
+	return &equivalence, nil
+}
+
+// DeleteEquivalence elimina una equivalencia que no esté aprobada; las aprobadas deben retirarse primero para
+// que la decisión del comité quede registrada. El borrado es lógico y queda en la auditoría.
+func DeleteEquivalence(db *gorm.DB, equivalenceID uint, who AuditActor) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var equivalence models.Equivalence
+		if err := tx.First(&equivalence, equivalenceID).Error; err != nil {
+			return ErrEquivalenceNotFound
+		}
+		if equivalence.Status == models.EquivalenceStatusApproved {
+			return &EquivalenceStateError{Message: "una equivalencia aprobada debe retirarse antes de eliminarla"}
+		}
+
+		before := equivalence
+		if err := tx.Delete(&equivalence).Error; err != nil {
+			return errors.New("error eliminando la equivalencia")
+		}
+		return RecordAudit(tx, who, AuditActionDelete, AuditEntityEquivalence, equivalence.ID, EquivalenceAuditKey(tx, before), before, nil)
+	})
+}
@@ -0,0 +1,114 @@
+package functions
+
+import (
+	"strings"
+	"time"
+
+	"olimpo-vicedecanatura/models"
+)
+
+// CompareOptions configura el comportamiento del motor de comparación
+type CompareOptions struct {
+	Explain bool       // Incluir la traza de cada materia del plan en el resultado
+	AsOf    *time.Time // Comparar contra el catálogo de ese instante; nulo usa el catálogo vigente
+}
+
+// explain agrega a cada materia del resultado la traza de cómo se llegó a su estado
+func (pc *planContext) explain(result *models.ComparisonResult, academicHistory models.AcademicHistoryInput) {
+	historyByCode := make(map[string][]models.SubjectInput)
+	for _, historySubject := range academicHistory.Subjects {
+		historyByCode[historySubject.Code] = append(historyByCode[historySubject.Code], historySubject)
+	}
+	approvedSubjects := approvedCodes(academicHistory)
+
+	for _, list := range [][]models.SubjectResult{result.EquivalentSubjects, result.MissingSubjects} {
+		for i := range list {
+			planSubject, exists := pc.subjectsByCode[list[i].Code]
+			if !exists {
+				continue
+			}
+			list[i].Trace = pc.traceSubject(*planSubject, historyByCode, approvedSubjects)
+		}
+	}
+}
+
+// traceSubject reconstruye la decisión del motor para una materia del plan
+func (pc *planContext) traceSubject(planSubject models.Subject, historyByCode map[string][]models.SubjectInput, approvedSubjects map[string]bool) *models.SubjectTrace {
+	matchedCode, _ := pc.matchSubject(planSubject, approvedSubjects)
+	candidateCodes := pc.equivalenceMap[planSubject.Code]
+
+	trace := &models.SubjectTrace{
+		HistoryEntries: []models.HistoryEntryTrace{},
+		Equivalence: models.EquivalenceTrace{
+			CandidateCodes: append([]string{}, candidateCodes...),
+		},
+	}
+
+	// 1. Entradas de la historia con el mismo código o con el código de una materia equivalente
+	used := false
+	consider := func(code, match string) {
+		for _, entry := range historyByCode[code] {
+			entryTrace := models.HistoryEntryTrace{
+				Code:     entry.Code,
+				Name:     entry.Name,
+				Status:   entry.Status,
+				Semester: entry.Semester,
+				Match:    match,
+			}
+			switch {
+			case entry.Status != "APROBADA":
+				entryTrace.Reason = "Estado " + entry.Status + ": solo se tienen en cuenta las materias aprobadas"
+			case used || code != matchedCode:
+				entryTrace.Reason = "La materia del plan ya quedó satisfecha con otra entrada de la historia"
+			default:
+				used = true
+				entryTrace.Used = true
+				if match == "DIRECTA" {
+					entryTrace.Reason = "Aprobada con el mismo código de la materia del plan"
+				} else {
+					entryTrace.Reason = "Aprobada y equivalente a la materia del plan"
+				}
+			}
+			trace.HistoryEntries = append(trace.HistoryEntries, entryTrace)
+		}
+	}
+	consider(planSubject.Code, "DIRECTA")
+	for _, code := range candidateCodes {
+		consider(code, "EQUIVALENCIA")
+	}
+
+	// 2. Regla de equivalencia aplicada
+	switch {
+	case matchedCode == planSubject.Code:
+		trace.Equivalence.Reason = "No se requiere equivalencia: la materia está aprobada directamente"
+	case matchedCode != "":
+		trace.Equivalence.Matched = true
+		trace.Equivalence.Rule = matchedCode + " ≡ " + planSubject.Code
+		trace.Equivalence.Reason = "La materia aprobada " + matchedCode + " es equivalente a " + planSubject.Code
+		for _, equiv := range pc.Equivalences {
+			if (equiv.SourceSubject.Code == matchedCode && equiv.TargetSubject.Code == planSubject.Code) ||
+				(equiv.TargetSubject.Code == matchedCode && equiv.SourceSubject.Code == planSubject.Code) {
+				trace.Equivalence.EquivalenceID = equiv.ID
+				trace.Equivalence.Rule = equiv.SourceSubject.Code + " → " + equiv.TargetSubject.Code + " (" + equiv.Type + ")"
+				break
+			}
+		}
+	case len(candidateCodes) == 0:
+		trace.Equivalence.Reason = "La materia no tiene equivalencias registradas"
+	default:
+		trace.Equivalence.Reason = "Ninguna de las materias equivalentes (" + strings.Join(candidateCodes, ", ") + ") aparece aprobada en la historia"
+	}
+
+	// 3. Grupo de créditos en el que quedan los créditos de la materia
+	switch {
+	case matchedCode == "":
+		trace.BucketReason = "La materia está pendiente; sus créditos no se suman"
+	case typologyBucket(planSubject.Type) == "":
+		trace.BucketReason = "La tipología " + string(planSubject.Type) + " no suma a ningún grupo de créditos"
+	default:
+		trace.Bucket = typologyBucket(planSubject.Type)
+		trace.BucketReason = "La materia cuenta como " + string(planSubject.Type) + " en el plan " + pc.StudyPlan.Version
+	}
+
+	return trace
+}
@@ -2,58 +2,158 @@ package functions
 
 import (
 	"errors"
+	"time"
 	"gorm.io/gorm"
 	"olimpo-vicedecanatura/models"
 )
 
-// CompareAcademicHistoryWithStudyPlan compara la historia académica de un estudiante con un plan de estudio
-func CompareAcademicHistoryWithStudyPlan(db *gorm.DB, academicHistory models.AcademicHistoryInput, studyPlanID uint) (*models.ComparisonResult, error) {
-	// 1. Obtener el plan de estudio con sus materias
-	var studyPlan models.StudyPlan
-	if err := db.Preload("Subjects").Preload("Career").First(&studyPlan, studyPlanID).Error; err != nil {
-		return nil, errors.New("plan de estudio no encontrado")
-	}
+// planContext agrupa un plan de estudio cargado con las equivalencias que le aplican,
+// de modo que pueda compararse contra varias historias sin volver a consultar la base de datos
+type planContext struct {
+	StudyPlan      models.StudyPlan
+	Equivalences   []models.Equivalence
+	subjectsByCode map[string]*models.Subject
+	equivalenceMap map[string][]string // código -> códigos equivalentes
+	resolver       *codeResolver       // Traduce los códigos de las historias a los del catálogo
+}
 
-	// 2. Obtener todas las equivalencias relevantes para las materias del plan
-	var studyPlanSubjectIDs []uint
-	for _, subject := range studyPlan.Subjects {
-		studyPlanSubjectIDs = append(studyPlanSubjectIDs, subject.ID)
+// loadPlanContext obtiene el plan de estudio con sus materias y equivalencias desde la copia
+// en memoria del catálogo, sin volver a consultar la base de datos
+func loadPlanContext(db *gorm.DB, studyPlanID uint) (*planContext, error) {
+	snapshot, err := CurrentCatalog(db)
+	if err != nil {
+		return nil, err
 	}
+	return snapshot.plan(studyPlanID)
+}
 
-	var equivalences []models.Equivalence
-	db.Preload("SourceSubject").Preload("TargetSubject").Where(
-		"source_subject_id IN ? OR target_subject_id IN ?", 
-		studyPlanSubjectIDs, studyPlanSubjectIDs,
-	).Find(&equivalences)
+// newPlanContext construye los mapas de búsqueda del plan y sus equivalencias
+func newPlanContext(studyPlan models.StudyPlan, equivalences []models.Equivalence) *planContext {
+	pc := &planContext{
+		StudyPlan:      studyPlan,
+		Equivalences:   equivalences,
+		subjectsByCode: make(map[string]*models.Subject),
+		equivalenceMap: make(map[string][]string),
+	}
 
 	// 3. Crear mapas para facilitar las búsquedas
-	studyPlanSubjectsMap := make(map[string]*models.Subject)
-	for i := range studyPlan.Subjects {
-		studyPlanSubjectsMap[studyPlan.Subjects[i].Code] = &studyPlan.Subjects[i]
+	for i := range pc.StudyPlan.Subjects {
+		pc.subjectsByCode[pc.StudyPlan.Subjects[i].Code] = &pc.StudyPlan.Subjects[i]
 	}
 
 	// Crear mapa de equivalencias
-	equivalenceMap := make(map[string][]string) // código -> códigos equivalentes
 	for _, equiv := range equivalences {
 		// Si la materia origen está en el plan, agregar la destino como equivalente
-		if _, exists := studyPlanSubjectsMap[equiv.SourceSubject.Code]; exists {
-			equivalenceMap[equiv.SourceSubject.Code] = append(equivalenceMap[equiv.SourceSubject.Code], equiv.TargetSubject.Code)
+		if _, exists := pc.subjectsByCode[equiv.SourceSubject.Code]; exists {
+			pc.equivalenceMap[equiv.SourceSubject.Code] = append(pc.equivalenceMap[equiv.SourceSubject.Code], equiv.TargetSubject.Code)
 		}
 		// Si la materia destino está en el plan, agregar la origen como equivalente
-		if _, exists := studyPlanSubjectsMap[equiv.TargetSubject.Code]; exists {
-			equivalenceMap[equiv.TargetSubject.Code] = append(equivalenceMap[equiv.TargetSubject.Code], equiv.SourceSubject.Code)
+		if _, exists := pc.subjectsByCode[equiv.TargetSubject.Code]; exists {
+			pc.equivalenceMap[equiv.TargetSubject.Code] = append(pc.equivalenceMap[equiv.TargetSubject.Code], equiv.SourceSubject.Code)
+		}
+	}
+
+	return pc
+}
+
+// ApplyPlanSubjectSettings reemplaza la tipología y los créditos generales de las materias del plan
+// por los definidos en la asociación study_plan_subjects para ese plan
+func ApplyPlanSubjectSettings(db *gorm.DB, studyPlan *models.StudyPlan) error {
+	var links []models.StudyPlanSubject
+	if err := db.Where("study_plan_id = ?", studyPlan.ID).Find(&links).Error; err != nil {
+		return errors.New("error obteniendo la tipología de las materias del plan")
+	}
+	applyPlanSubjectLinks(studyPlan, links)
+	return nil
+}
+
+// applyPlanSubjectLinks aplica a las materias del plan la tipología y los créditos de cada asociación
+func applyPlanSubjectLinks(studyPlan *models.StudyPlan, links []models.StudyPlanSubject) {
+	linksBySubject := make(map[uint]models.StudyPlanSubject)
+	for _, link := range links {
+		linksBySubject[link.SubjectID] = link
+	}
+	for i := range studyPlan.Subjects {
+		link, exists := linksBySubject[studyPlan.Subjects[i].ID]
+		if !exists {
+			continue
+		}
+		if link.Type != "" {
+			studyPlan.Subjects[i].Type = link.Type
 		}
+		if link.Credits > 0 {
+			studyPlan.Subjects[i].Credits = link.Credits
+		}
+	}
+}
+
+// typologyBucket traduce una tipología a la clave del resumen de créditos con la que se acumulan los
+// créditos aprobados. El trabajo de grado cuenta en el componente disciplinar obligatorio: los planes no exigen créditos
+// propios para él, así que sin esta regla sus créditos no contarían en ninguna tipología.
+func typologyBucket(tipo models.TipologiaAsignatura) string {
+	switch tipo {
+	case models.TipologiaFundamentalObligatoria:
+		return "fund.obligatoria"
+	case models.TipologiaFundamentalOptativa:
+		return "fund.optativa"
+	case models.TipologiaDisciplinarObligatoria, models.TipologiaTrabajoGrado:
+		return "dis.obligatoria"
+	case models.TipologiaDisciplinarOptativa:
+		return "dis.optativa"
+	case models.TipologiaLibreEleccion:
+		return "libre"
+	default:
+		return ""
 	}
+}
 
-	// 4. Procesar la historia académica
-	approvedSubjects := make(map[string]bool) // códigos de materias aprobadas
+// approvedCodes obtiene los códigos de las materias aprobadas en la historia académica
+func approvedCodes(academicHistory models.AcademicHistoryInput) map[string]bool {
+	approvedSubjects := make(map[string]bool)
 	for _, historySubject := range academicHistory.Subjects {
 		if historySubject.Status == "APROBADA" {
 			approvedSubjects[historySubject.Code] = true
 		}
 	}
+	return approvedSubjects
+}
+
+// matchSubject determina si una materia del plan está aprobada, directamente o por equivalencia.
+// Retorna el código de la historia que la satisface y la información de la equivalencia usada.
+func (pc *planContext) matchSubject(planSubject models.Subject, approvedSubjects map[string]bool) (string, *models.EquivalenceResult) {
+	// Verificar si está aprobada directamente
+	if approvedSubjects[planSubject.Code] {
+		return planSubject.Code, nil
+	}
+
+	// Verificar si está aprobada por equivalencia
+	for _, equivCode := range pc.equivalenceMap[planSubject.Code] {
+		if approvedSubjects[equivCode] {
+			return equivCode, &models.EquivalenceResult{
+				Type:  "total", // Asumimos equivalencia total por simplicidad
+				Notes: "Aprobada por equivalencia con " + equivCode,
+			}
+		}
+	}
 
-	// 5. Determinar qué materias del plan están aprobadas (directa o por equivalencia)
+	return "", nil
+}
+
+// compareHistory compara una historia académica con el plan: traduce sus códigos a los del catálogo,
+// compara, si se solicita agrega la traza de cada materia, y sugiere equivalencias para los códigos desconocidos
+func (pc *planContext) compareHistory(academicHistory models.AcademicHistoryInput, opts CompareOptions) *models.ComparisonResult {
+	resolved, diagnostics := pc.resolveHistory(academicHistory)
+	result := pc.compare(approvedCodes(resolved))
+	if opts.Explain {
+		pc.explain(result, resolved)
+	}
+	result.Diagnostics = diagnostics
+	result.PossibleEquivalences = pc.suggestEquivalences(resolved, result, diagnostics.UnresolvedCodes)
+	return result
+}
+
+// compare determina qué materias del plan están aprobadas a partir de los códigos aprobados
+func (pc *planContext) compare(approvedSubjects map[string]bool) *models.ComparisonResult {
 	var equivalentSubjects []models.SubjectResult
 	var missingSubjects []models.SubjectResult
 	
@@ -65,28 +165,8 @@ func CompareAcademicHistoryWithStudyPlan(db *gorm.DB, academicHistory models.Aca
 		"libre":            0,
 	}
 
-	for _, planSubject := range studyPlan.Subjects {
-		isApproved := false
-		var equivalenceInfo *models.EquivalenceResult
-
-		// Verificar si está aprobada directamente
-		if approvedSubjects[planSubject.Code] {
-			isApproved = true
-		} else {
-			// Verificar si está aprobada por equivalencia
-			if equivalentCodes, hasEquivalences := equivalenceMap[planSubject.Code]; hasEquivalences {
-				for _, equivCode := range equivalentCodes {
-					if approvedSubjects[equivCode] {
-						isApproved = true
-						equivalenceInfo = &models.EquivalenceResult{
-							Type:  "total", // Asumimos equivalencia total por simplicidad
-							Notes: "Aprobada por equivalencia con " + equivCode,
-						}
-						break
-					}
-				}
-			}
-		}
+	for _, planSubject := range pc.StudyPlan.Subjects {
+		matchedCode, equivalenceInfo := pc.matchSubject(planSubject, approvedSubjects)
 
 		subjectResult := models.SubjectResult{
 			Code:        planSubject.Code,
@@ -96,17 +176,80 @@ func CompareAcademicHistoryWithStudyPlan(db *gorm.DB, academicHistory models.Aca
 			Equivalence: equivalenceInfo,
 		}
 
-		if isApproved {
+		if matchedCode != "" {
 			subjectResult.Status = "APROBADA"
 			equivalentSubjects = append(equivalentSubjects, subjectResult)
-			creditsByType[string(planSubject.Type)] += planSubject.Credits
+			if bucket := typologyBucket(planSubject.Type); bucket != "" {
+				creditsByType[bucket] += planSubject.Credits
+			}
 		} else {
 			subjectResult.Status = "PENDIENTE"
 			missingSubjects = append(missingSubjects, subjectResult)
 		}
 	}
 
-	// 6. Calcular resumen de créditos
+	result := &models.ComparisonResult{
+		EquivalentSubjects: equivalentSubjects,
+		MissingSubjects:    missingSubjects,
+	}
+	pc.summarize(result, creditsByType)
+	return result
+}
+
+// summarize calcula el resumen de créditos por tipología y el avance en cada agrupación del plan.
+// Una tipología no se considera completa mientras alguna de sus agrupaciones tenga créditos pendientes.
+func (pc *planContext) summarize(result *models.ComparisonResult, creditsByType map[string]int) {
+	result.CreditsSummary = buildCreditsSummary(pc.StudyPlan, creditsByType)
+	result.GroupsSummary = nil
+
+	approvedInPlan := make(map[string]bool)
+	for _, subject := range result.EquivalentSubjects {
+		approvedInPlan[subject.Code] = true
+	}
+
+	groupsMissing := make(map[string]int) // tipología -> créditos pendientes en sus agrupaciones
+	for _, group := range pc.StudyPlan.Groups {
+		progress := models.GroupProgress{
+			Name:     group.Name,
+			Type:     group.Type,
+			Required: group.MinCredits,
+		}
+		for _, subject := range group.Subjects {
+			// Los créditos que cuentan son los definidos por el plan para la materia
+			if planSubject, inPlan := pc.subjectsByCode[subject.Code]; inPlan && approvedInPlan[subject.Code] {
+				progress.Completed += planSubject.Credits
+			}
+		}
+		progress.Missing = progress.Required - progress.Completed
+		if progress.Missing < 0 {
+			progress.Missing = 0
+		}
+		groupsMissing[typologyBucket(group.Type)] += progress.Missing
+		result.GroupsSummary = append(result.GroupsSummary, progress)
+	}
+
+	summary := &result.CreditsSummary
+	buckets := map[string]*models.CreditTypeInfo{
+		"fund.obligatoria": &summary.FundObligatoria,
+		"fund.optativa":    &summary.FundOptativa,
+		"dis.obligatoria":  &summary.DisObligatoria,
+		"dis.optativa":     &summary.DisOptativa,
+		"libre":            &summary.Libre,
+	}
+	totalMissing := 0
+	for bucket, info := range buckets {
+		if groupsMissing[bucket] > info.Missing {
+			info.Missing = groupsMissing[bucket]
+		}
+		totalMissing += info.Missing
+	}
+	if totalMissing > summary.Total.Missing {
+		summary.Total.Missing = totalMissing
+	}
+}
+
+// buildCreditsSummary calcula el resumen de créditos exigidos, aprobados y faltantes por tipología
+func buildCreditsSummary(studyPlan models.StudyPlan, creditsByType map[string]int) models.CreditsSummary {
 	creditsSummary := models.CreditsSummary{
 		FundObligatoria: models.CreditTypeInfo{
 			Required:  studyPlan.FundObligatoriaCredits,
@@ -165,36 +308,99 @@ func CompareAcademicHistoryWithStudyPlan(db *gorm.DB, academicHistory models.Aca
 		creditsSummary.Total.Missing = 0
 	}
 
-	return &models.ComparisonResult{
-		EquivalentSubjects: equivalentSubjects,
-		MissingSubjects:    missingSubjects,
-		CreditsSummary:     creditsSummary,
-	}, nil
+	return creditsSummary
+}
+
+// CompareAcademicHistoryWithStudyPlan compara la historia académica de un estudiante con un plan de estudio.
+// Retorna también el plan comparado, tomado de la misma copia del catálogo que la comparación.
+func CompareAcademicHistoryWithStudyPlan(db *gorm.DB, academicHistory models.AcademicHistoryInput, studyPlanID uint, opts CompareOptions) (*models.ComparisonResult, *models.StudyPlan, error) {
+	snapshot, err := CatalogAt(db, opts.AsOf)
+	if err != nil {
+		return nil, nil, err
+	}
+	pc, err := snapshot.plan(studyPlanID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pc.compareHistory(academicHistory, opts), copyStudyPlan(&pc.StudyPlan), nil
 }
 
 // GetStudyPlanByCareerCode obtiene el plan de estudio activo de una carrera por su código
 func GetStudyPlanByCareerCode(db *gorm.DB, careerCode string) (*models.StudyPlan, error) {
-	var studyPlan models.StudyPlan
-	err := db.Preload("Subjects").Preload("Career").
-		Joins("JOIN careers ON careers.id = study_plans.career_id").
-		Where("careers.code = ? AND study_plans.is_active = ?", careerCode, true).
-		First(&studyPlan).Error
-	
+	return ResolveStudyPlan(db, careerCode, "", "")
+}
+
+// ResolveStudyPlan obtiene el plan de estudio que aplica a un estudiante de la carrera según su periodo
+// de admisión y su sede (vacía para usar la de la facultad de la carrera); sin periodo, o si ningún plan
+// lo cubre, se usa el plan activo
+func ResolveStudyPlan(db *gorm.DB, careerCode, admissionPeriod, sede string) (*models.StudyPlan, error) {
+	return ResolveStudyPlanAsOf(db, careerCode, admissionPeriod, sede, nil)
+}
+
+// ResolveStudyPlanAsOf es ResolveStudyPlan sobre el catálogo de un instante; asOf nulo usa el catálogo vigente
+func ResolveStudyPlanAsOf(db *gorm.DB, careerCode, admissionPeriod, sede string, asOf *time.Time) (*models.StudyPlan, error) {
+	snapshot, err := CatalogAt(db, asOf)
 	if err != nil {
-		return nil, errors.New("plan de estudio activo no encontrado para la carrera: " + careerCode)
+		return nil, err
 	}
-	
-	return &studyPlan, nil
+	pc, err := snapshot.planForPeriod(careerCode, admissionPeriod, sede)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyStudyPlan(&pc.StudyPlan), nil
+}
+
+// GetStudyPlan obtiene un plan de estudio, con su carrera y sus materias, desde la copia en memoria del catálogo
+func GetStudyPlan(db *gorm.DB, studyPlanID uint) (*models.StudyPlan, error) {
+	return GetStudyPlanAsOf(db, studyPlanID, nil)
 }
 
-// CompareAcademicHistoryByCareerCode compara la historia académica usando el código de carrera
-func CompareAcademicHistoryByCareerCode(db *gorm.DB, academicHistory models.AcademicHistoryInput) (*models.ComparisonResult, error) {
-	// Obtener el plan de estudio activo de la carrera
-	studyPlan, err := GetStudyPlanByCareerCode(db, academicHistory.CareerCode)
+// GetStudyPlanAsOf es GetStudyPlan sobre el catálogo de un instante; asOf nulo usa el catálogo vigente
+func GetStudyPlanAsOf(db *gorm.DB, studyPlanID uint, asOf *time.Time) (*models.StudyPlan, error) {
+	snapshot, err := CatalogAt(db, asOf)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := snapshot.plan(studyPlanID)
 	if err != nil {
 		return nil, err
 	}
+
+	return copyStudyPlan(&pc.StudyPlan), nil
+}
+
+// copyStudyPlan copia un plan de la copia en memoria del catálogo, incluidas sus materias y agrupaciones,
+// para que quien lo reciba pueda modificarlo sin alterar el plan compartido
+func copyStudyPlan(shared *models.StudyPlan) *models.StudyPlan {
+	studyPlan := *shared
+	studyPlan.Subjects = append([]models.Subject(nil), shared.Subjects...)
+	studyPlan.Groups = make([]models.PlanGroup, len(shared.Groups))
+	for i, group := range shared.Groups {
+		group.Subjects = append([]models.Subject(nil), group.Subjects...)
+		studyPlan.Groups[i] = group
+	}
+	if shared.Sede != nil {
+		sede := *shared.Sede
+		studyPlan.Sede = &sede
+	}
+	return &studyPlan
+}
+
+// CompareAcademicHistoryByCareerCode compara la historia académica usando el código de carrera. Retorna
+// también el plan que se usó, tomado de la misma copia del catálogo que la comparación.
+func CompareAcademicHistoryByCareerCode(db *gorm.DB, academicHistory models.AcademicHistoryInput, opts CompareOptions) (*models.ComparisonResult, *models.StudyPlan, error) {
+	// Obtener el plan de estudio de la carrera que aplica según el periodo de admisión y la sede
+	snapshot, err := CatalogAt(db, opts.AsOf)
+	if err != nil {
+		return nil, nil, err
+	}
+	pc, err := snapshot.planForPeriod(academicHistory.CareerCode, academicHistory.AdmissionPeriod, academicHistory.Sede)
+	if err != nil {
+		return nil, nil, err
+	}
 	
 	// Realizar la comparación
-	return CompareAcademicHistoryWithStudyPlan(db, academicHistory, studyPlan.ID)
+	return pc.compareHistory(academicHistory, opts), copyStudyPlan(&pc.StudyPlan), nil
 }
@@ -0,0 +1,54 @@
+package functions
+
+import (
+	"testing"
+
+	"olimpo-vicedecanatura/models"
+)
+
+func TestTypologyBucket(t *testing.T) {
+	tests := []struct {
+		tipo models.TipologiaAsignatura
+		want string
+	}{
+		{models.TipologiaFundamentalObligatoria, "fund.obligatoria"},
+		{models.TipologiaFundamentalOptativa, "fund.optativa"},
+		{models.TipologiaDisciplinarObligatoria, "dis.obligatoria"},
+		{models.TipologiaTrabajoGrado, "dis.obligatoria"},
+		{models.TipologiaDisciplinarOptativa, "dis.optativa"},
+		{models.TipologiaLibreEleccion, "libre"},
+		{"DESCONOCIDA", ""},
+	}
+
+	for _, tt := range tests {
+		if got := typologyBucket(tt.tipo); got != tt.want {
+			t.Errorf("typologyBucket(%q) = %q, want %q", tt.tipo, got, tt.want)
+		}
+	}
+}
+
+func TestCompareCountsCompletedCreditsByTypology(t *testing.T) {
+	pc := newPlanContext(models.StudyPlan{
+		TotalCredits:           20,
+		FundObligatoriaCredits: 8,
+		DisObligatoriaCredits:  12,
+		Subjects: []models.Subject{
+			{Code: "F1", Credits: 4, Type: models.TipologiaFundamentalObligatoria},
+			{Code: "F2", Credits: 4, Type: models.TipologiaFundamentalObligatoria},
+			{Code: "D1", Credits: 6, Type: models.TipologiaDisciplinarObligatoria},
+			{Code: "TG", Credits: 6, Type: models.TipologiaTrabajoGrado},
+		},
+	}, nil)
+
+	result := pc.compare(map[string]bool{"F1": true, "D1": true, "TG": true})
+	summary := result.CreditsSummary
+	if summary.FundObligatoria.Completed != 4 || summary.FundObligatoria.Missing != 4 {
+		t.Errorf("fund. obligatoria = %+v, want 4 aprobados y 4 faltantes", summary.FundObligatoria)
+	}
+	if summary.DisObligatoria.Completed != 12 || summary.DisObligatoria.Missing != 0 {
+		t.Errorf("disciplinar obligatoria = %+v, want 12 aprobados (con el trabajo de grado) y 0 faltantes", summary.DisObligatoria)
+	}
+	if summary.Total.Completed != 16 {
+		t.Errorf("total aprobado = %d, want 16", summary.Total.Completed)
+	}
+}
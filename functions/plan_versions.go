@@ -0,0 +1,241 @@
+package functions
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// CloneStudyPlan crea una nueva versión inactiva de un plan de estudio, copiando los créditos exigidos,
+// las materias con su tipología en el plan, los prerrequisitos y las agrupaciones
+func CloneStudyPlan(db *gorm.DB, sourceStudyPlanID uint, version string, who AuditActor) (*models.StudyPlan, error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return nil, errors.New("la versión del nuevo plan no puede estar vacía")
+	}
+
+	var clone models.StudyPlan
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var source models.StudyPlan
+		if err := tx.Preload("Groups.Subjects").First(&source, sourceStudyPlanID).Error; err != nil {
+			return ErrStudyPlanNotFound
+		}
+
+		var count int64
+		if err := tx.Model(&models.StudyPlan{}).Where("career_id = ? AND version = ?", source.CareerID, version).Count(&count).Error; err != nil {
+			return errors.New("error verificando las versiones de la carrera")
+		}
+		if count > 0 {
+			return errors.New("la carrera ya tiene un plan con la versión " + version)
+		}
+
+		clone = models.StudyPlan{
+			CareerID:                  source.CareerID,
+			SedeID:                    source.SedeID,
+			Version:                   version,
+			TotalCredits:              source.TotalCredits,
+			FoundationalCredits:       source.FoundationalCredits,
+			DisciplinaryCredits:       source.DisciplinaryCredits,
+			ElectiveCreditsPercentage: source.ElectiveCreditsPercentage,
+			FundObligatoriaCredits:    source.FundObligatoriaCredits,
+			FundOptativaCredits:       source.FundOptativaCredits,
+			DisObligatoriaCredits:     source.DisObligatoriaCredits,
+			DisOptativaCredits:        source.DisOptativaCredits,
+			LibreCredits:              source.LibreCredits,
+		}
+		// La nueva versión se crea inactiva
+		if err := tx.Create(&clone).Error; err != nil {
+			return errors.New("error creando la nueva versión del plan")
+		}
+
+		var links []models.StudyPlanSubject
+		if err := tx.Where("study_plan_id = ?", source.ID).Find(&links).Error; err != nil {
+			return errors.New("error obteniendo las materias del plan")
+		}
+		for i := range links {
+			links[i].StudyPlanID = clone.ID
+		}
+		if len(links) > 0 {
+			if err := tx.Create(&links).Error; err != nil {
+				return errors.New("error copiando las materias del plan")
+			}
+		}
+
+		var prerequisites []models.PlanPrerequisite
+		if err := tx.Where("study_plan_id = ?", source.ID).Find(&prerequisites).Error; err != nil {
+			return errors.New("error obteniendo los prerrequisitos del plan")
+		}
+		for i := range prerequisites {
+			prerequisites[i].StudyPlanID = clone.ID
+			prerequisites[i].CreatedAt = clone.CreatedAt
+		}
+		if len(prerequisites) > 0 {
+			if err := tx.Omit("Subject", "Prerequisite").Create(&prerequisites).Error; err != nil {
+				return errors.New("error copiando los prerrequisitos del plan")
+			}
+		}
+
+		for _, group := range source.Groups {
+			copied := models.PlanGroup{
+				StudyPlanID: clone.ID,
+				Name:        group.Name,
+				Type:        group.Type,
+				MinCredits:  group.MinCredits,
+				Subjects:    group.Subjects,
+			}
+			if err := tx.Omit("Subjects.*").Create(&copied).Error; err != nil {
+				return errors.New("error copiando la agrupación " + group.Name)
+			}
+		}
+
+		state, err := LoadStudyPlanAuditState(tx, clone.ID)
+		if err != nil {
+			return err
+		}
+		return RecordAudit(tx, who, AuditActionCreate, AuditEntityStudyPlan, clone.ID, StudyPlanAuditKey(state), nil, state)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	InvalidateCatalog()
+	return &clone, nil
+}
+
+// DiffStudyPlans compara dos versiones de un plan: materias agregadas, retiradas o con otra tipología
+// o créditos, cambios en los créditos exigidos y cambios en los prerrequisitos
+func DiffStudyPlans(db *gorm.DB, fromStudyPlanID, toStudyPlanID uint) (*models.StudyPlanDiff, error) {
+	snapshot, err := CurrentCatalog(db)
+	if err != nil {
+		return nil, err
+	}
+	from, err := snapshot.plan(fromStudyPlanID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := snapshot.plan(toStudyPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.StudyPlanDiff{
+		FromStudyPlanID: from.StudyPlan.ID,
+		ToStudyPlanID:   to.StudyPlan.ID,
+		FromVersion:     from.StudyPlan.Version,
+		ToVersion:       to.StudyPlan.Version,
+	}
+
+	// 1. Materias
+	for _, code := range sortedSubjectCodes(from) {
+		fromSubject := from.subjectsByCode[code]
+		toSubject, exists := to.subjectsByCode[code]
+		if !exists {
+			diff.RemovedSubjects = append(diff.RemovedSubjects, planSubjectInfo(fromSubject))
+			continue
+		}
+		if fromSubject.Type != toSubject.Type || fromSubject.Credits != toSubject.Credits {
+			diff.ChangedSubjects = append(diff.ChangedSubjects, models.PlanSubjectChange{
+				Code:        code,
+				Name:        toSubject.Name,
+				FromType:    fromSubject.Type,
+				ToType:      toSubject.Type,
+				FromCredits: fromSubject.Credits,
+				ToCredits:   toSubject.Credits,
+			})
+		}
+	}
+	for _, code := range sortedSubjectCodes(to) {
+		if _, exists := from.subjectsByCode[code]; !exists {
+			diff.AddedSubjects = append(diff.AddedSubjects, planSubjectInfo(to.subjectsByCode[code]))
+		}
+	}
+
+	// 2. Créditos exigidos por tipología
+	requirements := []struct {
+		name     string
+		from, to int
+	}{
+		{"total", from.StudyPlan.TotalCredits, to.StudyPlan.TotalCredits},
+		{"fund_obligatoria", from.StudyPlan.FundObligatoriaCredits, to.StudyPlan.FundObligatoriaCredits},
+		{"fund_optativa", from.StudyPlan.FundOptativaCredits, to.StudyPlan.FundOptativaCredits},
+		{"dis_obligatoria", from.StudyPlan.DisObligatoriaCredits, to.StudyPlan.DisObligatoriaCredits},
+		{"dis_optativa", from.StudyPlan.DisOptativaCredits, to.StudyPlan.DisOptativaCredits},
+		{"libre", from.StudyPlan.LibreCredits, to.StudyPlan.LibreCredits},
+	}
+	for _, r := range requirements {
+		if r.from != r.to {
+			diff.RequirementChanges = append(diff.RequirementChanges, models.RequirementChange{Name: r.name, From: r.from, To: r.to})
+		}
+	}
+
+	// 3. Prerrequisitos, comparados por código porque las materias pueden cambiar entre versiones
+	fromEdges := prerequisiteEdges(snapshot, fromStudyPlanID)
+	toEdges := prerequisiteEdges(snapshot, toStudyPlanID)
+	for _, edge := range sortedEdges(fromEdges) {
+		if !toEdges[edge] {
+			diff.RemovedPrerequisites = append(diff.RemovedPrerequisites, edge)
+		}
+	}
+	for _, edge := range sortedEdges(toEdges) {
+		if !fromEdges[edge] {
+			diff.AddedPrerequisites = append(diff.AddedPrerequisites, edge)
+		}
+	}
+
+	return diff, nil
+}
+
+// sortedSubjectCodes retorna los códigos de las materias del plan en orden
+func sortedSubjectCodes(pc *planContext) []string {
+	codes := make([]string, 0, len(pc.subjectsByCode))
+	for code := range pc.subjectsByCode {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// planSubjectInfo resume una materia con su tipología y créditos en el plan
+func planSubjectInfo(subject *models.Subject) models.PlanSubjectInfo {
+	return models.PlanSubjectInfo{
+		Code:    subject.Code,
+		Name:    subject.Name,
+		Type:    subject.Type,
+		Credits: subject.Credits,
+	}
+}
+
+// prerequisiteEdges obtiene los prerrequisitos de un plan expresados con códigos de materia
+func prerequisiteEdges(snapshot *CatalogSnapshot, studyPlanID uint) map[models.PrerequisiteEdge]bool {
+	edges := make(map[models.PrerequisiteEdge]bool)
+	for subjectID, prerequisiteIDs := range snapshot.Prerequisites[studyPlanID] {
+		subject, exists := snapshot.Subjects[subjectID]
+		if !exists {
+			continue
+		}
+		for _, prerequisiteID := range prerequisiteIDs {
+			if prerequisite, exists := snapshot.Subjects[prerequisiteID]; exists {
+				edges[models.PrerequisiteEdge{SubjectCode: subject.Code, PrerequisiteCode: prerequisite.Code}] = true
+			}
+		}
+	}
+	return edges
+}
+
+// sortedEdges ordena los prerrequisitos por materia y luego por prerrequisito
+func sortedEdges(edges map[models.PrerequisiteEdge]bool) []models.PrerequisiteEdge {
+	sorted := make([]models.PrerequisiteEdge, 0, len(edges))
+	for edge := range edges {
+		sorted = append(sorted, edge)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].SubjectCode != sorted[j].SubjectCode {
+			return sorted[i].SubjectCode < sorted[j].SubjectCode
+		}
+		return sorted[i].PrerequisiteCode < sorted[j].PrerequisiteCode
+	})
+	return sorted
+}
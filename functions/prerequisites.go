@@ -0,0 +1,349 @@
+package functions
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"olimpo-vicedecanatura/models"
+)
+
+// PrerequisiteCycleError se retorna cuando un prerrequisito crearía un ciclo en el plan
+type PrerequisiteCycleError struct {
+	Cycle []string // Códigos de las materias que forman el ciclo, empezando y terminando en la misma
+}
+
+func (e *PrerequisiteCycleError) Error() string {
+	return "el prerrequisito crearía un ciclo: " + strings.Join(e.Cycle, " → ")
+}
+
+// AddPlanPrerequisite registra que, en el plan, subjectCode requiere haber aprobado prerequisiteCode.
+// Ambas materias deben pertenecer al plan y el cambio se rechaza si crea un ciclo.
+func AddPlanPrerequisite(db *gorm.DB, studyPlanID uint, subjectCode, prerequisiteCode string, who AuditActor) error {
+	if subjectCode == prerequisiteCode {
+		return &PrerequisiteCycleError{Cycle: []string{subjectCode, subjectCode}}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Se bloquea el plan para que dos cambios simultáneos no formen un ciclo entre ambos
+		var studyPlan models.StudyPlan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&studyPlan, studyPlanID).Error; err != nil {
+			return ErrStudyPlanNotFound
+		}
+
+		subjects, err := planSubjectsByCode(tx, studyPlanID, subjectCode, prerequisiteCode)
+		if err != nil {
+			return err
+		}
+		subject, prerequisite := subjects[subjectCode], subjects[prerequisiteCode]
+
+		var rows []models.PlanPrerequisite
+		if err := tx.Where("study_plan_id = ?", studyPlanID).Find(&rows).Error; err != nil {
+			return errors.New("error obteniendo los prerrequisitos del plan")
+		}
+		edges := make(map[uint][]uint)
+		for _, row := range rows {
+			edges[row.SubjectID] = append(edges[row.SubjectID], row.PrerequisiteID)
+		}
+
+		// Hay ciclo si el prerrequisito ya depende, directa o indirectamente, de la materia
+		if path := PrerequisitePath(edges, prerequisite.ID, subject.ID); path != nil {
+			var codes []models.Subject
+			if err := tx.Where("id IN ?", path).Find(&codes).Error; err != nil {
+				return errors.New("error obteniendo las materias del ciclo")
+			}
+			codeByID := make(map[uint]string)
+			for _, s := range codes {
+				codeByID[s.ID] = s.Code
+			}
+			cycle := []string{subject.Code}
+			for _, id := range path {
+				cycle = append(cycle, codeByID[id])
+			}
+			return &PrerequisiteCycleError{Cycle: cycle}
+		}
+
+		return RecordStudyPlanChange(tx, who, AuditActionAddPrerequisite, studyPlanID, func() error {
+			row := models.PlanPrerequisite{StudyPlanID: studyPlanID, SubjectID: subject.ID, PrerequisiteID: prerequisite.ID}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+				return errors.New("error guardando el prerrequisito")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	InvalidateCatalog()
+	return nil
+}
+
+// RemovePlanPrerequisite elimina un prerrequisito de una materia en el plan
+func RemovePlanPrerequisite(db *gorm.DB, studyPlanID uint, subjectCode, prerequisiteCode string, who AuditActor) error {
+	subjects, err := planSubjectsByCode(db, studyPlanID, subjectCode, prerequisiteCode)
+	if err != nil {
+		return err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return RecordStudyPlanChange(tx, who, AuditActionRemovePrerequisite, studyPlanID, func() error {
+			result := tx.Where("study_plan_id = ? AND subject_id = ? AND prerequisite_id = ?",
+				studyPlanID, subjects[subjectCode].ID, subjects[prerequisiteCode].ID).
+				Delete(&models.PlanPrerequisite{})
+			if result.Error != nil {
+				return errors.New("error eliminando el prerrequisito")
+			}
+			if result.RowsAffected == 0 {
+				return errors.New(prerequisiteCode + " no es prerrequisito de " + subjectCode + " en el plan")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	InvalidateCatalog()
+	return nil
+}
+
+// CopyLegacyPrerequisites copia los prerrequisitos generales (tabla subject_prerequisites, anterior a los
+// prerrequisitos por plan) a cada plan que tiene ambas materias. Omite los que el plan ya tiene y los que
+// formarían un ciclo, que retorna descritos; cada plan modificado queda en la auditoría. Solo debe
+// ejecutarse una vez, desde la migración: después los prerrequisitos se editan únicamente por plan.
+func CopyLegacyPrerequisites(tx *gorm.DB, who AuditActor) (int, []string, error) {
+	var candidates []models.PlanPrerequisite
+	if err := tx.Raw(`SELECT a.study_plan_id, sp.subject_id, sp.prerequisite_id
+		FROM subject_prerequisites sp
+		JOIN study_plan_subjects a ON a.subject_id = sp.subject_id
+		JOIN study_plan_subjects b ON b.subject_id = sp.prerequisite_id AND b.study_plan_id = a.study_plan_id
+		ORDER BY a.study_plan_id, sp.subject_id, sp.prerequisite_id`).Scan(&candidates).Error; err != nil {
+		return 0, nil, errors.New("error obteniendo los prerrequisitos generales")
+	}
+	if len(candidates) == 0 {
+		return 0, nil, nil
+	}
+
+	var subjects []models.Subject
+	if err := tx.Unscoped().Select("id", "code").Find(&subjects).Error; err != nil {
+		return 0, nil, errors.New("error obteniendo las materias")
+	}
+	codeByID := make(map[uint]string)
+	for _, subject := range subjects {
+		codeByID[subject.ID] = subject.Code
+	}
+
+	byPlan := make(map[uint][]models.PlanPrerequisite)
+	var planIDs []uint
+	for _, candidate := range candidates {
+		if _, exists := byPlan[candidate.StudyPlanID]; !exists {
+			planIDs = append(planIDs, candidate.StudyPlanID)
+		}
+		byPlan[candidate.StudyPlanID] = append(byPlan[candidate.StudyPlanID], candidate)
+	}
+
+	copied := 0
+	var skipped []string
+	for _, studyPlanID := range planIDs {
+		var rows []models.PlanPrerequisite
+		if err := tx.Where("study_plan_id = ?", studyPlanID).Find(&rows).Error; err != nil {
+			return 0, nil, errors.New("error obteniendo los prerrequisitos del plan")
+		}
+		edges := make(map[uint][]uint)
+		for _, row := range rows {
+			edges[row.SubjectID] = append(edges[row.SubjectID], row.PrerequisiteID)
+		}
+
+		var added []models.PlanPrerequisite
+		for _, candidate := range byPlan[studyPlanID] {
+			if containsID(edges[candidate.SubjectID], candidate.PrerequisiteID) {
+				continue
+			}
+			if candidate.SubjectID == candidate.PrerequisiteID ||
+				PrerequisitePath(edges, candidate.PrerequisiteID, candidate.SubjectID) != nil {
+				skipped = append(skipped, fmt.Sprintf("plan %d: %s requiere %s (formaría un ciclo)",
+					studyPlanID, codeByID[candidate.SubjectID], codeByID[candidate.PrerequisiteID]))
+				continue
+			}
+			edges[candidate.SubjectID] = append(edges[candidate.SubjectID], candidate.PrerequisiteID)
+			added = append(added, models.PlanPrerequisite{StudyPlanID: studyPlanID, SubjectID: candidate.SubjectID, PrerequisiteID: candidate.PrerequisiteID})
+		}
+		if len(added) == 0 {
+			continue
+		}
+
+		err := RecordStudyPlanChange(tx, who, AuditActionAddPrerequisite, studyPlanID, func() error {
+			if err := tx.Create(&added).Error; err != nil {
+				return errors.New("error guardando los prerrequisitos del plan")
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		copied += len(added)
+	}
+	return copied, skipped, nil
+}
+
+// containsID indica si el ID está en la lista
+func containsID(ids []uint, id uint) bool {
+	for _, current := range ids {
+		if current == id {
+			return true
+		}
+	}
+	return false
+}
+
+// planSubjectsByCode obtiene materias del plan por su código, fallando si alguna no pertenece al plan
+func planSubjectsByCode(db *gorm.DB, studyPlanID uint, codes ...string) (map[string]models.Subject, error) {
+	var subjects []models.Subject
+	if err := db.Joins("JOIN study_plan_subjects sps ON sps.subject_id = subjects.id").
+		Where("sps.study_plan_id = ? AND subjects.code IN ?", studyPlanID, codes).
+		Find(&subjects).Error; err != nil {
+		return nil, errors.New("error obteniendo las materias del plan")
+	}
+
+	byCode := make(map[string]models.Subject)
+	for _, subject := range subjects {
+		byCode[subject.Code] = subject
+	}
+	for _, code := range codes {
+		if _, exists := byCode[code]; !exists {
+			return nil, errors.New("la materia " + code + " no pertenece al plan de estudio")
+		}
+	}
+	return byCode, nil
+}
+
+// PrerequisitePath busca, siguiendo los prerrequisitos, un camino desde una materia hasta otra.
+// Retorna las materias del camino (incluidos ambos extremos) o nil si no existe. Sirve tanto para
+// grafos por ID como para grafos por código.
+func PrerequisitePath[K comparable](edges map[K][]K, from, to K) []K {
+	parent := map[K]K{from: from}
+	queue := []K{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == to {
+			var path []K
+			for id := to; id != from; id = parent[id] {
+				path = append([]K{id}, path...)
+			}
+			return append([]K{from}, path...)
+		}
+		for _, next := range edges[current] {
+			if _, seen := parent[next]; !seen {
+				parent[next] = current
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
+
+// PrerequisiteCycle busca un ciclo en un grafo de prerrequisitos: una materia con un prerrequisito que
+// ya depende de ella. Recorre las materias en orden para que el ciclo reportado sea siempre el mismo.
+// Retorna el ciclo empezando y terminando en la misma materia, o nil si el grafo no tiene ciclos.
+func PrerequisiteCycle[K cmp.Ordered](edges map[K][]K) []K {
+	keys := make([]K, 0, len(edges))
+	for key := range edges {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for _, next := range edges[key] {
+			if path := PrerequisitePath(edges, next, key); path != nil {
+				return append([]K{key}, path...)
+			}
+		}
+	}
+	return nil
+}
+
+// BuildPrerequisiteGraph arma el grafo de prerrequisitos de un plan con los niveles topológicos de cada materia
+func BuildPrerequisiteGraph(db *gorm.DB, studyPlanID uint) (*models.PrerequisiteGraph, error) {
+	snapshot, err := CurrentCatalog(db)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := snapshot.plan(studyPlanID)
+	if err != nil {
+		return nil, err
+	}
+	return prerequisiteGraph(pc, snapshot.Prerequisites[studyPlanID]), nil
+}
+
+// prerequisiteGraph calcula los niveles con el algoritmo de Kahn, recorriendo las materias por código
+// para que el resultado sea determinista
+func prerequisiteGraph(pc *planContext, edges map[uint][]uint) *models.PrerequisiteGraph {
+	subjects := append([]models.Subject(nil), pc.StudyPlan.Subjects...)
+	sort.Slice(subjects, func(i, j int) bool { return subjects[i].Code < subjects[j].Code })
+
+	codeByID := make(map[uint]string)
+	for _, subject := range subjects {
+		codeByID[subject.ID] = subject.Code
+	}
+
+	pending := make(map[string]int)      // Prerrequisitos aún sin nivel asignado
+	unlocks := make(map[string][]string) // Materia -> materias que la tienen como prerrequisito
+	prerequisites := make(map[string][]string)
+	for _, subject := range subjects {
+		for _, prerequisiteID := range edges[subject.ID] {
+			prerequisiteCode, inPlan := codeByID[prerequisiteID]
+			if !inPlan {
+				continue
+			}
+			prerequisites[subject.Code] = append(prerequisites[subject.Code], prerequisiteCode)
+			unlocks[prerequisiteCode] = append(unlocks[prerequisiteCode], subject.Code)
+			pending[subject.Code]++
+		}
+	}
+
+	levels := make(map[string]int)
+	var current []string
+	for _, subject := range subjects {
+		if pending[subject.Code] == 0 {
+			current = append(current, subject.Code)
+		}
+	}
+	graph := &models.PrerequisiteGraph{StudyPlanID: pc.StudyPlan.ID}
+	for level := 0; len(current) > 0; level++ {
+		graph.Levels = append(graph.Levels, current)
+		var next []string
+		for _, code := range current {
+			levels[code] = level
+			for _, unlocked := range unlocks[code] {
+				pending[unlocked]--
+				if pending[unlocked] == 0 {
+					next = append(next, unlocked)
+				}
+			}
+		}
+		sort.Strings(next)
+		current = next
+	}
+
+	for _, subject := range subjects {
+		node := models.PrerequisiteNode{
+			Code:          subject.Code,
+			Name:          subject.Name,
+			Credits:       subject.Credits,
+			Type:          subject.Type,
+			Level:         levels[subject.Code],
+			Prerequisites: prerequisites[subject.Code],
+			Unlocks:       unlocks[subject.Code],
+		}
+		sort.Strings(node.Prerequisites)
+		sort.Strings(node.Unlocks)
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	return graph
+}
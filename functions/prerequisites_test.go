@@ -0,0 +1,93 @@
+package functions
+
+import (
+	"reflect"
+	"testing"
+
+	"olimpo-vicedecanatura/models"
+)
+
+func TestPrerequisiteGraphLevels(t *testing.T) {
+	subjects := []models.Subject{{ID: 1, Code: "A"}, {ID: 2, Code: "B"}, {ID: 3, Code: "C"}, {ID: 4, Code: "D"}}
+
+	tests := []struct {
+		name  string
+		edges map[uint][]uint // Materia -> sus prerrequisitos
+		want  [][]string
+	}{
+		{
+			name:  "sin prerrequisitos",
+			edges: map[uint][]uint{},
+			want:  [][]string{{"A", "B", "C", "D"}},
+		},
+		{
+			name:  "cadena",
+			edges: map[uint][]uint{2: {1}, 3: {2}, 4: {3}},
+			want:  [][]string{{"A"}, {"B"}, {"C"}, {"D"}},
+		},
+		{
+			name:  "rombo",
+			edges: map[uint][]uint{2: {1}, 3: {1}, 4: {2, 3}},
+			want:  [][]string{{"A"}, {"B", "C"}, {"D"}},
+		},
+		{
+			name:  "el camino más largo define el nivel",
+			edges: map[uint][]uint{2: {1}, 3: {1, 2}},
+			want:  [][]string{{"A", "D"}, {"B"}, {"C"}},
+		},
+		{
+			name:  "prerrequisitos fuera del plan no cuentan",
+			edges: map[uint][]uint{2: {99}, 3: {1}},
+			want:  [][]string{{"A", "B", "D"}, {"C"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := newPlanContext(models.StudyPlan{ID: 1, Subjects: append([]models.Subject(nil), subjects...)}, nil)
+			graph := prerequisiteGraph(pc, tt.edges)
+			if !reflect.DeepEqual(graph.Levels, tt.want) {
+				t.Errorf("niveles = %v, want %v", graph.Levels, tt.want)
+			}
+			for _, node := range graph.Nodes {
+				for level, codes := range tt.want {
+					for _, code := range codes {
+						if code == node.Code && node.Level != level {
+							t.Errorf("la materia %s quedó en el nivel %d, want %d", node.Code, node.Level, level)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPrerequisiteGraphUnlocks(t *testing.T) {
+	pc := newPlanContext(models.StudyPlan{ID: 1, Subjects: []models.Subject{{ID: 1, Code: "A"}, {ID: 2, Code: "B"}, {ID: 3, Code: "C"}}}, nil)
+	graph := prerequisiteGraph(pc, map[uint][]uint{2: {1}, 3: {1, 2}})
+
+	want := map[string]struct{ prerequisites, unlocks []string }{
+		"A": {nil, []string{"B", "C"}},
+		"B": {[]string{"A"}, []string{"C"}},
+		"C": {[]string{"A", "B"}, nil},
+	}
+	for _, node := range graph.Nodes {
+		if !reflect.DeepEqual(node.Prerequisites, want[node.Code].prerequisites) || !reflect.DeepEqual(node.Unlocks, want[node.Code].unlocks) {
+			t.Errorf("%s: prerrequisitos %v y desbloquea %v, want %v y %v", node.Code, node.Prerequisites, node.Unlocks,
+				want[node.Code].prerequisites, want[node.Code].unlocks)
+		}
+	}
+}
+
+func TestPrerequisitePath(t *testing.T) {
+	edges := map[uint][]uint{3: {2}, 2: {1}, 4: {1}}
+	if got := PrerequisitePath(edges, 3, 1); !reflect.DeepEqual(got, []uint{3, 2, 1}) {
+		t.Errorf("PrerequisitePath(3, 1) = %v, want [3 2 1]", got)
+	}
+	if got := PrerequisitePath(edges, 1, 3); got != nil {
+		t.Errorf("PrerequisitePath(1, 3) = %v, want nil", got)
+	}
+	if got := PrerequisitePath(edges, 4, 4); !reflect.DeepEqual(got, []uint{4}) {
+		t.Errorf("PrerequisitePath(4, 4) = %v, want [4]", got)
+	}
+}
@@ -0,0 +1,158 @@
+package functions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// EngineVersion identifica la versión del motor de comparación con la que se calculó un resultado.
+// Debe incrementarse cada vez que un cambio en el motor pueda alterar los resultados.
+const EngineVersion = "1.5.0"
+
+// SaveComparisonRun guarda una comparación ejecutada junto con su entrada y su resultado
+func SaveComparisonRun(db *gorm.DB, endpoint string, studyPlan *models.StudyPlan, academicHistory models.AcademicHistoryInput, opts CompareOptions, result *models.ComparisonResult, createdBy string) (*models.ComparisonRun, error) {
+	run, err := newComparisonRun(endpoint, studyPlan, academicHistory, opts, result, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Create(run).Error; err != nil {
+		return nil, errors.New("error guardando la comparación")
+	}
+	return run, nil
+}
+
+// newComparisonRun arma el registro de una comparación serializando su entrada y su resultado
+func newComparisonRun(endpoint string, studyPlan *models.StudyPlan, academicHistory models.AcademicHistoryInput, opts CompareOptions, result *models.ComparisonResult, createdBy string) (*models.ComparisonRun, error) {
+	if studyPlan == nil || result == nil {
+		return nil, errors.New("la comparación no tiene plan de estudio o resultado")
+	}
+	input, err := json.Marshal(academicHistory)
+	if err != nil {
+		return nil, errors.New("error serializando la historia académica")
+	}
+	output, err := json.Marshal(result)
+	if err != nil {
+		return nil, errors.New("error serializando el resultado de la comparación")
+	}
+
+	careerCode := academicHistory.CareerCode
+	if careerCode == "" {
+		careerCode = studyPlan.Career.Code
+	}
+
+	return &models.ComparisonRun{
+		Endpoint:         endpoint,
+		CareerCode:       careerCode,
+		SedeCode:         historySede(*studyPlan, academicHistory),
+		StudyPlanID:      studyPlan.ID,
+		StudyPlanVersion: studyPlan.Version,
+		EngineVersion:    EngineVersion,
+		Explain:          opts.Explain,
+		AsOf:             opts.AsOf,
+		InputSnapshot:    string(input),
+		Result:           string(output),
+		CreatedBy:        createdBy,
+	}, nil
+}
+
+// ErrComparisonRunNotFound se retorna cuando la comparación guardada solicitada no existe
+var ErrComparisonRunNotFound = errors.New("comparación no encontrada")
+
+// GetComparisonRun obtiene una comparación guardada por su ID
+func GetComparisonRun(db *gorm.DB, runID uint) (*models.ComparisonRun, error) {
+	var run models.ComparisonRun
+	if err := db.First(&run, runID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrComparisonRunNotFound
+		}
+		return nil, errors.New("error obteniendo la comparación")
+	}
+	return &run, nil
+}
+
+// DecodeComparisonRun obtiene la historia académica y el resultado guardados en una comparación
+func DecodeComparisonRun(run *models.ComparisonRun) (models.AcademicHistoryInput, *models.ComparisonResult, error) {
+	var academicHistory models.AcademicHistoryInput
+	if err := json.Unmarshal([]byte(run.InputSnapshot), &academicHistory); err != nil {
+		return academicHistory, nil, errors.New("la historia académica guardada no es válida")
+	}
+	var result models.ComparisonResult
+	if err := json.Unmarshal([]byte(run.Result), &result); err != nil {
+		return academicHistory, nil, errors.New("el resultado guardado no es válido")
+	}
+	return academicHistory, &result, nil
+}
+
+// RerunOptions configura la re-ejecución de una comparación guardada
+type RerunOptions struct {
+	UseActivePlan   bool       // Comparar contra el plan activo de la carrera en lugar del plan original
+	AsOf            *time.Time // Comparar contra el catálogo de ese instante; nulo usa el catálogo vigente
+	OriginalCatalog bool       // Comparar contra el catálogo con el que se hizo la comparación original
+}
+
+// RerunComparison vuelve a ejecutar una comparación guardada y guarda la nueva ejecución. Por defecto se usa
+// el catálogo vigente; con OriginalCatalog se reproduce la decisión original con el catálogo de entonces.
+// Retorna también advertencias sobre lo que impide reproducir exactamente la comparación original, como
+// una versión distinta del motor.
+func RerunComparison(db *gorm.DB, runID uint, rerun RerunOptions, createdBy string) (*models.ComparisonRun, *models.ComparisonResult, []string, error) {
+	original, err := GetComparisonRun(db, runID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	academicHistory, _, err := DecodeComparisonRun(original)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	asOf := rerun.AsOf
+	if rerun.OriginalCatalog {
+		catalogTime := original.CreatedAt
+		if original.AsOf != nil {
+			catalogTime = *original.AsOf
+		}
+		asOf = &catalogTime
+	}
+
+	var studyPlan *models.StudyPlan
+	if rerun.UseActivePlan {
+		studyPlan, err = ResolveStudyPlanAsOf(db, original.CareerCode, "", original.SedeCode, asOf)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		studyPlan, err = GetStudyPlanAsOf(db, original.StudyPlanID, asOf)
+		if errors.Is(err, ErrStudyPlanNotFound) {
+			return nil, nil, nil, fmt.Errorf("%w: el plan de la comparación original no existe en el catálogo consultado", ErrStudyPlanNotFound)
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	opts := CompareOptions{Explain: original.Explain, AsOf: asOf}
+	result, studyPlan, err := CompareAcademicHistoryWithStudyPlan(db, academicHistory, studyPlan.ID, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	run, err := newComparisonRun("rerun", studyPlan, academicHistory, opts, result, createdBy)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	run.RerunOfID = &original.ID
+	if err := db.Create(run).Error; err != nil {
+		return nil, nil, nil, errors.New("error guardando la comparación")
+	}
+
+	var warnings []string
+	if original.EngineVersion != EngineVersion {
+		warnings = append(warnings, fmt.Sprintf("la comparación original se calculó con la versión %s del motor y esta con la %s: las diferencias pueden deberse al motor y no al catálogo",
+			original.EngineVersion, EngineVersion))
+	}
+	return run, result, warnings, nil
+}
@@ -0,0 +1,287 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// PlanRequirements son los créditos exigidos por un plan de estudio en cada tipología
+type PlanRequirements struct {
+	TotalCredits           int `json:"total_credits"`
+	FundObligatoriaCredits int `json:"fund_obligatoria_credits"`
+	FundOptativaCredits    int `json:"fund_optativa_credits"`
+	DisObligatoriaCredits  int `json:"dis_obligatoria_credits"`
+	DisOptativaCredits     int `json:"dis_optativa_credits"`
+	LibreCredits           int `json:"libre_credits"`
+}
+
+// ErrStudyPlanNotFound se retorna cuando el plan de estudio solicitado no existe
+var ErrStudyPlanNotFound = errors.New("plan de estudio no encontrado")
+
+// PlanValidationError se retorna cuando un cambio dejaría inconsistente un plan de estudio activo
+type PlanValidationError struct {
+	Issues []string
+}
+
+func (e *PlanValidationError) Error() string {
+	return "el plan de estudio quedaría inconsistente: " + strings.Join(e.Issues, "; ")
+}
+
+// PlanConflictError se retorna cuando un plan entra en conflicto con otro plan de la misma carrera:
+// dos planes activos o vigencias que se traslapan
+type PlanConflictError struct {
+	Message string
+}
+
+func (e *PlanConflictError) Error() string {
+	return e.Message
+}
+
+// PlanPeriodError se retorna cuando la vigencia de un plan no es válida
+type PlanPeriodError struct {
+	Message string
+}
+
+func (e *PlanPeriodError) Error() string {
+	return e.Message
+}
+
+// PlanSubjectNotFoundError se retorna cuando se retira del plan una materia que no pertenece a él
+type PlanSubjectNotFoundError struct {
+	Message string
+}
+
+func (e *PlanSubjectNotFoundError) Error() string {
+	return e.Message
+}
+
+// CheckPlanConflicts verifica que el plan tenga una vigencia válida, que sea el único plan activo de su
+// carrera en su sede y que su vigencia no se traslape con la de otro plan de la carrera en la misma sede.
+// Los planes sin sede rigen en todas; un plan propio de una sede tiene prioridad sobre ellos.
+func CheckPlanConflicts(tx *gorm.DB, studyPlan *models.StudyPlan) error {
+	if studyPlan.EffectiveFrom != "" && !models.ValidarPeriodo(studyPlan.EffectiveFrom) {
+		return &PlanPeriodError{Message: "periodo de inicio de vigencia inválido, use el formato AAAA-S: " + studyPlan.EffectiveFrom}
+	}
+	if studyPlan.EffectiveTo != "" {
+		if !models.ValidarPeriodo(studyPlan.EffectiveTo) {
+			return &PlanPeriodError{Message: "periodo de fin de vigencia inválido, use el formato AAAA-S: " + studyPlan.EffectiveTo}
+		}
+		if studyPlan.EffectiveFrom == "" || studyPlan.EffectiveTo < studyPlan.EffectiveFrom {
+			return &PlanPeriodError{Message: "el fin de vigencia debe ser posterior al inicio de vigencia"}
+		}
+	}
+
+	var others []models.StudyPlan
+	if err := tx.Where("career_id = ? AND id <> ?", studyPlan.CareerID, studyPlan.ID).Order("id").Find(&others).Error; err != nil {
+		return errors.New("error verificando los demás planes de la carrera")
+	}
+	for _, other := range others {
+		if !samePlanSede(studyPlan.SedeID, other.SedeID) {
+			continue
+		}
+		if studyPlan.IsActive && other.IsActive {
+			return &PlanConflictError{Message: fmt.Sprintf("la carrera ya tiene un plan activo en la misma sede (versión %s, ID %d); desactívelo antes de activar otro", other.Version, other.ID)}
+		}
+		if periodsOverlap(studyPlan.EffectiveFrom, studyPlan.EffectiveTo, other.EffectiveFrom, other.EffectiveTo) {
+			return &PlanConflictError{Message: fmt.Sprintf("la vigencia del plan se traslapa con la del plan versión %s (ID %d)", other.Version, other.ID)}
+		}
+	}
+	return nil
+}
+
+// samePlanSede indica si dos planes rigen en la misma sede; nil indica un plan que rige en todas las sedes
+func samePlanSede(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// periodsOverlap indica si dos vigencias comparten algún periodo. Las vigencias sin inicio no se comparan.
+func periodsOverlap(fromA, toA, fromB, toB string) bool {
+	if fromA == "" || fromB == "" {
+		return false
+	}
+	return (toB == "" || fromA <= toB) && (toA == "" || fromB <= toA)
+}
+
+// ApplyPlanRequirements asigna al plan los créditos por tipología y recalcula los campos derivados
+func ApplyPlanRequirements(studyPlan *models.StudyPlan, requirements PlanRequirements) {
+	studyPlan.TotalCredits = requirements.TotalCredits
+	studyPlan.FundObligatoriaCredits = requirements.FundObligatoriaCredits
+	studyPlan.FundOptativaCredits = requirements.FundOptativaCredits
+	studyPlan.DisObligatoriaCredits = requirements.DisObligatoriaCredits
+	studyPlan.DisOptativaCredits = requirements.DisOptativaCredits
+	studyPlan.LibreCredits = requirements.LibreCredits
+
+	// Campos generales que se mantienen por compatibilidad
+	studyPlan.FoundationalCredits = requirements.FundObligatoriaCredits + requirements.FundOptativaCredits
+	studyPlan.DisciplinaryCredits = requirements.DisObligatoriaCredits + requirements.DisOptativaCredits
+	studyPlan.ElectiveCreditsPercentage = 0
+	if requirements.TotalCredits > 0 {
+		studyPlan.ElectiveCreditsPercentage = requirements.LibreCredits * 100 / requirements.TotalCredits
+	}
+}
+
+// ValidateRequirements verifica que los créditos por tipología no sean negativos y sumen el total del plan
+func ValidateRequirements(requirements PlanRequirements) []string {
+	var issues []string
+	values := []struct {
+		name  string
+		value int
+	}{
+		{"total_credits", requirements.TotalCredits},
+		{"fund_obligatoria_credits", requirements.FundObligatoriaCredits},
+		{"fund_optativa_credits", requirements.FundOptativaCredits},
+		{"dis_obligatoria_credits", requirements.DisObligatoriaCredits},
+		{"dis_optativa_credits", requirements.DisOptativaCredits},
+		{"libre_credits", requirements.LibreCredits},
+	}
+	for _, v := range values {
+		if v.value < 0 {
+			issues = append(issues, fmt.Sprintf("%s no puede ser negativo", v.name))
+		}
+	}
+
+	sum := requirements.FundObligatoriaCredits + requirements.FundOptativaCredits +
+		requirements.DisObligatoriaCredits + requirements.DisOptativaCredits + requirements.LibreCredits
+	if sum != requirements.TotalCredits {
+		issues = append(issues, fmt.Sprintf("los créditos por tipología suman %d pero el total del plan es %d", sum, requirements.TotalCredits))
+	}
+	return issues
+}
+
+// ValidateStudyPlan revisa la consistencia de un plan cargado con sus materias (ya con la tipología del plan):
+// los créditos por tipología deben sumar el total, los obligatorios deben coincidir con las materias
+// obligatorias asociadas y las optativas deben ofrecer al menos los créditos exigidos.
+func ValidateStudyPlan(studyPlan models.StudyPlan) []string {
+	issues := ValidateRequirements(PlanRequirements{
+		TotalCredits:           studyPlan.TotalCredits,
+		FundObligatoriaCredits: studyPlan.FundObligatoriaCredits,
+		FundOptativaCredits:    studyPlan.FundOptativaCredits,
+		DisObligatoriaCredits:  studyPlan.DisObligatoriaCredits,
+		DisOptativaCredits:     studyPlan.DisOptativaCredits,
+		LibreCredits:           studyPlan.LibreCredits,
+	})
+
+	attached := make(map[string]int)
+	for _, subject := range studyPlan.Subjects {
+		attached[typologyBucket(subject.Type)] += subject.Credits
+	}
+
+	if attached["fund.obligatoria"] != studyPlan.FundObligatoriaCredits {
+		issues = append(issues, fmt.Sprintf("las materias de fundamentación obligatoria suman %d créditos pero el plan exige %d",
+			attached["fund.obligatoria"], studyPlan.FundObligatoriaCredits))
+	}
+	if attached["dis.obligatoria"] != studyPlan.DisObligatoriaCredits {
+		issues = append(issues, fmt.Sprintf("las materias disciplinares obligatorias (incluido el trabajo de grado) suman %d créditos pero el plan exige %d",
+			attached["dis.obligatoria"], studyPlan.DisObligatoriaCredits))
+	}
+	if attached["fund.optativa"] < studyPlan.FundOptativaCredits {
+		issues = append(issues, fmt.Sprintf("las materias de fundamentación optativa ofrecen %d créditos y el plan exige %d",
+			attached["fund.optativa"], studyPlan.FundOptativaCredits))
+	}
+	if attached["dis.optativa"] < studyPlan.DisOptativaCredits {
+		issues = append(issues, fmt.Sprintf("las materias disciplinares optativas ofrecen %d créditos y el plan exige %d",
+			attached["dis.optativa"], studyPlan.DisOptativaCredits))
+	}
+
+	return issues
+}
+
+// LoadStudyPlanForValidation obtiene un plan con su carrera, su sede y sus materias, con la tipología definida en el plan
+func LoadStudyPlanForValidation(db *gorm.DB, studyPlanID uint) (*models.StudyPlan, error) {
+	var studyPlan models.StudyPlan
+	if err := db.Preload("Career").Preload("Sede").Preload("Subjects").First(&studyPlan, studyPlanID).Error; err != nil {
+		return nil, ErrStudyPlanNotFound
+	}
+	if err := ApplyPlanSubjectSettings(db, &studyPlan); err != nil {
+		return nil, err
+	}
+	return &studyPlan, nil
+}
+
+// ChangeStudyPlan aplica un cambio sobre un plan de estudio dentro de una transacción y valida el resultado.
+// Los conflictos con otros planes de la carrera siempre descartan el cambio. Si el plan queda activo con inconsistencias el cambio se descarta y se retorna un *PlanValidationError;
+// un plan inactivo (borrador) sí puede guardarse incompleto, y sus inconsistencias se retornan como avisos.
+// El cambio queda registrado en la auditoría con la acción indicada.
+func ChangeStudyPlan(db *gorm.DB, studyPlanID uint, who AuditActor, action string, change func(tx *gorm.DB, studyPlan *models.StudyPlan) error) (*models.StudyPlan, []string, error) {
+	var studyPlan *models.StudyPlan
+	var issues []string
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var current models.StudyPlan
+		if err := tx.First(&current, studyPlanID).Error; err != nil {
+			return ErrStudyPlanNotFound
+		}
+		if err := RecordStudyPlanChange(tx, who, action, studyPlanID, func() error { return change(tx, &current) }); err != nil {
+			return err
+		}
+
+		updated, err := LoadStudyPlanForValidation(tx, studyPlanID)
+		if err != nil {
+			return err
+		}
+		if err := CheckPlanConflicts(tx, updated); err != nil {
+			return err
+		}
+		issues = ValidateStudyPlan(*updated)
+		if updated.IsActive && len(issues) > 0 {
+			return &PlanValidationError{Issues: issues}
+		}
+		studyPlan = updated
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	InvalidateCatalog()
+	return studyPlan, issues, nil
+}
+
+// AttachSubjectToPlan asocia una materia a un plan con la tipología y los créditos que tiene en ese plan,
+// o actualiza la asociación si la materia ya pertenecía al plan. Una tipología vacía o créditos en cero
+// indican que el plan usa los generales de la materia.
+func AttachSubjectToPlan(tx *gorm.DB, studyPlanID uint, subject models.Subject, tipo models.TipologiaAsignatura, credits int) error {
+	link := models.StudyPlanSubject{
+		StudyPlanID: studyPlanID,
+		SubjectID:   subject.ID,
+		Type:        tipo,
+		Credits:     credits,
+	}
+	if link.Credits < 0 {
+		link.Credits = 0
+	}
+	if err := tx.Save(&link).Error; err != nil {
+		return errors.New("error asociando la materia " + subject.Code + " al plan")
+	}
+	return nil
+}
+
+// DetachSubjectFromPlan retira una materia de un plan, de las agrupaciones y de los prerrequisitos de ese plan
+func DetachSubjectFromPlan(tx *gorm.DB, studyPlanID uint, subject models.Subject) error {
+	result := tx.Where("study_plan_id = ? AND subject_id = ?", studyPlanID, subject.ID).Delete(&models.StudyPlanSubject{})
+	if result.Error != nil {
+		return errors.New("error retirando la materia " + subject.Code + " del plan")
+	}
+	if result.RowsAffected == 0 {
+		return &PlanSubjectNotFoundError{Message: "la materia " + subject.Code + " no pertenece al plan de estudio"}
+	}
+
+	if err := tx.Exec(`DELETE FROM plan_group_subjects
+		WHERE subject_id = ? AND plan_group_id IN (SELECT id FROM plan_groups WHERE study_plan_id = ?)`,
+		subject.ID, studyPlanID).Error; err != nil {
+		return errors.New("error retirando la materia " + subject.Code + " de las agrupaciones del plan")
+	}
+
+	if err := tx.Where("study_plan_id = ? AND (subject_id = ? OR prerequisite_id = ?)", studyPlanID, subject.ID, subject.ID).
+		Delete(&models.PlanPrerequisite{}).Error; err != nil {
+		return errors.New("error retirando los prerrequisitos de la materia " + subject.Code + " en el plan")
+	}
+	return nil
+}
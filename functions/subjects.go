@@ -0,0 +1,93 @@
+package functions
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// SubjectSearch son los filtros de la búsqueda de materias del catálogo
+type SubjectSearch struct {
+	Query       string // Prefijo del código o palabras del nombre
+	Type        models.TipologiaAsignatura
+	Credits     int
+	StudyPlanID uint // Si se indica, solo materias del plan y con la tipología que tienen en él
+	Limit       int
+	Offset      int
+}
+
+// NormalizeSearchText pasa un texto a minúsculas, le quita las tildes y colapsa los espacios,
+// para que "algebra" coincida con "Álgebra Lineal"
+func NormalizeSearchText(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(text) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// SearchSubjects busca materias en la copia en memoria del catálogo, ordenadas por código.
+// Retorna la página solicitada y el total de coincidencias.
+func SearchSubjects(db *gorm.DB, search SubjectSearch) ([]models.Subject, int, error) {
+	snapshot, err := CurrentCatalog(db)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var candidates []models.Subject
+	if search.StudyPlanID != 0 {
+		pc, err := snapshot.plan(search.StudyPlanID)
+		if err != nil {
+			return nil, 0, err
+		}
+		candidates = append(candidates, pc.StudyPlan.Subjects...)
+	} else {
+		for _, subject := range snapshot.Subjects {
+			candidates = append(candidates, *subject)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Code < candidates[j].Code })
+
+	query := NormalizeSearchText(search.Query)
+	words := strings.Fields(query)
+	var matches []models.Subject
+	for _, subject := range candidates {
+		if search.Type != "" && subject.Type != search.Type {
+			continue
+		}
+		if search.Credits != 0 && subject.Credits != search.Credits {
+			continue
+		}
+		if query != "" && !strings.HasPrefix(NormalizeSearchText(subject.Code), query) && !containsAll(NormalizeSearchText(subject.Name), words) {
+			continue
+		}
+		matches = append(matches, subject)
+	}
+
+	total := len(matches)
+	if search.Offset >= total {
+		return []models.Subject{}, total, nil
+	}
+	end := total
+	if search.Limit > 0 && search.Offset+search.Limit < total {
+		end = search.Offset + search.Limit
+	}
+	return matches[search.Offset:end], total, nil
+}
+
+// containsAll indica si el texto contiene todas las palabras, en cualquier orden
+func containsAll(text string, words []string) bool {
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
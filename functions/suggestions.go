@@ -0,0 +1,299 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// Parámetros de las sugerencias de equivalencias por nombre y créditos
+const (
+	DefaultSuggestionMinScore = 0.6 // Puntaje mínimo para sugerir una equivalencia
+	maxSuggestionsPerSubject  = 3   // Sugerencias por materia de la historia
+	nameWeight                = 0.85
+	creditsWeight             = 0.15
+	ordinalMismatchPenalty    = 0.5 // "Estadística I" y "Estadística II" no son la misma materia
+)
+
+var (
+	nameParentheses = regexp.MustCompile(`\([^)]*\)`)
+	nameStopwords   = map[string]bool{"de": true, "del": true, "la": true, "las": true, "el": true, "los": true,
+		"y": true, "e": true, "a": true, "al": true, "en": true, "para": true, "con": true, "por": true, "un": true, "una": true}
+	nameOrdinals = map[string]string{"i": "1", "ii": "2", "iii": "3", "iv": "4", "v": "5", "vi": "6",
+		"1": "1", "2": "2", "3": "3", "4": "4", "5": "5", "6": "6"}
+)
+
+// ErrSuggestionPlans se retorna cuando los planes de una sugerencia de equivalencias no son válidos
+var ErrSuggestionPlans = errors.New("los planes de origen y destino deben ser distintos")
+
+// subjectName es el nombre de una materia preparado para compararlo con otros
+type subjectName struct {
+	words    []string        // Palabras significativas, sin tildes ni artículos
+	bigrams  map[string]int  // Pares de caracteres de las palabras
+	ordinals map[string]bool // Numerales del nombre en arábigos (I y 1 son el mismo)
+}
+
+// parseSubjectName normaliza un nombre de materia: quita tildes, mayúsculas, lo que va entre paréntesis
+// (por ejemplo "(Antigua)") y las palabras sin significado
+func parseSubjectName(name string) subjectName {
+	parsed := subjectName{bigrams: make(map[string]int), ordinals: make(map[string]bool)}
+	text := NormalizeSearchText(nameParentheses.ReplaceAllString(name, " "))
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == '-' || r == ',' || r == '.' }) {
+		switch {
+		case nameOrdinals[word] != "":
+			parsed.ordinals[nameOrdinals[word]] = true
+		case !nameStopwords[word]:
+			parsed.words = append(parsed.words, word)
+		}
+	}
+	joined := []rune(strings.Join(parsed.words, " "))
+	for i := 0; i+1 < len(joined); i++ {
+		parsed.bigrams[string(joined[i:i+2])]++
+	}
+	return parsed
+}
+
+// nameSimilarity compara dos nombres con el coeficiente de Dice sobre pares de caracteres (0 a 1),
+// penalizando los nombres que solo difieren en el numeral
+func nameSimilarity(a, b subjectName) float64 {
+	total := 0
+	for _, count := range a.bigrams {
+		total += count
+	}
+	for _, count := range b.bigrams {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+	shared := 0
+	for bigram, count := range a.bigrams {
+		if other := b.bigrams[bigram]; other < count {
+			shared += other
+		} else {
+			shared += count
+		}
+	}
+	similarity := 2 * float64(shared) / float64(total)
+
+	if len(a.ordinals) > 0 || len(b.ordinals) > 0 {
+		same := len(a.ordinals) == len(b.ordinals)
+		for ordinal := range a.ordinals {
+			same = same && b.ordinals[ordinal]
+		}
+		if !same {
+			similarity *= ordinalMismatchPenalty
+		}
+	}
+	return similarity
+}
+
+// suggestionScore combina la similitud del nombre con la cercanía en créditos
+func suggestionScore(similarity float64, creditsA, creditsB int) float64 {
+	creditsScore := 0.0
+	if creditsA > 0 && creditsB > 0 {
+		diff, max := creditsA-creditsB, creditsA
+		if diff < 0 {
+			diff = -diff
+		}
+		if creditsB > max {
+			max = creditsB
+		}
+		creditsScore = 1 - float64(diff)/float64(max)
+	}
+	return roundScore(nameWeight*similarity + creditsWeight*creditsScore)
+}
+
+// roundScore redondea un puntaje a tres decimales
+func roundScore(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+// suggestEquivalences busca, para cada materia aprobada de la historia cuyo código no está en el catálogo,
+// las materias pendientes del plan con nombre y créditos parecidos. Son solo sugerencias: no cambian el
+// estado de ninguna materia del resultado.
+func (pc *planContext) suggestEquivalences(academicHistory models.AcademicHistoryInput, result *models.ComparisonResult, unresolved []string) []models.PossibleEquivalence {
+	suggestions := []models.PossibleEquivalence{}
+	if len(unresolved) == 0 || len(result.MissingSubjects) == 0 {
+		return suggestions
+	}
+	isUnresolved := make(map[string]bool)
+	for _, code := range unresolved {
+		isUnresolved[code] = true
+	}
+
+	missing := make([]subjectName, len(result.MissingSubjects))
+	for i, subject := range result.MissingSubjects {
+		missing[i] = parseSubjectName(subject.Name)
+	}
+
+	for _, historySubject := range academicHistory.Subjects {
+		if historySubject.Status != "APROBADA" || !isUnresolved[historySubject.Code] {
+			continue
+		}
+		isUnresolved[historySubject.Code] = false // Una sola vez por código
+		historyName := parseSubjectName(historySubject.Name)
+
+		var candidates []models.PossibleEquivalence
+		for i, planSubject := range result.MissingSubjects {
+			similarity := nameSimilarity(historyName, missing[i])
+			score := suggestionScore(similarity, historySubject.Credits, planSubject.Credits)
+			if score < DefaultSuggestionMinScore {
+				continue
+			}
+			candidates = append(candidates, models.PossibleEquivalence{
+				SourceCode:     historySubject.Code,
+				SourceName:     historySubject.Name,
+				SourceCredits:  historySubject.Credits,
+				TargetCode:     planSubject.Code,
+				TargetName:     planSubject.Name,
+				TargetCredits:  planSubject.Credits,
+				NameSimilarity: roundScore(similarity),
+				Score:          score,
+			})
+		}
+		suggestions = append(suggestions, bestSuggestions(candidates, maxSuggestionsPerSubject)...)
+	}
+	return suggestions
+}
+
+// bestSuggestions ordena las sugerencias por puntaje (y por código para desempatar) y conserva las primeras
+func bestSuggestions(candidates []models.PossibleEquivalence, limit int) []models.PossibleEquivalence {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].TargetCode < candidates[j].TargetCode
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// SuggestPlanEquivalences propone equivalencias entre dos planes: para cada materia del plan de origen que
+// no está en el plan destino ni tiene ya una equivalencia aprobada hacia él, busca las materias del plan
+// destino con nombre y créditos parecidos. Solo se sugiere la mejor coincidencia por materia de origen.
+func SuggestPlanEquivalences(db *gorm.DB, sourceStudyPlanID, targetStudyPlanID uint, minScore float64) ([]models.PossibleEquivalence, error) {
+	if sourceStudyPlanID == targetStudyPlanID {
+		return nil, ErrSuggestionPlans
+	}
+	if minScore <= 0 {
+		minScore = DefaultSuggestionMinScore
+	}
+
+	snapshot, err := CurrentCatalog(db)
+	if err != nil {
+		return nil, err
+	}
+	source, err := snapshot.plan(sourceStudyPlanID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := snapshot.plan(targetStudyPlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Materias del plan destino que no están en el plan de origen: son las que podrían recibir una equivalencia
+	var targetSubjects []models.Subject
+	targetNames := make(map[string]subjectName)
+	for _, subject := range target.StudyPlan.Subjects {
+		if _, shared := source.subjectsByCode[subject.Code]; shared {
+			continue
+		}
+		targetSubjects = append(targetSubjects, subject)
+		targetNames[subject.Code] = parseSubjectName(subject.Name)
+	}
+	sort.Slice(targetSubjects, func(i, j int) bool { return targetSubjects[i].Code < targetSubjects[j].Code })
+
+	sourceSubjects := append([]models.Subject(nil), source.StudyPlan.Subjects...)
+	sort.Slice(sourceSubjects, func(i, j int) bool { return sourceSubjects[i].Code < sourceSubjects[j].Code })
+
+	suggestions := []models.PossibleEquivalence{}
+	for _, sourceSubject := range sourceSubjects {
+		if _, shared := target.subjectsByCode[sourceSubject.Code]; shared {
+			continue
+		}
+		if len(target.equivalenceMap[sourceSubject.Code]) > 0 || hasEquivalenceInto(target, sourceSubject.Code) {
+			continue
+		}
+
+		sourceName := parseSubjectName(sourceSubject.Name)
+		var candidates []models.PossibleEquivalence
+		for _, targetSubject := range targetSubjects {
+			similarity := nameSimilarity(sourceName, targetNames[targetSubject.Code])
+			score := suggestionScore(similarity, sourceSubject.Credits, targetSubject.Credits)
+			if score < minScore {
+				continue
+			}
+			candidates = append(candidates, models.PossibleEquivalence{
+				SourceCode:     sourceSubject.Code,
+				SourceName:     sourceSubject.Name,
+				SourceCredits:  sourceSubject.Credits,
+				TargetCode:     targetSubject.Code,
+				TargetName:     targetSubject.Name,
+				TargetCredits:  targetSubject.Credits,
+				NameSimilarity: roundScore(similarity),
+				Score:          score,
+			})
+		}
+		suggestions = append(suggestions, bestSuggestions(candidates, 1)...)
+	}
+	return suggestions, nil
+}
+
+// hasEquivalenceInto indica si una materia ya tiene una equivalencia aprobada hacia alguna materia del plan
+func hasEquivalenceInto(pc *planContext, code string) bool {
+	for _, codes := range pc.equivalenceMap {
+		for _, equivalent := range codes {
+			if equivalent == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ProposeSuggestedEquivalences registra como propuestas las equivalencias sugeridas entre dos planes, para
+// que el comité las revise. Las que ya existen aprobadas o en trámite se omiten; cualquier otro error
+// detiene la propuesta y se retorna.
+func ProposeSuggestedEquivalences(db *gorm.DB, sourceStudyPlanID, targetStudyPlanID uint, minScore float64, who AuditActor) ([]models.Equivalence, []models.PossibleEquivalence, error) {
+	suggestions, err := SuggestPlanEquivalences(db, sourceStudyPlanID, targetStudyPlanID, minScore)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot, err := CurrentCatalog(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proposed := []models.Equivalence{}
+	skipped := []models.PossibleEquivalence{}
+	for _, suggestion := range suggestions {
+		equivalence := models.Equivalence{
+			SourceSubjectID: snapshot.SubjectsByCode[suggestion.SourceCode].ID,
+			TargetSubjectID: snapshot.SubjectsByCode[suggestion.TargetCode].ID,
+			StudyPlanID:     targetStudyPlanID,
+			Type:            "TOTAL",
+			Notes:           fmt.Sprintf("Sugerida por similitud de nombre y créditos (puntaje %.2f)", suggestion.Score),
+			ProposedBy:      who.Actor,
+		}
+		err := ProposeEquivalence(db, &equivalence, who)
+		if errors.Is(err, ErrEquivalenceExists) || errors.Is(err, ErrEquivalenceSameSubject) {
+			skipped = append(skipped, suggestion)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		proposed = append(proposed, equivalence)
+	}
+	return proposed, skipped, nil
+}
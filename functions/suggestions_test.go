@@ -0,0 +1,57 @@
+package functions
+
+import "testing"
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{"mismo nombre sin tildes ni mayúsculas", "Cálculo Diferencial", "CALCULO DIFERENCIAL", 1},
+		{"artículos y paréntesis no cuentan", "Física de Ondas (Antigua)", "Física Ondas", 1},
+		{"mismo numeral en romano y arábigo", "Estadística I", "Estadística 1", 1},
+		{"numerales distintos", "Estadística I", "Estadística II", ordinalMismatchPenalty},
+		{"numeral solo en un nombre", "Estadística I", "Estadística", ordinalMismatchPenalty},
+		{"nombres sin letras en común", "Química", "Dibujo", 0},
+		{"nombre vacío", "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := roundScore(nameSimilarity(parseSubjectName(tt.a), parseSubjectName(tt.b)))
+			if got != tt.want {
+				t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestionScore(t *testing.T) {
+	tests := []struct {
+		name               string
+		similarity         float64
+		creditsA, creditsB int
+		want               float64
+	}{
+		{"nombre y créditos iguales", 1, 4, 4, 1},
+		{"créditos distintos", 1, 2, 4, nameWeight + creditsWeight*0.5},
+		{"créditos desconocidos", 1, 4, 0, nameWeight},
+		{"nombre parecido", 0.8, 4, 4, 0.83},
+		{"solo difieren en el numeral", ordinalMismatchPenalty, 4, 4, 0.575},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggestionScore(tt.similarity, tt.creditsA, tt.creditsB); got != roundScore(tt.want) {
+				t.Errorf("suggestionScore(%v, %d, %d) = %v, want %v", tt.similarity, tt.creditsA, tt.creditsB, got, roundScore(tt.want))
+			}
+		})
+	}
+
+	// Dos materias que solo difieren en el numeral no se sugieren aunque tengan los mismos créditos
+	similarity := nameSimilarity(parseSubjectName("Estadística I"), parseSubjectName("Estadística II"))
+	if score := suggestionScore(similarity, 3, 3); score >= DefaultSuggestionMinScore {
+		t.Errorf("Estadística I y Estadística II tienen puntaje %v, que alcanza el mínimo %v", score, DefaultSuggestionMinScore)
+	}
+}
@@ -0,0 +1,150 @@
+package functions
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/models"
+)
+
+// Reglas del régimen de transición entre versiones de un plan de estudio
+const (
+	TransitionRuleSameSubject   = "MISMA_ASIGNATURA"
+	TransitionRuleEquivalence   = "EQUIVALENCIA"
+	TransitionRuleTypologyShift = "CAMBIO_TIPOLOGIA"
+	TransitionRuleFreeElective  = "LIBRE_ELECCION"
+	TransitionRuleNoMapping     = "SIN_HOMOLOGACION"
+)
+
+// BuildTransitionReport mapea las materias aprobadas de un estudiante readmitido desde una versión
+// antigua del plan a la versión vigente, usando las equivalencias definidas para el plan destino
+func BuildTransitionReport(db *gorm.DB, academicHistory models.AcademicHistoryInput, sourceStudyPlanID, targetStudyPlanID uint) (*models.TransitionReport, error) {
+	if sourceStudyPlanID == targetStudyPlanID {
+		return nil, errors.New("el plan destino debe ser una versión distinta al plan de origen")
+	}
+
+	source, err := loadPlanContext(db, sourceStudyPlanID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := loadPlanContext(db, targetStudyPlanID)
+	if err != nil {
+		return nil, err
+	}
+	if source.StudyPlan.CareerID != target.StudyPlan.CareerID {
+		return nil, errors.New("los planes de origen y destino deben pertenecer a la misma carrera")
+	}
+
+	return buildTransitionReport(source, target, academicHistory), nil
+}
+
+// buildTransitionReport aplica las reglas de transición sobre planes ya cargados
+func buildTransitionReport(source, target *planContext, academicHistory models.AcademicHistoryInput) *models.TransitionReport {
+	academicHistory, diagnostics := source.resolveHistory(academicHistory)
+
+	// Solo aplican las equivalencias definidas para el plan destino
+	equivalentTargets := make(map[string][]string) // código antiguo -> códigos del plan destino
+	for _, equiv := range target.Equivalences {
+		if equiv.StudyPlanID != target.StudyPlan.ID {
+			continue
+		}
+		if _, inTarget := target.subjectsByCode[equiv.TargetSubject.Code]; inTarget {
+			equivalentTargets[equiv.SourceSubject.Code] = append(equivalentTargets[equiv.SourceSubject.Code], equiv.TargetSubject.Code)
+		}
+	}
+
+	report := &models.TransitionReport{
+		SourcePlanVersion: source.StudyPlan.Version,
+		TargetPlanVersion: target.StudyPlan.Version,
+		RulesApplied:      make(map[string]int),
+	}
+
+	remainingLibre := target.StudyPlan.LibreCredits
+	creditsByType := make(map[string]int)
+	satisfied := make(map[string]bool) // materias del plan destino ya cubiertas
+
+	for _, historySubject := range academicHistory.Subjects {
+		if historySubject.Status != "APROBADA" {
+			continue
+		}
+
+		item := models.TransitionItem{
+			HistoryCode: historySubject.Code,
+			HistoryName: historySubject.Name,
+			Credits:     historySubject.Credits,
+			SourceType:  historySubject.Type,
+		}
+		if sourceSubject, inSource := source.subjectsByCode[historySubject.Code]; inSource {
+			item.Credits = sourceSubject.Credits
+			item.SourceType = sourceSubject.Type
+		}
+
+		// 1. Buscar la materia en el plan destino, por código o por equivalencia
+		var targetSubject *models.Subject
+		rule := ""
+		if subject, exists := target.subjectsByCode[historySubject.Code]; exists && !satisfied[subject.Code] {
+			targetSubject = subject
+			rule = TransitionRuleSameSubject
+		} else {
+			for _, code := range equivalentTargets[historySubject.Code] {
+				if !satisfied[code] {
+					targetSubject = target.subjectsByCode[code]
+					rule = TransitionRuleEquivalence
+					break
+				}
+			}
+		}
+
+		switch {
+		case targetSubject != nil:
+			satisfied[targetSubject.Code] = true
+			item.TargetCode = targetSubject.Code
+			item.TargetName = targetSubject.Name
+			item.TargetType = targetSubject.Type
+			item.Credits = targetSubject.Credits
+			if rule == TransitionRuleSameSubject {
+				item.Description = "La asignatura se conserva en el plan " + target.StudyPlan.Version
+			} else {
+				item.Description = "Homologada por equivalencia con " + targetSubject.Code
+			}
+			if item.SourceType != "" && item.SourceType != targetSubject.Type {
+				item.Description += fmt.Sprintf("; pasa de %s a %s", item.SourceType, targetSubject.Type)
+				rule = TransitionRuleTypologyShift
+			}
+			if bucket := typologyBucket(targetSubject.Type); bucket != "" {
+				creditsByType[bucket] += targetSubject.Credits
+			}
+
+		// 2. Sin homologación, la materia cuenta como libre elección mientras haya cupo
+		case remainingLibre >= item.Credits:
+			rule = TransitionRuleFreeElective
+			item.TargetType = models.TipologiaLibreEleccion
+			item.Description = "Sin equivalente en el plan " + target.StudyPlan.Version + "; se reconoce como libre elección"
+			remainingLibre -= item.Credits
+			creditsByType["libre"] += item.Credits
+
+		default:
+			rule = TransitionRuleNoMapping
+			item.Description = "Sin equivalente en el plan " + target.StudyPlan.Version + " y sin cupo de libre elección"
+		}
+
+		item.Rule = rule
+		report.RulesApplied[rule]++
+		if rule == TransitionRuleNoMapping {
+			report.Lost = append(report.Lost, item)
+			report.LostCredits += item.Credits
+		} else {
+			report.CarriedOver = append(report.CarriedOver, item)
+			report.CarriedCredits += item.Credits
+		}
+	}
+
+	// 3. Calcular la situación del estudiante en el plan vigente
+	result := target.compare(satisfied)
+	target.summarize(result, creditsByType)
+	result.Diagnostics = diagnostics
+	report.Result = *result
+
+	return report
+}
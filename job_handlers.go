@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"olimpo-vicedecanatura/catalog"
+	"olimpo-vicedecanatura/config"
+	"olimpo-vicedecanatura/functions"
+	"olimpo-vicedecanatura/jobs"
+	"olimpo-vicedecanatura/models"
+)
+
+// Tipos de trabajos asíncronos
+const (
+	jobTypeBatchCompare  = "batch_compare"
+	jobTypeCatalogImport = "catalog_import"
+	jobTypeCatalogReport = "catalog_report"
+)
+
+// jobQueue es la cola de trabajos asíncronos del servidor
+var jobQueue *jobs.Queue
+
+// batchComparePayload son los datos de un trabajo de comparación en lote
+type batchComparePayload struct {
+	StudyPlanID uint               `json:"study_plan_id"`
+	Items       []models.BatchItem `json:"items"`
+	Explain     bool               `json:"explain"`
+	AsOf        *time.Time         `json:"as_of,omitempty"` // Catálogo del instante contra el que se compara
+}
+
+// catalogImportPayload son los datos de un trabajo de importación de catálogo. Quien importa es quien
+// creó el trabajo.
+type catalogImportPayload struct {
+	Format  string `json:"format"`  // json o yaml
+	Content string `json:"content"` // Contenido del archivo de catálogo
+	DryRun  bool   `json:"dry_run"`
+	Reason  string `json:"reason,omitempty"` // Justificación que queda en la auditoría
+}
+
+// catalogReportPayload son los datos de un trabajo que genera el archivo de catálogo de una carrera
+type catalogReportPayload struct {
+	CareerCode string `json:"career_code"`
+	Format     string `json:"format"`          // json, yaml, csv o xlsx
+	Table      string `json:"table,omitempty"` // Tabla a exportar en formato csv
+}
+
+// SubmitJobRequest estructura para la solicitud de creación de un trabajo asíncrono
+type SubmitJobRequest struct {
+	Type    string          `json:"type" binding:"required"`
+	Payload json.RawMessage `json:"payload" binding:"required"`
+}
+
+// startJobQueue crea la cola de trabajos, registra los tipos conocidos y arranca los workers
+func startJobQueue(ctx context.Context) {
+	jobQueue = jobs.NewQueue(config.DB, config.JobWorkers())
+	jobQueue.Register(jobTypeBatchCompare, runBatchCompareJob, validateBatchComparePayload)
+	jobQueue.Register(jobTypeCatalogImport, runCatalogImportJob, validateCatalogImportPayload)
+	jobQueue.Register(jobTypeCatalogReport, runCatalogReportJob, validateCatalogReportPayload)
+	jobQueue.Start(ctx)
+}
+
+// decodeJobPayload lee los datos de un trabajo rechazando campos desconocidos
+func decodeJobPayload(data []byte, payload interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(payload); err != nil {
+		return errors.New("los datos del trabajo no son válidos: " + err.Error())
+	}
+	return nil
+}
+
+// validateBatchComparePayload revisa los datos de una comparación en lote antes de encolarla
+func validateBatchComparePayload(data []byte) error {
+	var payload batchComparePayload
+	if err := decodeJobPayload(data, &payload); err != nil {
+		return err
+	}
+	if payload.StudyPlanID == 0 {
+		return errors.New("falta study_plan_id")
+	}
+	if len(payload.Items) == 0 {
+		return errors.New("el lote no contiene historias académicas")
+	}
+	if len(payload.Items) > maxBatchItems {
+		return errors.New("el lote supera el máximo de " + strconv.Itoa(maxBatchItems) + " historias")
+	}
+	return nil
+}
+
+// runBatchCompareJob ejecuta una comparación en lote informando el avance
+func runBatchCompareJob(ctx context.Context, job *models.Job, report func(progress int)) (interface{}, error) {
+	var payload batchComparePayload
+	if err := decodeJobPayload([]byte(job.Payload), &payload); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	workers := config.BatchWorkers()
+	opts := functions.CompareOptions{Explain: payload.Explain, AsOf: payload.AsOf}
+	results, err := functions.CompareBatch(ctx, config.DB, payload.StudyPlanID, payload.Items, workers, opts, func(done, total int) {
+		report(done * 100 / total)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"study_plan_id": payload.StudyPlanID,
+		"results":       results,
+		"summary":       batchSummary(results, workers),
+	}, nil
+}
+
+// validateCatalogImportPayload revisa que el archivo de catálogo de una importación sea válido
+func validateCatalogImportPayload(data []byte) error {
+	var payload catalogImportPayload
+	if err := decodeJobPayload(data, &payload); err != nil {
+		return err
+	}
+	_, err := catalog.Parse([]byte(payload.Content), payload.Format)
+	return err
+}
+
+// runCatalogImportJob importa un archivo de catálogo. Los errores del archivo no se reintentan.
+func runCatalogImportJob(ctx context.Context, job *models.Job, report func(progress int)) (interface{}, error) {
+	var payload catalogImportPayload
+	if err := decodeJobPayload([]byte(job.Payload), &payload); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	file, err := catalog.Parse([]byte(payload.Content), payload.Format)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	importReport, err := catalog.Import(config.DB, file, catalog.Options{
+		DryRun: payload.DryRun,
+		Actor:  job.CreatedBy,
+		Reason: payload.Reason,
+	})
+	var validationErr *catalog.ValidationError
+	var conflictErr *functions.PlanConflictError
+	var periodErr *functions.PlanPeriodError
+	if errors.As(err, &validationErr) || errors.As(err, &conflictErr) || errors.As(err, &periodErr) {
+		return nil, jobs.Permanent(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"report": importReport,
+	}, nil
+}
+
+// validateCatalogReportPayload revisa la carrera, el formato y la tabla de un reporte de catálogo
+func validateCatalogReportPayload(data []byte) error {
+	var payload catalogReportPayload
+	if err := decodeJobPayload(data, &payload); err != nil {
+		return err
+	}
+	return payload.validate()
+}
+
+// runCatalogReportJob genera el archivo de catálogo de una carrera. El archivo queda en el resultado
+// del trabajo y se descarga desde /jobs/:id/download.
+func runCatalogReportJob(ctx context.Context, job *models.Job, report func(progress int)) (interface{}, error) {
+	var payload catalogReportPayload
+	if err := decodeJobPayload([]byte(job.Payload), &payload); err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	catalogReport, err := buildCatalogReport(payload)
+	if errors.Is(err, catalog.ErrCareerNotFound) {
+		return nil, jobs.Permanent(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return catalogReport, nil
+}
+
+// submitJob crea un trabajo asíncrono del tipo indicado
+func submitJob(c *gin.Context) {
+	var req SubmitJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+	if !jobQueue.HasType(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo de trabajo desconocido: " + req.Type})
+		return
+	}
+
+	job, err := jobQueue.Submit(req.Type, req.Payload, requestActor(c))
+	if err != nil {
+		respondSubmitError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job": jobResponse(job),
+	})
+}
+
+// respondSubmitError responde con 400 si los datos del trabajo no son válidos y con 500 si no se pudo guardar
+func respondSubmitError(c *gin.Context, err error) {
+	var payloadErr *jobs.PayloadError
+	if errors.As(err, &payloadErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// getJobs lista los trabajos más recientes, con filtros opcionales por status y type
+func getJobs(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro limit debe estar entre 1 y 200"})
+		return
+	}
+
+	query := config.DB.Model(&models.Job{}).Omit("payload", "result")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if jobType := c.Query("type"); jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+
+	var jobList []models.Job
+	if err := query.Order("created_at DESC").Limit(limit).Find(&jobList).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo trabajos"})
+		return
+	}
+
+	response := make([]gin.H, 0, len(jobList))
+	for i := range jobList {
+		response = append(response, jobResponse(&jobList[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"jobs": response,
+	})
+}
+
+// getJob obtiene el estado de un trabajo y, si terminó, su resultado
+func getJob(c *gin.Context) {
+	job, ok := findJob(c)
+	if !ok {
+		return
+	}
+
+	response := gin.H{
+		"job": jobResponse(job),
+	}
+	if job.Result != nil {
+		response["result"] = json.RawMessage(*job.Result)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// downloadJobResult descarga el archivo generado por un trabajo de reporte terminado
+func downloadJobResult(c *gin.Context) {
+	job, ok := findJob(c)
+	if !ok {
+		return
+	}
+	if job.Type != jobTypeCatalogReport {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El trabajo no genera un archivo"})
+		return
+	}
+	if job.Status != models.JobStatusCompleted || job.Result == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "El trabajo no ha terminado correctamente (estado " + job.Status + ")"})
+		return
+	}
+
+	var report catalogReport
+	if err := json.Unmarshal([]byte(*job.Result), &report); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "El resultado del trabajo no es válido"})
+		return
+	}
+	sendCatalogReport(c, &report)
+}
+
+// cancelJob cancela un trabajo pendiente o en ejecución
+func cancelJob(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de trabajo inválido"})
+		return
+	}
+
+	job, err := jobQueue.Cancel(uint(jobID))
+	if errors.Is(err, jobs.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job": jobResponse(job),
+	})
+}
+
+// streamJobEvents envía el avance de un trabajo como server-sent events hasta que termina
+func streamJobEvents(c *gin.Context) {
+	job, ok := findJob(c)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	lastStatus, lastProgress := "", -1
+	c.Stream(func(w io.Writer) bool {
+		if job.Status != lastStatus || job.Progress != lastProgress {
+			lastStatus, lastProgress = job.Status, job.Progress
+			c.SSEvent("progress", jobResponse(job))
+		}
+		if isJobFinished(job.Status) {
+			c.SSEvent("done", jobResponse(job))
+			return false
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-ticker.C:
+		}
+
+		current, err := jobQueue.Get(job.ID)
+		if err != nil {
+			c.SSEvent("error", gin.H{"error": err.Error()})
+			return false
+		}
+		job = current
+		return true
+	})
+}
+
+// findJob obtiene el trabajo indicado en la ruta o responde con el error correspondiente
+func findJob(c *gin.Context) (*models.Job, bool) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de trabajo inválido"})
+		return nil, false
+	}
+	job, err := jobQueue.Get(uint(jobID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return job, true
+}
+
+// isJobFinished indica si un trabajo está en un estado final
+func isJobFinished(status string) bool {
+	return status == models.JobStatusCompleted || status == models.JobStatusFailed || status == models.JobStatusCancelled
+}
+
+// jobResponse arma la información pública de un trabajo, sin sus datos de entrada ni su resultado
+func jobResponse(job *models.Job) gin.H {
+	return gin.H{
+		"id":           job.ID,
+		"type":         job.Type,
+		"status":       job.Status,
+		"progress":     job.Progress,
+		"attempts":     job.Attempts,
+		"max_attempts": job.MaxAttempts,
+		"error":        job.Error,
+		"created_by":   job.CreatedBy,
+		"created_at":   job.CreatedAt,
+		"started_at":   job.StartedAt,
+		"finished_at":  job.FinishedAt,
+	}
+}
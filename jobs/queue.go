@@ -0,0 +1,433 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"olimpo-vicedecanatura/models"
+)
+
+// Handler ejecuta un trabajo de un tipo determinado. report permite informar el porcentaje de avance.
+// El contexto se cancela cuando se solicita la cancelación del trabajo.
+type Handler func(ctx context.Context, job *models.Job, report func(progress int)) (interface{}, error)
+
+// Validator revisa los datos de un trabajo antes de encolarlo
+type Validator func(payload []byte) error
+
+// ErrJobNotFound se retorna cuando el trabajo solicitado no existe
+var ErrJobNotFound = errors.New("trabajo no encontrado")
+
+// PayloadError se retorna al encolar un trabajo cuyos datos no son válidos para su tipo
+type PayloadError struct {
+	Err error
+}
+
+func (e *PayloadError) Error() string {
+	return "los datos del trabajo no son válidos: " + e.Err.Error()
+}
+
+func (e *PayloadError) Unwrap() error {
+	return e.Err
+}
+
+// PermanentError marca un error que no se corrige reintentando, como datos del trabajo inválidos.
+// El trabajo falla de inmediato sin agotar sus intentos.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent envuelve un error para que el trabajo falle sin reintentarse
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// Queue es una cola de trabajos respaldada por la tabla jobs y atendida por workers en el mismo proceso
+type Queue struct {
+	db           *gorm.DB
+	workers      int
+	pollInterval time.Duration
+	retryDelay   time.Duration
+	lease        time.Duration
+	handlers     map[string]Handler
+	validators   map[string]Validator
+
+	mu      sync.Mutex
+	running map[uint]context.CancelFunc
+	wake    chan struct{}
+}
+
+// NewQueue crea una cola de trabajos con el número de workers indicado
+func NewQueue(db *gorm.DB, workers int) *Queue {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Queue{
+		db:           db,
+		workers:      workers,
+		pollInterval: 2 * time.Second,
+		retryDelay:   30 * time.Second,
+		lease:        time.Minute,
+		handlers:     make(map[string]Handler),
+		validators:   make(map[string]Validator),
+		running:      make(map[uint]context.CancelFunc),
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Register asocia un tipo de trabajo con la función que lo ejecuta y la que revisa sus datos al encolarlo.
+// validate puede ser nulo si el tipo no necesita revisión.
+func (q *Queue) Register(jobType string, handler Handler, validate Validator) {
+	q.handlers[jobType] = handler
+	if validate != nil {
+		q.validators[jobType] = validate
+	}
+}
+
+// HasType indica si la cola sabe ejecutar un tipo de trabajo
+func (q *Queue) HasType(jobType string) bool {
+	_, exists := q.handlers[jobType]
+	return exists
+}
+
+// Start arranca los workers y la revisión de trabajos abandonados. Un trabajo en ejecución renueva su
+// señal de vida (heartbeat_at) mientras su worker lo atiende; si la señal vence, el proceso que lo tenía
+// se detuvo y el trabajo vuelve a la cola. Así varias instancias pueden compartir la tabla sin quitarse
+// trabajos vivos.
+func (q *Queue) Start(ctx context.Context) {
+	q.recoverAbandoned()
+	go func() {
+		ticker := time.NewTicker(q.lease / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				q.recoverAbandoned()
+			}
+		}
+	}()
+
+	for i := 0; i < q.workers; i++ {
+		go q.work(ctx)
+	}
+}
+
+// recoverAbandoned retoma los trabajos en ejecución cuya señal de vida venció: vuelven a la cola, salvo
+// que se hubiera solicitado su cancelación o ya hubieran agotado sus intentos
+func (q *Queue) recoverAbandoned() {
+	now := time.Now()
+	expired := q.db.Model(&models.Job{}).
+		Where("status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", models.JobStatusRunning, now.Add(-q.lease))
+
+	if err := expired.Session(&gorm.Session{}).Where("cancel_requested = ?", true).
+		Updates(map[string]interface{}{"status": models.JobStatusCancelled, "finished_at": now}).Error; err != nil {
+		log.Printf("Error cancelando trabajos abandonados: %v", err)
+	}
+	if err := expired.Session(&gorm.Session{}).Where("attempts >= max_attempts").
+		Updates(map[string]interface{}{"status": models.JobStatusFailed, "error": "el trabajo se interrumpió y agotó sus intentos", "finished_at": now}).Error; err != nil {
+		log.Printf("Error cerrando trabajos abandonados: %v", err)
+	}
+	result := expired.Session(&gorm.Session{}).
+		Updates(map[string]interface{}{"status": models.JobStatusPending, "available_at": now})
+	if result.Error != nil {
+		log.Printf("Error retomando trabajos abandonados: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("Se retomaron %d trabajos interrumpidos", result.RowsAffected)
+		q.notify()
+	}
+}
+
+// Submit guarda un nuevo trabajo en la cola
+func (q *Queue) Submit(jobType string, payload interface{}, createdBy string) (*models.Job, error) {
+	if !q.HasType(jobType) {
+		return nil, errors.New("tipo de trabajo desconocido: " + jobType)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.New("error serializando los datos del trabajo")
+	}
+	if validate, exists := q.validators[jobType]; exists {
+		if err := validate(data); err != nil {
+			return nil, &PayloadError{Err: err}
+		}
+	}
+
+	job := models.Job{
+		Type:        jobType,
+		Status:      models.JobStatusPending,
+		Payload:     string(data),
+		MaxAttempts: 3,
+		AvailableAt: time.Now(),
+		CreatedBy:   createdBy,
+	}
+	if err := q.db.Create(&job).Error; err != nil {
+		return nil, errors.New("error guardando el trabajo")
+	}
+
+	q.notify()
+	return &job, nil
+}
+
+// notify despierta a un worker sin esperar al siguiente sondeo
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Get obtiene un trabajo por su ID
+func (q *Queue) Get(jobID uint) (*models.Job, error) {
+	var job models.Job
+	if err := q.db.First(&job, jobID).Error; err != nil {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+// Cancel cancela un trabajo pendiente o solicita la cancelación de uno en ejecución
+func (q *Queue) Cancel(jobID uint) (*models.Job, error) {
+	job, err := q.Get(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch job.Status {
+	case models.JobStatusPending:
+		now := time.Now()
+		result := q.db.Model(&models.Job{}).
+			Where("id = ? AND status = ?", jobID, models.JobStatusPending).
+			Updates(map[string]interface{}{"status": models.JobStatusCancelled, "cancel_requested": true, "finished_at": now})
+		if result.Error != nil {
+			return nil, errors.New("error cancelando el trabajo")
+		}
+		if result.RowsAffected == 0 {
+			// Un worker lo tomó mientras tanto: se cancela como trabajo en ejecución
+			return q.Cancel(jobID)
+		}
+	case models.JobStatusRunning:
+		if err := q.db.Model(&models.Job{}).Where("id = ?", jobID).Update("cancel_requested", true).Error; err != nil {
+			return nil, errors.New("error cancelando el trabajo")
+		}
+		q.mu.Lock()
+		if cancel, exists := q.running[jobID]; exists {
+			cancel()
+		}
+		q.mu.Unlock()
+	default:
+		return nil, errors.New("el trabajo ya terminó con estado " + job.Status)
+	}
+
+	return q.Get(jobID)
+}
+
+// work atiende la cola hasta que se cancela el contexto
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := q.claim()
+		if err != nil {
+			log.Printf("Error obteniendo trabajo de la cola: %v", err)
+		}
+		if job != nil {
+			q.run(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// claim toma el siguiente trabajo disponible, bloqueándolo para que ningún otro worker lo tome
+func (q *Queue) claim() (*models.Job, error) {
+	var job models.Job
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND available_at <= ?", models.JobStatusPending, time.Now()).
+			Order("id").
+			Limit(1).
+			Find(&job)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		now := time.Now()
+		job.Status = models.JobStatusRunning
+		job.Attempts++
+		job.StartedAt = &now
+		job.HeartbeatAt = &now
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":       job.Status,
+			"attempts":     job.Attempts,
+			"started_at":   now,
+			"heartbeat_at": now,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// run ejecuta un trabajo y guarda su resultado, su error o su cancelación
+func (q *Queue) run(parent context.Context, job *models.Job) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	q.mu.Lock()
+	q.running[job.ID] = cancel
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, job.ID)
+		q.mu.Unlock()
+	}()
+
+	// Mientras el trabajo corre se renueva su señal de vida y se revisa si se solicitó su cancelación, que
+	// puede venir de otra instancia del servidor. Si el trabajo dejó de pertenecer a este worker (otra
+	// instancia lo retomó tras vencer la señal) se detiene.
+	go func() {
+		ticker := time.NewTicker(q.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				result := q.owned(job).Update("heartbeat_at", time.Now())
+				if result.Error != nil {
+					log.Printf("Error renovando el trabajo %d: %v", job.ID, result.Error)
+					continue
+				}
+				if result.RowsAffected == 0 {
+					cancel()
+					return
+				}
+				var current models.Job
+				if err := q.db.Select("cancel_requested").First(&current, job.ID).Error; err == nil && current.CancelRequested {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	lastProgress := job.Progress
+	report := func(progress int) {
+		if progress < 0 {
+			progress = 0
+		}
+		if progress > 100 {
+			progress = 100
+		}
+		if progress == lastProgress {
+			return
+		}
+		lastProgress = progress
+		if err := q.owned(job).Update("progress", progress).Error; err != nil {
+			log.Printf("Error actualizando el avance del trabajo %d: %v", job.ID, err)
+		}
+	}
+
+	result, err := q.execute(ctx, job, report)
+	now := time.Now()
+	updates := map[string]interface{}{}
+
+	var current models.Job
+	cancelRequested := q.db.Select("cancel_requested").First(&current, job.ID).Error == nil && current.CancelRequested
+	var permanent *PermanentError
+
+	switch {
+	case err == nil:
+		data, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			updates["status"] = models.JobStatusFailed
+			updates["error"] = "error serializando el resultado del trabajo"
+		} else {
+			updates["status"] = models.JobStatusCompleted
+			updates["result"] = string(data)
+			updates["progress"] = 100
+			updates["error"] = ""
+		}
+		updates["finished_at"] = now
+	case cancelRequested || (ctx.Err() != nil && parent.Err() == nil):
+		updates["status"] = models.JobStatusCancelled
+		updates["finished_at"] = now
+	case parent.Err() != nil:
+		// El servidor se está apagando: el trabajo se retomará al reiniciar
+		updates["status"] = models.JobStatusPending
+		updates["available_at"] = now
+	case job.Attempts < job.MaxAttempts && !errors.As(err, &permanent):
+		updates["status"] = models.JobStatusPending
+		updates["error"] = err.Error()
+		updates["available_at"] = now.Add(time.Duration(job.Attempts) * q.retryDelay)
+	default:
+		updates["status"] = models.JobStatusFailed
+		updates["error"] = err.Error()
+		updates["finished_at"] = now
+	}
+
+	updates["heartbeat_at"] = nil
+
+	saved := q.owned(job).Updates(updates)
+	if saved.Error != nil {
+		log.Printf("Error guardando el estado del trabajo %d: %v", job.ID, saved.Error)
+	} else if saved.RowsAffected == 0 {
+		log.Printf("El trabajo %d fue retomado por otro worker; se descarta este resultado", job.ID)
+	}
+}
+
+// owned limita una actualización al trabajo mientras siga en ejecución en el intento que tomó este worker
+func (q *Queue) owned(job *models.Job) *gorm.DB {
+	return q.db.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND attempts = ?", job.ID, models.JobStatusRunning, job.Attempts)
+}
+
+// execute invoca el handler del trabajo protegiendo al worker de errores inesperados
+func (q *Queue) execute(ctx context.Context, job *models.Job, report func(progress int)) (result interface{}, err error) {
+	handler, exists := q.handlers[job.Type]
+	if !exists {
+		return nil, errors.New("tipo de trabajo desconocido: " + job.Type)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Error inesperado ejecutando el trabajo %d: %v", job.ID, r)
+			result = nil
+			err = errors.New("error interno ejecutando el trabajo")
+		}
+	}()
+
+	return handler(ctx, job, report)
+}
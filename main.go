@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"github.com/joho/godotenv"
 	"github.com/gin-gonic/gin"
@@ -13,6 +15,7 @@ import (
 	"strings"
 	"errors"
 	"regexp"
+	"time"
 )
 
 
@@ -101,16 +104,35 @@ func main() {
 	database.RunMigrations(config.DB)
 	log.Println("✅ Migraciones ejecutadas exitosamente")
 
+	// Ejecutar un subcomando (por ejemplo import-catalog) en lugar de iniciar el servidor
+	if handled, err := runCommand(config.DB, os.Args[1:]); handled {
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
 	// Insertar datos iniciales (opcional)
 	database.SeedInitialData(config.DB)
 	log.Println("✅ Datos iniciales cargados (si era necesario)")
 
+	// Construir la copia en memoria del catálogo usada por las comparaciones
+	if err := functions.LoadCatalog(config.DB); err != nil {
+		log.Printf("Error cargando el catálogo en memoria (se reintentará en la primera comparación): %v", err)
+	} else {
+		log.Println("✅ Catálogo cargado en memoria")
+	}
+
+	// Iniciar la cola de trabajos asíncronos (retoma los trabajos pendientes)
+	startJobQueue(context.Background())
+	log.Println("✅ Cola de trabajos asíncronos iniciada")
+
 	// Configurar CORS y middlewares
 	r := gin.Default()
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-User")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -125,12 +147,77 @@ func main() {
 			"status":  "online",
 			"db":      "connected",
 			"endpoints": []string{
-				"GET /api/careers - Obtener todas las carreras",
+				"GET /api/sedes - Listar las sedes",
+				"POST /api/sedes - Registrar una sede",
+				"GET /api/sedes/:code - Obtener una sede por su código",
+				"PUT /api/sedes/:code - Actualizar una sede",
+				"DELETE /api/sedes/:code - Eliminar una sede sin facultades, materias ni planes",
+				"GET /api/faculties?sede= - Listar las facultades",
+				"POST /api/faculties - Registrar una facultad",
+				"GET /api/faculties/:code - Obtener una facultad por su código",
+				"PUT /api/faculties/:code - Actualizar una facultad",
+				"DELETE /api/faculties/:code - Eliminar una facultad sin carreras",
+				"GET /api/careers?faculty=&sede= - Obtener las carreras, filtradas por facultad o sede",
+				"POST /api/careers - Registrar una carrera",
+				"GET /api/careers/:code - Obtener una carrera por su código",
+				"PUT /api/careers/:code - Actualizar una carrera",
+				"DELETE /api/careers/:code - Eliminar una carrera sin planes de estudio (borrado lógico; crearla de nuevo la restaura)",
 				"GET /api/careers/:code/study-plans - Obtener planes de estudio de una carrera",
-				"GET /api/study-plans/:id - Obtener detalles de un plan de estudio",
-				"POST /api/compare - Comparar historia académica con plan de estudio",
-				"POST /api/compare-by-career - Comparar por código de carrera",
-				"POST /api/api-compare - Comparar historia académica en texto plano",
+				"GET /api/study-plans/:id?as_of= - Obtener detalles de un plan de estudio, actual o como estaba en una fecha",
+				"POST /api/study-plans - Crear un plan de estudio (como borrador inactivo)",
+				"PUT /api/study-plans/:id - Editar la versión, vigencia y sede de un plan o activarlo/desactivarlo (un solo plan activo por carrera y sede)",
+				"PUT /api/study-plans/:id/requirements - Definir los créditos exigidos por tipología",
+				"POST /api/study-plans/:id/subjects - Asociar una materia al plan con su tipología",
+				"DELETE /api/study-plans/:id/subjects/:code - Retirar una materia del plan",
+				"GET /api/study-plans/:id/validation - Revisar la consistencia de créditos del plan",
+				"GET /api/study-plans/:id/prerequisites - Grafo de prerrequisitos del plan por niveles",
+				"POST /api/study-plans/:id/prerequisites - Agregar un prerrequisito (rechaza ciclos)",
+				"DELETE /api/study-plans/:id/prerequisites/:code/:prerequisite - Eliminar un prerrequisito",
+				"POST /api/study-plans/:id/clone - Crear una nueva versión inactiva a partir del plan",
+				"GET /api/study-plans/diff?from=&to= - Diferencias entre dos versiones de un plan",
+				"GET /api/subjects?q=&type=&credits=&study_plan_id= - Buscar materias por código o nombre (sin distinguir tildes)",
+				"POST /api/subjects - Registrar una materia",
+				"GET /api/subjects/:code - Obtener una materia por su código",
+				"PUT /api/subjects/:code - Actualizar una materia",
+				"DELETE /api/subjects/:code - Eliminar una materia sin uso (borrado lógico; crearla de nuevo la restaura)",
+				"GET /api/subjects/:code/aliases - Listar los códigos alternos de una materia",
+				"POST /api/subjects/:code/aliases - Registrar un código alterno (alias) de una materia",
+				"DELETE /api/subjects/:code/aliases/:alias - Eliminar un alias de una materia",
+				"GET /api/equivalences?status=&study_plan_id=&subject_code= - Listar equivalencias",
+				"GET /api/equivalences/:id - Obtener una equivalencia",
+				"GET /api/equivalences/suggestions?source_plan_id=&target_plan_id=&min_score= - Sugerir equivalencias entre planes por nombre y créditos",
+				"POST /api/equivalences/suggestions - Proponer las equivalencias sugeridas entre dos planes",
+				"POST /api/equivalences - Proponer una equivalencia",
+				"POST /api/equivalences/:id/review - Pasar una equivalencia propuesta a revisión",
+				"POST /api/equivalences/:id/approve - Aprobar una equivalencia (requiere número y fecha de resolución)",
+				"POST /api/equivalences/:id/reject - Rechazar una equivalencia",
+				"POST /api/equivalences/:id/retire - Retirar una equivalencia aprobada",
+				"DELETE /api/equivalences/:id - Eliminar una equivalencia no aprobada (borrado lógico)",
+				"GET /api/catalog/status?as_of= - Estado de la copia en memoria del catálogo, o resumen del catálogo de una fecha",
+				"POST /api/catalog/reload - Reconstruir la copia en memoria del catálogo",
+				"POST /api/catalog/import - Importar una carrera y sus planes desde un archivo JSON o YAML (dry_run=true para solo reportar, async=true para encolarla como trabajo)",
+				"GET /api/catalog/export/:code - Exportar una carrera y sus planes (format=json, yaml, csv con table=, o xlsx; async=true para generarlo como trabajo)",
+				"GET /api/audit?entity_type=&entity_id=&entity_key=&actor=&from=&to= - Consultar la auditoría de cambios del catálogo",
+				"GET /api/study-plans/:id/groups - Obtener agrupaciones de un plan de estudio",
+				"POST /api/study-plans/:id/groups - Crear una agrupación con mínimo de créditos",
+				"PUT /api/study-plans/:id/groups/:group - Actualizar una agrupación (nombre, tipología, mínimo de créditos y materias)",
+				"DELETE /api/study-plans/:id/groups/:group - Eliminar una agrupación",
+				"POST /api/compare - Comparar historia académica con plan de estudio (?explain=true incluye la traza, ?as_of=AAAA-MM-DD usa el catálogo de esa fecha)",
+				"POST /api/compare-by-career - Comparar por código de carrera (?explain=true incluye la traza, ?as_of=AAAA-MM-DD usa el catálogo de esa fecha)",
+				"POST /api/api-compare - Comparar historia académica en texto plano (?explain=true incluye la traza, ?as_of=AAAA-MM-DD usa el catálogo de esa fecha)",
+				"POST /api/compare/batch - Comparar muchas historias académicas (JSON o zip de archivos .txt, ?async=true lo encola, ?as_of= usa el catálogo de esa fecha)",
+				"POST /api/jobs - Crear un trabajo asíncrono (batch_compare, catalog_import o catalog_report)",
+				"GET /api/jobs - Listar trabajos asíncronos",
+				"GET /api/jobs/:id - Consultar el estado y resultado de un trabajo",
+				"POST /api/jobs/:id/cancel - Cancelar un trabajo",
+				"GET /api/jobs/:id/events - Avance de un trabajo como server-sent events",
+				"GET /api/jobs/:id/download - Descargar el archivo generado por un trabajo catalog_report",
+				"GET /api/comparison-runs?career_code=&faculty=&sede= - Listar comparaciones guardadas",
+				"GET /api/comparison-runs/:id - Obtener una comparación guardada",
+				"GET /api/comparison-runs/diff?from=&to= - Diferencias entre dos comparaciones guardadas",
+				"POST /api/comparison-runs/:id/rerun - Volver a ejecutar una comparación guardada (?as_of=original reproduce la decisión con el catálogo de entonces)",
+				"POST /api/double-degree - Analizar créditos compartidos para doble titulación",
+				"POST /api/transition - Migrar historia académica a la versión vigente del plan (régimen de transición)",
 			},
 		})
 	})
@@ -139,15 +226,87 @@ func main() {
 	// API Routes
 	api := r.Group("/api")
 	{
+		// Sedes y facultades
+		api.GET("/sedes", getSedes)
+		api.POST("/sedes", createSede)
+		api.GET("/sedes/:code", getSedeByCode)
+		api.PUT("/sedes/:code", updateSede)
+		api.DELETE("/sedes/:code", deleteSede)
+		api.GET("/faculties", getFaculties)
+		api.POST("/faculties", createFaculty)
+		api.GET("/faculties/:code", getFacultyByCode)
+		api.PUT("/faculties/:code", updateFaculty)
+		api.DELETE("/faculties/:code", deleteFaculty)
+		
 		// Obtener todas las carreras disponibles
 		api.GET("/careers", getCareers)
 		
+		// Administración de carreras
+		api.POST("/careers", createCareer)
+		api.GET("/careers/:code", getCareerByCode)
+		api.PUT("/careers/:code", updateCareer)
+		api.DELETE("/careers/:code", deleteCareer)
+		
 		// Obtener planes de estudio de una carrera específica
 		api.GET("/careers/:code/study-plans", getStudyPlansByCareer)
 		
 		// Obtener detalles de un plan de estudio específico
 		api.GET("/study-plans/:id", getStudyPlanDetails)
 		
+		// Edición de planes de estudio
+		api.POST("/study-plans", createStudyPlan)
+		api.PUT("/study-plans/:id", updateStudyPlan)
+		api.PUT("/study-plans/:id/requirements", setStudyPlanRequirements)
+		api.POST("/study-plans/:id/subjects", attachStudyPlanSubject)
+		api.DELETE("/study-plans/:id/subjects/:code", detachStudyPlanSubject)
+		api.GET("/study-plans/:id/validation", validateStudyPlan)
+		
+		// Prerrequisitos de las materias dentro de un plan
+		api.GET("/study-plans/:id/prerequisites", getStudyPlanPrerequisites)
+		api.POST("/study-plans/:id/prerequisites", addStudyPlanPrerequisite)
+		api.DELETE("/study-plans/:id/prerequisites/:code/:prerequisite", removeStudyPlanPrerequisite)
+		
+		// Versiones de un plan de estudio
+		api.POST("/study-plans/:id/clone", cloneStudyPlan)
+		api.GET("/study-plans/diff", diffStudyPlans)
+		
+		// Catálogo de materias
+		api.GET("/subjects", searchSubjects)
+		api.POST("/subjects", createSubject)
+		api.GET("/subjects/:code", getSubjectByCode)
+		api.PUT("/subjects/:code", updateSubject)
+		api.DELETE("/subjects/:code", deleteSubject)
+		api.GET("/subjects/:code/aliases", getSubjectAliases)
+		api.POST("/subjects/:code/aliases", addSubjectAlias)
+		api.DELETE("/subjects/:code/aliases/:alias", removeSubjectAlias)
+		
+		// Equivalencias y su flujo de aprobación por el comité
+		api.GET("/equivalences", getEquivalences)
+		api.GET("/equivalences/:id", getEquivalence)
+		api.POST("/equivalences", proposeEquivalence)
+		api.GET("/equivalences/suggestions", getEquivalenceSuggestions)
+		api.POST("/equivalences/suggestions", proposeEquivalenceSuggestions)
+		api.POST("/equivalences/:id/review", decideEquivalence(functions.EquivalenceActionReview))
+		api.POST("/equivalences/:id/approve", decideEquivalence(functions.EquivalenceActionApprove))
+		api.POST("/equivalences/:id/reject", decideEquivalence(functions.EquivalenceActionReject))
+		api.POST("/equivalences/:id/retire", decideEquivalence(functions.EquivalenceActionRetire))
+		api.DELETE("/equivalences/:id", deleteEquivalence)
+		
+		// Copia en memoria del catálogo
+		api.GET("/catalog/status", getCatalogStatus)
+		api.POST("/catalog/reload", reloadCatalog)
+		api.POST("/catalog/import", importCatalog)
+		api.GET("/catalog/export/:code", exportCatalog)
+		
+		// Auditoría de cambios del catálogo
+		api.GET("/audit", getAuditLogs)
+		
+		// Agrupaciones de asignaturas de un plan de estudio
+		api.GET("/study-plans/:id/groups", getStudyPlanGroups)
+		api.POST("/study-plans/:id/groups", createStudyPlanGroup)
+		api.PUT("/study-plans/:id/groups/:group", updateStudyPlanGroup)
+		api.DELETE("/study-plans/:id/groups/:group", deleteStudyPlanGroup)
+		
 		// Comparar historia académica con plan de estudio
 		api.POST("/compare", compareAcademicHistory)
 		
@@ -156,6 +315,29 @@ func main() {
 		
 		// Nuevo endpoint para comparar historia académica en texto plano
 		api.POST("/api-compare", compareAcademicHistoryFromText)
+		
+		// Comparación de muchas historias en una sola solicitud
+		api.POST("/compare/batch", compareBatch)
+		
+		// Trabajos asíncronos
+		api.POST("/jobs", submitJob)
+		api.GET("/jobs", getJobs)
+		api.GET("/jobs/:id", getJob)
+		api.POST("/jobs/:id/cancel", cancelJob)
+		api.GET("/jobs/:id/events", streamJobEvents)
+		api.GET("/jobs/:id/download", downloadJobResult)
+		
+		// Comparaciones guardadas
+		api.GET("/comparison-runs", getComparisonRuns)
+		api.GET("/comparison-runs/diff", diffComparisonRuns)
+		api.GET("/comparison-runs/:id", getComparisonRun)
+		api.POST("/comparison-runs/:id/rerun", rerunComparison)
+		
+		// Análisis de créditos compartidos para doble titulación
+		api.POST("/double-degree", compareDoubleDegree)
+		
+		// Reporte de régimen de transición para estudiantes readmitidos
+		api.POST("/transition", compareTransition)
 	}
 
 
@@ -166,10 +348,18 @@ func main() {
 	}
 }
 
-// getCareers obtiene todas las carreras disponibles
+// getCareers obtiene todas las carreras disponibles, con filtros opcionales por faculty y sede (códigos)
 func getCareers(c *gin.Context) {
+	query := config.DB.Preload("Faculty.Sede")
+	if faculty := c.Query("faculty"); faculty != "" {
+		query = query.Where("faculty_id IN (SELECT id FROM faculties WHERE code = ?)", strings.ToUpper(faculty))
+	}
+	if sede := c.Query("sede"); sede != "" {
+		query = query.Where("faculty_id IN (SELECT faculties.id FROM faculties JOIN sedes ON sedes.id = faculties.sede_id WHERE sedes.code = ?)", strings.ToUpper(sede))
+	}
+
 	var careers []models.Career
-	if err := config.DB.Find(&careers).Error; err != nil {
+	if err := query.Find(&careers).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo carreras"})
 		return
 	}
@@ -187,6 +377,7 @@ func getStudyPlansByCareer(c *gin.Context) {
 	if err := config.DB.Preload("Career").
 		Joins("JOIN careers ON careers.id = study_plans.career_id").
 		Where("careers.code = ?", careerCode).
+		Order("study_plans.effective_from, study_plans.id").
 		Find(&studyPlans).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo planes de estudio"})
 		return
@@ -202,19 +393,39 @@ func getStudyPlansByCareer(c *gin.Context) {
 	})
 }
 
-// getStudyPlanDetails obtiene los detalles completos de un plan de estudio
+// getStudyPlanDetails obtiene los detalles completos de un plan de estudio. Con ?as_of=AAAA-MM-DD se
+// obtiene el plan como estaba en esa fecha.
 func getStudyPlanDetails(c *gin.Context) {
 	studyPlanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de plan de estudio inválido"})
 		return
 	}
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
 	
 	var studyPlan models.StudyPlan
-	if err := config.DB.Preload("Career").Preload("Subjects").
-		First(&studyPlan, uint(studyPlanID)).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Plan de estudio no encontrado"})
-		return
+	if asOf != nil {
+		plan, err := functions.GetStudyPlanAsOf(config.DB, uint(studyPlanID), asOf)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Plan de estudio no encontrado en el catálogo de esa fecha"})
+			return
+		}
+		studyPlan = *plan
+	} else {
+		if err := config.DB.Preload("Career").Preload("Subjects").Preload("Groups.Subjects").
+			First(&studyPlan, uint(studyPlanID)).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Plan de estudio no encontrado"})
+			return
+		}
+		
+		// Usar la tipología y los créditos con los que cada materia cuenta en este plan
+		if err := functions.ApplyPlanSubjectSettings(config.DB, &studyPlan); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 	}
 	
 	// Calcular estadísticas del plan
@@ -248,18 +459,23 @@ func compareAcademicHistory(c *gin.Context) {
 		return
 	}
 	
+	opts, ok := compareOptions(c)
+	if !ok {
+		return
+	}
+	
 	// Realizar la comparación usando la función que creamos
-	result, err := functions.CompareAcademicHistoryWithStudyPlan(config.DB, req.AcademicHistory, req.StudyPlanID)
+	result, studyPlan, err := functions.CompareAcademicHistoryWithStudyPlan(config.DB, req.AcademicHistory, req.StudyPlanID, opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	
-	// Obtener información adicional del plan de estudio para el contexto
-	var studyPlan models.StudyPlan
-	config.DB.Preload("Career").First(&studyPlan, req.StudyPlanID)
+	// Guardar la comparación para poder consultarla después
+	runID := saveComparisonRun(c, "compare", studyPlan, req.AcademicHistory, opts, result)
 	
 	c.JSON(http.StatusOK, gin.H{
+		"comparison_run_id": runID,
 		"comparison_result": result,
 		"study_plan_info": gin.H{
 			"id":      studyPlan.ID,
@@ -283,17 +499,23 @@ func compareByCareerCode(c *gin.Context) {
 		return
 	}
 	
+	opts, ok := compareOptions(c)
+	if !ok {
+		return
+	}
+	
 	// Realizar la comparación usando el código de carrera
-	result, err := functions.CompareAcademicHistoryByCareerCode(config.DB, academicHistory)
+	result, studyPlan, err := functions.CompareAcademicHistoryByCareerCode(config.DB, academicHistory, opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	
-	// Obtener información del plan de estudio usado
-	studyPlan, _ := functions.GetStudyPlanByCareerCode(config.DB, academicHistory.CareerCode)
+		
+	// Guardar la comparación para poder consultarla después
+	runID := saveComparisonRun(c, "compare-by-career", studyPlan, academicHistory, opts, result)
 	
 	c.JSON(http.StatusOK, gin.H{
+		"comparison_run_id": runID,
 		"comparison_result": result,
 		"study_plan_info": gin.H{
 			"id":      studyPlan.ID,
@@ -309,6 +531,64 @@ func compareByCareerCode(c *gin.Context) {
 	})
 }
 
+// compareOptions lee las opciones del motor de comparación desde la query (?explain=true y ?as_of= para
+// comparar contra el catálogo de una fecha pasada). Si as_of no es válido responde con el error.
+func compareOptions(c *gin.Context) (functions.CompareOptions, bool) {
+	explain, _ := strconv.ParseBool(c.Query("explain"))
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return functions.CompareOptions{}, false
+	}
+	return functions.CompareOptions{Explain: explain, AsOf: asOf}, true
+}
+
+// parseAsOf lee la fecha de corte ?as_of= y verifica que la auditoría permita reconstruir el catálogo de
+// esa fecha; si no, responde con el error
+func parseAsOf(c *gin.Context) (*time.Time, bool) {
+	asOf, err := functions.ParseAsOf(c.Query("as_of"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err := functions.CheckAsOfCoverage(config.DB, asOf); err != nil {
+		var beforeAudit *functions.AsOfBeforeAuditError
+		if errors.As(err, &beforeAudit) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return nil, false
+	}
+	return asOf, true
+}
+
+// requestActor obtiene el usuario que realiza la solicitud desde la cabecera X-User
+func requestActor(c *gin.Context) string {
+	if actor := strings.TrimSpace(c.GetHeader("X-User")); actor != "" {
+		return actor
+	}
+	return "anonimo"
+}
+
+// requestAuditActor obtiene quién hace un cambio sobre el catálogo (cabecera X-User) y su justificación
+// (cabecera X-Change-Reason), para registrarlos en la auditoría
+func requestAuditActor(c *gin.Context) functions.AuditActor {
+	return functions.AuditActor{
+		Actor:  requestActor(c),
+		Reason: strings.TrimSpace(c.GetHeader("X-Change-Reason")),
+	}
+}
+
+// saveComparisonRun guarda la comparación realizada y retorna su ID (0 si no se pudo guardar)
+func saveComparisonRun(c *gin.Context, endpoint string, studyPlan *models.StudyPlan, academicHistory models.AcademicHistoryInput, opts functions.CompareOptions, result *models.ComparisonResult) uint {
+	run, err := functions.SaveComparisonRun(config.DB, endpoint, studyPlan, academicHistory, opts, result, requestActor(c))
+	if err != nil {
+		log.Printf("Error guardando la comparación: %v", err)
+		return 0
+	}
+	return run.ID
+}
+
 // calculateCompletionPercentage calcula el porcentaje de completitud basado en créditos
 func calculateCompletionPercentage(summary models.CreditsSummary) float64 {
 	if summary.Total.Required == 0 {
@@ -321,6 +601,8 @@ func calculateCompletionPercentage(summary models.CreditsSummary) float64 {
 type APICompareRequest struct {
 	AcademicHistoryText string `json:"academic_history_text" binding:"required"`
 	TargetCareerCode    string `json:"target_career_code" binding:"required"`
+	AdmissionPeriod     string `json:"admission_period"` // Opcional: periodo de admisión del estudiante (por ejemplo 2019-1)
+	Sede                string `json:"sede"`             // Opcional: sede donde se cursaron las materias (por ejemplo MED)
 }
 
 // ParsedSubject representa una materia extraída del texto de historia académica
@@ -353,6 +635,24 @@ func parseAcademicHistoryText(text string) ([]ParsedSubject, error) {
 	return subjects, nil
 }
 
+// parsedSubjectsToInputs convierte las materias extraídas del texto al formato de entrada de la API
+func parsedSubjectsToInputs(parsedSubjects []ParsedSubject) []models.SubjectInput {
+	var subjects []models.SubjectInput
+	for _, ps := range parsedSubjects {
+		subject := models.SubjectInput{
+			Code:     ps.Code,
+			Name:     ps.Name,
+			Credits:  ps.Credits,
+			Type:     models.TipologiaAsignatura(ps.Type),
+			Grade:    ps.Grade,
+			Status:   ps.Status,
+			Semester: ps.Semester,
+		}
+		subjects = append(subjects, subject)
+	}
+	return subjects
+}
+
 // Versión ultra tolerante del parser de materias
 func parseSubjectLineUltraTolerant(line string) (ParsedSubject, error) {
 	codeStart := strings.Index(line, "(")
@@ -455,7 +755,7 @@ func preprocessAcademicHistoryText(raw string) string {
 
 // compareAcademicHistoryFromText compara historia académica en texto con el pensum
 func compareAcademicHistoryFromText(c *gin.Context) {
-	var academicHistoryText, targetCareerCode string
+	var academicHistoryText, targetCareerCode, admissionPeriod, sede string
 
 	contentType := c.GetHeader("Content-Type")
 	if strings.HasPrefix(contentType, "application/json") {
@@ -466,10 +766,14 @@ func compareAcademicHistoryFromText(c *gin.Context) {
 		}
 		academicHistoryText = req.AcademicHistoryText
 		targetCareerCode = req.TargetCareerCode
+		admissionPeriod = req.AdmissionPeriod
+		sede = req.Sede
 	} else if strings.HasPrefix(contentType, "multipart/form-data") || strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
 		// Leer desde form-data o x-www-form-urlencoded
 		academicHistoryText = c.PostForm("academic_history_text")
 		targetCareerCode = c.PostForm("target_career_code")
+		admissionPeriod = c.PostForm("admission_period")
+		sede = c.PostForm("sede")
 		if academicHistoryText == "" || targetCareerCode == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Faltan campos en el formulario: academic_history_text y target_career_code son requeridos"})
 			return
@@ -479,6 +783,11 @@ func compareAcademicHistoryFromText(c *gin.Context) {
 		return
 	}
 
+	opts, ok := compareOptions(c)
+	if !ok {
+		return
+	}
+
 	// Limpieza y normalización del texto
 	cleanedText := preprocessAcademicHistoryText(academicHistoryText)
 
@@ -490,36 +799,25 @@ func compareAcademicHistoryFromText(c *gin.Context) {
 	}
 
 	// Convertir a formato de entrada de la API
-	var subjects []models.SubjectInput
-	for _, ps := range parsedSubjects {
-		subject := models.SubjectInput{
-			Code:     ps.Code,
-			Name:     ps.Name,
-			Credits:  ps.Credits,
-			Type:     models.TipologiaAsignatura(ps.Type),
-			Grade:    ps.Grade,
-			Status:   ps.Status,
-			Semester: ps.Semester,
-		}
-		subjects = append(subjects, subject)
-	}
-
 	academicHistory := models.AcademicHistoryInput{
-		CareerCode: targetCareerCode,
-		Subjects:   subjects,
+		CareerCode:      targetCareerCode,
+		Subjects:        parsedSubjectsToInputs(parsedSubjects),
+		AdmissionPeriod: admissionPeriod,
+		Sede:            sede,
 	}
 
 	// Realizar la comparación
-	result, err := functions.CompareAcademicHistoryByCareerCode(config.DB, academicHistory)
+	result, studyPlan, err := functions.CompareAcademicHistoryByCareerCode(config.DB, academicHistory, opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Obtener información del plan de estudio usado
-	studyPlan, _ := functions.GetStudyPlanByCareerCode(config.DB, targetCareerCode)
+	// Guardar la comparación para poder consultarla después
+	runID := saveComparisonRun(c, "api-compare", studyPlan, academicHistory, opts, result)
 
 	c.JSON(http.StatusOK, gin.H{
+		"comparison_run_id": runID,
 		"parsed_subjects": parsedSubjects,
 		"comparison_result": result,
 		"study_plan_info": gin.H{
@@ -536,3 +834,144 @@ func compareAcademicHistoryFromText(c *gin.Context) {
 		},
 	})
 }
+
+// DoubleDegreeRequest estructura para la solicitud de análisis de doble titulación
+type DoubleDegreeRequest struct {
+	AcademicHistory            models.AcademicHistoryInput `json:"academic_history" binding:"required"`
+	TargetCareerCode           string                      `json:"target_career_code" binding:"required"`
+	MaxSharedCreditsPercentage int                         `json:"max_shared_credits_percentage"`
+}
+
+// compareDoubleDegree analiza los créditos compartidos entre el plan actual del estudiante y un segundo plan
+func compareDoubleDegree(c *gin.Context) {
+	var req DoubleDegreeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+	if req.MaxSharedCreditsPercentage == 0 {
+		req.MaxSharedCreditsPercentage = functions.DefaultMaxSharedCreditsPercentage
+	}
+
+	// El plan de origen es el plan de la carrera en la que el estudiante sigue matriculado,
+	// según su periodo de admisión
+	homePlan, err := functions.ResolveStudyPlan(config.DB, req.AcademicHistory.CareerCode, req.AcademicHistory.AdmissionPeriod, req.AcademicHistory.Sede)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	targetPlan, err := functions.GetStudyPlanByCareerCode(config.DB, req.TargetCareerCode)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := functions.CompareDoubleDegree(config.DB, req.AcademicHistory, homePlan.ID, targetPlan.ID, req.MaxSharedCreditsPercentage)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"double_degree_result": result,
+		"home_study_plan_info": gin.H{
+			"id":      homePlan.ID,
+			"version": homePlan.Version,
+			"career":  homePlan.Career.Name,
+		},
+		"target_study_plan_info": gin.H{
+			"id":      targetPlan.ID,
+			"version": targetPlan.Version,
+			"career":  targetPlan.Career.Name,
+		},
+		"summary": gin.H{
+			"shared_subjects":     len(result.SharedSubjects),
+			"excluded_subjects":   len(result.ExcludedSubjects),
+			"shared_credits":      result.SharedCredits,
+			"max_shared_credits":  result.MaxSharedCredits,
+			"home_credits_owed":   result.HomeCreditsOwed,
+			"target_credits_owed": result.TargetCreditsOwed,
+		},
+	})
+}
+
+// TransitionRequest estructura para la solicitud de migración de plan de un estudiante readmitido
+type TransitionRequest struct {
+	SourceStudyPlanID uint                        `json:"source_study_plan_id" binding:"required"`
+	TargetStudyPlanID uint                        `json:"target_study_plan_id"` // Opcional: por defecto el plan activo de la carrera
+	AcademicHistory   models.AcademicHistoryInput `json:"academic_history" binding:"required"`
+}
+
+// compareTransition genera el reporte de régimen de transición entre dos versiones de un plan
+func compareTransition(c *gin.Context) {
+	var req TransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+
+	// Si no se indica el plan destino se usa el plan vigente de la carrera
+	if req.TargetStudyPlanID == 0 {
+		studyPlan, err := functions.GetStudyPlanByCareerCode(config.DB, req.AcademicHistory.CareerCode)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		req.TargetStudyPlanID = studyPlan.ID
+	}
+
+	report, err := functions.BuildTransitionReport(config.DB, req.AcademicHistory, req.SourceStudyPlanID, req.TargetStudyPlanID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transition_report": report,
+		"summary": gin.H{
+			"source_plan_version":   report.SourcePlanVersion,
+			"target_plan_version":   report.TargetPlanVersion,
+			"carried_over_subjects": len(report.CarriedOver),
+			"lost_subjects":         len(report.Lost),
+			"carried_credits":       report.CarriedCredits,
+			"lost_credits":          report.LostCredits,
+			"completion_percentage": calculateCompletionPercentage(report.Result.CreditsSummary),
+		},
+	})
+}
+
+// getCatalogStatus muestra cuándo se construyó la copia en memoria del catálogo y qué contiene. Con
+// ?as_of=AAAA-MM-DD resume el catálogo como estaba en esa fecha.
+func getCatalogStatus(c *gin.Context) {
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+	snapshot, err := functions.CatalogAt(config.DB, asOf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"catalog": snapshot.Stats(),
+	})
+}
+
+// reloadCatalog reconstruye la copia en memoria del catálogo, por ejemplo después de cambios hechos
+// directamente en la base de datos
+func reloadCatalog(c *gin.Context) {
+	if err := functions.LoadCatalog(config.DB); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	snapshot, err := functions.CurrentCatalog(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"catalog": snapshot.Stats(),
+	})
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"regexp"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // TipologiaAsignatura representa los tipos permitidos de asignaturas
@@ -31,15 +34,71 @@ func ValidarTipologia(tipo string) bool {
 	}
 }
 
+// codigoPattern define el formato de los códigos de carreras y materias: letras mayúsculas y dígitos,
+// opcionalmente separados por guiones (por ejemplo ISIS, ING-SISTEMAS o 1000004-M)
+var codigoPattern = regexp.MustCompile(`^[A-Z0-9]+(-[A-Z0-9]+)*$`)
+
+// ValidarCodigoCarrera verifica si un código de carrera tiene un formato válido
+func ValidarCodigoCarrera(code string) bool {
+	return len(code) >= 2 && len(code) <= 20 && codigoPattern.MatchString(code)
+}
+
+// ValidarCodigoMateria verifica si un código de materia tiene un formato válido
+func ValidarCodigoMateria(code string) bool {
+	return len(code) <= 20 && codigoPattern.MatchString(code)
+}
+
+// sufijoSedePattern define el formato del sufijo de los códigos de materia de una sede (por ejemplo M)
+var sufijoSedePattern = regexp.MustCompile(`^[A-Z]{1,5}$`)
+
+// ValidarSufijoSede verifica si el sufijo de códigos de una sede tiene un formato válido
+func ValidarSufijoSede(suffix string) bool {
+	return sufijoSedePattern.MatchString(suffix)
+}
+
+// periodoPattern define el formato de los periodos académicos: año y semestre (por ejemplo 2023-1)
+var periodoPattern = regexp.MustCompile(`^[0-9]{4}-[12]$`)
+
+// ValidarPeriodo verifica si un periodo académico tiene un formato válido
+func ValidarPeriodo(periodo string) bool {
+	return periodoPattern.MatchString(periodo)
+}
+
+// Sede representa una sede de la universidad (Bogotá, Medellín...). Los códigos de las materias
+// propias de una sede pueden llevar un sufijo, por ejemplo 1000004-M en Medellín.
+type Sede struct {
+	ID         uint   `gorm:"primaryKey"`
+	Code       string `gorm:"size:20;unique;not null"` // Ejemplo: "MED"
+	Name       string `gorm:"size:100;not null"`
+	CodeSuffix string `gorm:"size:5"` // Sufijo de los códigos de materia de la sede ("M"); vacío si no usa sufijo
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Faculty representa una facultad de una sede, a la que pertenecen las carreras
+type Faculty struct {
+	ID        uint   `gorm:"primaryKey"`
+	Code      string `gorm:"size:20;unique;not null"` // Ejemplo: "FMINAS"
+	Name      string `gorm:"size:100;not null"`
+	SedeID    uint   `gorm:"not null;index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// Relaciones
+	Sede Sede `gorm:"foreignKey:SedeID"`
+}
+
 // Career representa una carrera en la universidad
 type Career struct {
 	ID          uint      `gorm:"primaryKey"`
 	Name        string    `gorm:"size:100;not null"`
 	Code        string    `gorm:"size:20;unique;not null"`
 	Description string    `gorm:"type:text"`
+	FacultyID   *uint     `gorm:"index"` // Facultad de la carrera; su sede es la de la facultad
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"` // Borrado lógico: las comparaciones guardadas siguen refiriéndose a la carrera
 	StudyPlans  []StudyPlan `gorm:"foreignKey:CareerID"`
+	Faculty     *Faculty    `gorm:"foreignKey:FacultyID"`
 }
 
 // StudyPlan representa un plan de estudio de una carrera
@@ -47,7 +106,10 @@ type StudyPlan struct {
 	ID          uint      `gorm:"primaryKey"`
 	CareerID    uint      `gorm:"not null"`
 	Version     string    `gorm:"size:20;not null"` // Ejemplo: "2023-1"
-	IsActive    bool      `gorm:"default:true"`
+	IsActive    bool      `gorm:"default:false"` // Los planes se crean inactivos (borradores) y se activan explícitamente
+	// Sede en la que rige el plan cuando la carrera tiene un plan distinto por sede; nulo si rige en todas.
+	// Cada carrera tiene a lo sumo un plan activo por sede.
+	SedeID      *uint     `gorm:"index"`
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	TotalCredits int `gorm:"not null"`
@@ -56,6 +118,12 @@ type StudyPlan struct {
 	ElectiveCreditsPercentage int `gorm:"not null"`
 	Subjects    []Subject `gorm:"many2many:study_plan_subjects;"`
 	Career      Career    `gorm:"foreignKey:CareerID"`
+	Groups      []PlanGroup `gorm:"foreignKey:StudyPlanID"`
+	Sede        *Sede       `gorm:"foreignKey:SedeID"`
+	// Periodos académicos de vigencia (formato "2023-1"); EffectiveTo vacío indica que sigue vigente.
+	// Con ellos se resuelve el plan que aplica a un estudiante según su periodo de admisión.
+	EffectiveFrom string `gorm:"size:10"`
+	EffectiveTo   string `gorm:"size:10"`
 	// Nuevos campos para créditos por tipología
 	FundObligatoriaCredits int `gorm:"not null"`
 	FundOptativaCredits    int `gorm:"not null"`
@@ -72,14 +140,83 @@ type Subject struct {
 	Credits     int               `gorm:"not null"`
 	Type        TipologiaAsignatura `gorm:"size:50;not null"` // Tipo de materia (fundamental, disciplinar, etc)
 	Description string            `gorm:"type:text"`
+	SedeID      *uint             `gorm:"index"` // Sede que ofrece esta variante del código; si es nulo se deduce del sufijo
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt    `gorm:"index"` // Borrado lógico: las equivalencias y la auditoría siguen refiriéndose a la materia
 	// Relaciones
-	Prerequisites []Subject `gorm:"many2many:subject_prerequisites;"`
+	// Los prerrequisitos se definen por plan (PlanPrerequisite); los generales de subject_prerequisites se
+	// copiaron a los planes una sola vez y ya no se usan
+	Sede          *Sede     `gorm:"foreignKey:SedeID"`
 	Equivalences  []Equivalence `gorm:"foreignKey:SourceSubjectID"`
 	StudyPlans    []StudyPlan   `gorm:"many2many:study_plan_subjects;"`
 }
 
+// SchemaMigration registra una migración de datos que ya se aplicó, para que no vuelva a ejecutarse
+type SchemaMigration struct {
+	Name      string `gorm:"primaryKey;size:100"`
+	AppliedAt time.Time
+}
+
+// SubjectAlias registra otro código con el que una materia aparece en las historias académicas (códigos
+// antiguos, de otra sede o mal digitados). Alias se guarda normalizado y no puede repetirse.
+type SubjectAlias struct {
+	ID        uint   `gorm:"primaryKey"`
+	Alias     string `gorm:"size:30;uniqueIndex;not null"`
+	SubjectID uint   `gorm:"not null;index"`
+	Notes     string `gorm:"type:text"`
+	CreatedBy string `gorm:"size:100"`
+	CreatedAt time.Time
+	// Relaciones
+	Subject Subject `gorm:"foreignKey:SubjectID"`
+}
+
+// StudyPlanSubject representa la asociación entre un plan de estudio y una materia.
+// Cada plan define con qué tipología y cuántos créditos cuenta la materia, porque una misma
+// asignatura puede ser fundamentación optativa en un plan y libre elección en otro.
+type StudyPlanSubject struct {
+	StudyPlanID uint              `gorm:"primaryKey"`
+	SubjectID   uint              `gorm:"primaryKey"`
+	Type        TipologiaAsignatura `gorm:"size:50"` // Si está vacío se usa la tipología de la materia
+	Credits     int               // Si es cero se usan los créditos de la materia
+}
+
+// PlanPrerequisite representa un prerrequisito dentro de un plan de estudio: para cursar SubjectID
+// en el plan se debe haber aprobado PrerequisiteID. Los prerrequisitos de un plan forman un grafo sin ciclos.
+type PlanPrerequisite struct {
+	StudyPlanID    uint `gorm:"primaryKey"`
+	SubjectID      uint `gorm:"primaryKey"`
+	PrerequisiteID uint `gorm:"primaryKey;index"`
+	CreatedAt      time.Time
+	// Relaciones
+	Subject      Subject `gorm:"foreignKey:SubjectID"`
+	Prerequisite Subject `gorm:"foreignKey:PrerequisiteID"`
+}
+
+// PlanGroup representa una agrupación de asignaturas de un plan de estudio con su propio mínimo
+// de créditos (por ejemplo "Matemáticas: mínimo 8 créditos" dentro de la fundamentación optativa)
+type PlanGroup struct {
+	ID          uint              `gorm:"primaryKey"`
+	StudyPlanID uint              `gorm:"not null;index"`
+	Name        string            `gorm:"size:100;not null"`
+	Type        TipologiaAsignatura `gorm:"size:50;not null"` // Tipología a la que pertenece la agrupación
+	MinCredits  int               `gorm:"not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	// Relaciones
+	Subjects  []Subject `gorm:"many2many:plan_group_subjects;"`
+	StudyPlan StudyPlan `gorm:"foreignKey:StudyPlanID"`
+}
+
+// Estados de una equivalencia. Solo las equivalencias aprobadas se usan en las comparaciones.
+const (
+	EquivalenceStatusProposed = "PROPUESTA"
+	EquivalenceStatusInReview = "EN_REVISION"
+	EquivalenceStatusApproved = "APROBADA"
+	EquivalenceStatusRejected = "RECHAZADA"
+	EquivalenceStatusRetired  = "RETIRADA"
+)
+
 // Equivalence representa una equivalencia entre materias de diferentes planes
 type Equivalence struct {
 	ID              uint      `gorm:"primaryKey"`
@@ -88,19 +225,99 @@ type Equivalence struct {
 	Type            string    `gorm:"size:20;not null"` // Tipo de equivalencia (total, parcial, etc)
 	Notes           string    `gorm:"type:text"`
 	StudyPlanID     uint      `gorm:"not null"` // Plan de estudio al que aplica la equivalencia
+	// Flujo de aprobación; las equivalencias existentes antes del flujo quedan aprobadas
+	Status           string     `gorm:"size:20;not null;index;default:APROBADA"`
+	ResolutionNumber string     `gorm:"size:50"` // Resolución del comité que aprobó la equivalencia
+	ResolutionDate   *time.Time `gorm:"type:date"`
+	ProposedBy       string     `gorm:"size:100"`
+	ReviewedBy       string     `gorm:"size:100"` // Quien tomó la última decisión sobre la equivalencia
+	ReviewNotes      string     `gorm:"type:text"`
+	ReviewedAt       *time.Time
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"` // Borrado lógico
 	// Relaciones
 	SourceSubject Subject   `gorm:"foreignKey:SourceSubjectID"`
 	TargetSubject Subject   `gorm:"foreignKey:TargetSubjectID"`
 	StudyPlan     StudyPlan `gorm:"foreignKey:StudyPlanID"`
 }
 
+// ComparisonRun representa una comparación ejecutada y guardada, para que el resultado que vio
+// el asesor pueda consultarse, compararse o volver a ejecutarse más adelante
+type ComparisonRun struct {
+	ID               uint      `gorm:"primaryKey"`
+	Endpoint         string    `gorm:"size:50;not null"` // compare, compare-by-career, api-compare, rerun
+	CareerCode       string    `gorm:"size:20;index"`
+	SedeCode         string    `gorm:"size:20;index"` // Sede con la que se resolvieron los códigos de la historia
+	StudyPlanID      uint      `gorm:"not null;index"`
+	StudyPlanVersion string    `gorm:"size:20;not null"`
+	EngineVersion    string    `gorm:"size:20;not null"`
+	Explain          bool      `gorm:"default:false"`
+	InputSnapshot    string    `gorm:"type:jsonb;not null"` // Historia académica usada como entrada
+	Result           string    `gorm:"type:jsonb;not null"` // ComparisonResult obtenido
+	RerunOfID        *uint     // Ejecución original cuando se trata de una re-ejecución
+	AsOf             *time.Time // Instante del catálogo usado; nulo si se usó el catálogo vigente
+	CreatedBy        string    `gorm:"size:100"`
+	CreatedAt        time.Time `gorm:"index"`
+	// Relaciones
+	StudyPlan StudyPlan `gorm:"foreignKey:StudyPlanID"`
+}
+
+// AuditLog registra un cambio sobre el catálogo académico: quién lo hizo, con qué justificación y el estado
+// de la entidad antes y después. Se guarda en la misma transacción que el cambio.
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey"`
+	Actor      string    `gorm:"size:100;not null;index"`
+	Action     string    `gorm:"size:30;not null"` // CREATE, UPDATE, DELETE o una acción específica (APPROVE, ATTACH_SUBJECT...)
+	EntityType string    `gorm:"size:30;not null;index:idx_audit_logs_entity"` // career, study_plan, subject o equivalence
+	EntityID   uint      `gorm:"not null;index:idx_audit_logs_entity"`
+	EntityKey  string    `gorm:"size:100"`   // Clave legible: código de la carrera o materia, carrera y versión del plan...
+	Before     *string   `gorm:"type:jsonb"` // Estado antes del cambio; nulo al crear
+	After      *string   `gorm:"type:jsonb"` // Estado después del cambio; nulo al eliminar
+	Reason     string    `gorm:"type:text"`  // Justificación del cambio
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+// Estados de un trabajo asíncrono
+const (
+	JobStatusPending   = "PENDIENTE"
+	JobStatusRunning   = "EN_EJECUCION"
+	JobStatusCompleted = "COMPLETADO"
+	JobStatusFailed    = "FALLIDO"
+	JobStatusCancelled = "CANCELADO"
+)
+
+// Job representa un trabajo asíncrono de larga duración (comparaciones en lote, reportes, importaciones).
+// Los trabajos se guardan en la base de datos para poder retomarlos si el servidor se reinicia.
+type Job struct {
+	ID              uint      `gorm:"primaryKey"`
+	Type            string    `gorm:"size:50;not null;index"`
+	Status          string    `gorm:"size:20;not null;index;default:PENDIENTE"`
+	Payload         string    `gorm:"type:jsonb;not null"`
+	Result          *string   `gorm:"type:jsonb"`
+	Error           string    `gorm:"type:text"`
+	Progress        int       `gorm:"not null;default:0"` // Porcentaje de avance (0-100)
+	Attempts        int       `gorm:"not null;default:0"`
+	MaxAttempts     int       `gorm:"not null;default:3"`
+	CancelRequested bool      `gorm:"default:false"`
+	AvailableAt     time.Time `gorm:"not null;index"` // No se ejecuta antes de esta fecha (reintentos)
+	CreatedBy       string    `gorm:"size:100"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	StartedAt       *time.Time
+	FinishedAt      *time.Time
+	HeartbeatAt     *time.Time `gorm:"index"` // Última señal del worker que lo ejecuta; vencida, el trabajo se retoma
+}
+
 // AcademicHistoryInput representa la entrada de historia académica para procesar
 // Este es un DTO (Data Transfer Object) y no se almacena en la base de datos
 type AcademicHistoryInput struct {
 	CareerCode    string   `json:"career_code" binding:"required"`
 	Subjects      []SubjectInput `json:"subjects" binding:"required"`
+	AdmissionPeriod string `json:"admission_period,omitempty"` // Opcional: resuelve el plan vigente en ese periodo
+	// Opcional: código de la sede donde se cursaron las materias. Elige la variante del código de cada
+	// materia (por ejemplo 1000004-M en Medellín); si se omite se usa la sede de la facultad de la carrera.
+	Sede string `json:"sede,omitempty"`
 }
 
 // SubjectInput representa una materia en la historia académica de entrada
@@ -122,6 +339,36 @@ type ComparisonResult struct {
 	TotalCredits       int             `json:"total_credits"`
 	MissingCredits     int             `json:"missing_credits"`
 	CreditsSummary     CreditsSummary  `json:"credits_summary"`
+	GroupsSummary      []GroupProgress `json:"groups_summary"`
+	Diagnostics        *ComparisonDiagnostics `json:"diagnostics,omitempty"`
+	// Materias pendientes del plan que se parecen, por nombre y créditos, a materias aprobadas cuyo código
+	// no está en el catálogo. Son solo sugerencias para el comité: no cambian el estado de ninguna materia.
+	PossibleEquivalences []PossibleEquivalence `json:"possible_equivalences,omitempty"`
+}
+
+// PossibleEquivalence es una equivalencia sugerida por similitud de nombre y créditos, nunca aprobada automáticamente
+type PossibleEquivalence struct {
+	SourceCode     string  `json:"source_code"`
+	SourceName     string  `json:"source_name"`
+	SourceCredits  int     `json:"source_credits"`
+	TargetCode     string  `json:"target_code"`
+	TargetName     string  `json:"target_name"`
+	TargetCredits  int     `json:"target_credits"`
+	NameSimilarity float64 `json:"name_similarity"` // Entre 0 y 1
+	Score          float64 `json:"score"`           // Similitud del nombre combinada con la cercanía en créditos
+}
+
+// ComparisonDiagnostics reporta cómo se interpretaron los códigos de la historia académica
+type ComparisonDiagnostics struct {
+	CodeResolutions []CodeResolution `json:"code_resolutions"` // Códigos de la historia que se tradujeron a una materia del catálogo
+	UnresolvedCodes []string         `json:"unresolved_codes"` // Códigos aprobados que no corresponden a ninguna materia del catálogo
+}
+
+// CodeResolution indica que un código de la historia académica se interpretó como el de otra materia
+type CodeResolution struct {
+	HistoryCode  string `json:"history_code"`
+	ResolvedCode string `json:"resolved_code"`
+	Method       string `json:"method"` // NORMALIZACION, ALIAS o SUFIJO
 }
 
 // SubjectResult representa una materia en el resultado de la comparación
@@ -132,6 +379,35 @@ type SubjectResult struct {
 	Type        TipologiaAsignatura `json:"type"`
 	Status      string            `json:"status"` // Equivalente, Falta, etc.
 	Equivalence *EquivalenceResult `json:"equivalence,omitempty"`
+	Trace       *SubjectTrace     `json:"trace,omitempty"` // Solo se incluye cuando se solicita explain=true
+}
+
+// SubjectTrace explica cómo llegó el motor de comparación al resultado de una materia del plan
+type SubjectTrace struct {
+	HistoryEntries []HistoryEntryTrace `json:"history_entries"`
+	Equivalence    EquivalenceTrace    `json:"equivalence"`
+	Bucket         string              `json:"bucket"` // Grupo de créditos en el que quedaron los créditos
+	BucketReason   string              `json:"bucket_reason"`
+}
+
+// HistoryEntryTrace representa una entrada de la historia académica considerada para una materia del plan
+type HistoryEntryTrace struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Semester string `json:"semester"`
+	Match    string `json:"match"` // DIRECTA o EQUIVALENCIA
+	Used     bool   `json:"used"`
+	Reason   string `json:"reason"`
+}
+
+// EquivalenceTrace describe qué regla de equivalencia se aplicó o por qué no se aplicó ninguna
+type EquivalenceTrace struct {
+	Matched        bool     `json:"matched"`
+	EquivalenceID  uint     `json:"equivalence_id,omitempty"`
+	Rule           string   `json:"rule,omitempty"`
+	CandidateCodes []string `json:"candidate_codes"`
+	Reason         string   `json:"reason"`
 }
 
 // EquivalenceResult representa una equivalencia en el resultado
@@ -155,4 +431,184 @@ type CreditsSummary struct {
 	DisOptativa       CreditTypeInfo `json:"dis_optativa"`
 	Libre             CreditTypeInfo `json:"libre"`
 	Total             CreditTypeInfo `json:"total"`
-}
\ No newline at end of file
+}
+// DoubleDegreeResult representa el análisis de créditos compartidos para una doble titulación
+// Este es un DTO y no se almacena en la base de datos
+type DoubleDegreeResult struct {
+	HomePlan          ComparisonResult      `json:"home_plan"`
+	TargetPlan        ComparisonResult      `json:"target_plan"`
+	SharedSubjects    []SharedSubjectResult `json:"shared_subjects"`
+	ExcludedSubjects  []SharedSubjectResult `json:"excluded_subjects"`
+	SharedCredits     int                   `json:"shared_credits"`
+	MaxSharedCredits  int                   `json:"max_shared_credits"`
+	HomeCreditsOwed   int                   `json:"home_credits_owed"`
+	TargetCreditsOwed int                   `json:"target_credits_owed"`
+}
+
+// SharedSubjectResult representa una materia aprobada en el primer plan y su reconocimiento en el segundo
+type SharedSubjectResult struct {
+	HistoryCode string              `json:"history_code"`
+	HistoryName string              `json:"history_name"`
+	TargetCode  string              `json:"target_code,omitempty"`
+	TargetName  string              `json:"target_name,omitempty"`
+	Credits     int                 `json:"credits"`
+	Type        TipologiaAsignatura `json:"type"` // Tipología con la que cuenta en el segundo plan
+	Status      string              `json:"status"` // RECONOCIDA, NO RECONOCIDA
+	Reason      string              `json:"reason"`
+}
+
+// TransitionReport representa el resultado de migrar la historia de un estudiante readmitido
+// de una versión antigua del plan de estudio a la versión vigente (régimen de transición)
+// Este es un DTO y no se almacena en la base de datos
+type TransitionReport struct {
+	SourcePlanVersion string           `json:"source_plan_version"`
+	TargetPlanVersion string           `json:"target_plan_version"`
+	CarriedOver       []TransitionItem `json:"carried_over"`
+	Lost              []TransitionItem `json:"lost"`
+	RulesApplied      map[string]int   `json:"rules_applied"`
+	CarriedCredits    int              `json:"carried_credits"`
+	LostCredits       int              `json:"lost_credits"`
+	Result            ComparisonResult `json:"result"` // Situación del estudiante en el plan vigente
+}
+
+// TransitionItem representa el tratamiento de una materia aprobada al cambiar de plan
+type TransitionItem struct {
+	HistoryCode string              `json:"history_code"`
+	HistoryName string              `json:"history_name"`
+	Credits     int                 `json:"credits"`
+	SourceType  TipologiaAsignatura `json:"source_type"`
+	TargetCode  string              `json:"target_code,omitempty"`
+	TargetName  string              `json:"target_name,omitempty"`
+	TargetType  TipologiaAsignatura `json:"target_type,omitempty"`
+	Rule        string              `json:"rule"` // MISMA_ASIGNATURA, EQUIVALENCIA, CAMBIO_TIPOLOGIA, LIBRE_ELECCION, SIN_HOMOLOGACION
+	Description string              `json:"description"`
+}
+
+// GroupProgress representa el avance del estudiante en una agrupación del plan
+type GroupProgress struct {
+	Name      string              `json:"name"`
+	Type      TipologiaAsignatura `json:"type"`
+	Required  int                 `json:"required"`
+	Completed int                 `json:"completed"`
+	Missing   int                 `json:"missing"`
+}
+
+// ComparisonDiff representa las diferencias entre dos resultados de comparación
+// Este es un DTO y no se almacena en la base de datos
+type ComparisonDiff struct {
+	StatusChanges      []SubjectStatusChange `json:"status_changes"`
+	AddedSubjects      []SubjectResult       `json:"added_subjects"`   // Materias que solo están en el segundo resultado
+	RemovedSubjects    []SubjectResult       `json:"removed_subjects"` // Materias que solo están en el primer resultado
+	CreditDeltas       []CreditDelta         `json:"credit_deltas"`
+	GroupDeltas        []CreditDelta         `json:"group_deltas"`
+	EquivalenceChanges []EquivalenceChange   `json:"equivalence_changes"`
+}
+
+// SubjectStatusChange representa una materia del plan cuyo estado cambió entre dos resultados
+type SubjectStatusChange struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+}
+
+// CreditDelta representa la variación de créditos de una tipología o agrupación entre dos resultados
+type CreditDelta struct {
+	Name           string `json:"name"`
+	FromRequired   int    `json:"from_required"`
+	ToRequired     int    `json:"to_required"`
+	FromCompleted  int    `json:"from_completed"`
+	ToCompleted    int    `json:"to_completed"`
+	CompletedDelta int    `json:"completed_delta"`
+	FromMissing    int    `json:"from_missing"`
+	ToMissing      int    `json:"to_missing"`
+	MissingDelta   int    `json:"missing_delta"`
+}
+
+// EquivalenceChange representa una materia cuya equivalencia aplicada cambió entre dos resultados
+type EquivalenceChange struct {
+	Code string             `json:"code"`
+	Name string             `json:"name"`
+	From *EquivalenceResult `json:"from"`
+	To   *EquivalenceResult `json:"to"`
+}
+
+// BatchItem representa una historia académica dentro de una comparación en lote
+// Este es un DTO y no se almacena en la base de datos
+type BatchItem struct {
+	ID              string               `json:"id"`
+	AcademicHistory AcademicHistoryInput `json:"academic_history"`
+	Error           string               `json:"error,omitempty"` // Error previo a la comparación (por ejemplo, al leer el archivo)
+}
+
+// BatchItemResult representa el resultado de comparar una historia dentro de un lote
+type BatchItemResult struct {
+	ID     string            `json:"id"`
+	Result *ComparisonResult `json:"comparison_result,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// PrerequisiteGraph es el grafo de prerrequisitos de un plan de estudio ordenado por niveles:
+// el nivel 0 son las materias sin prerrequisitos y cada materia está un nivel por encima de su
+// prerrequisito más alto
+type PrerequisiteGraph struct {
+	StudyPlanID uint               `json:"study_plan_id"`
+	Nodes       []PrerequisiteNode `json:"nodes"`
+	Levels      [][]string         `json:"levels"`
+}
+
+// PrerequisiteNode representa una materia del plan en el grafo de prerrequisitos
+type PrerequisiteNode struct {
+	Code          string              `json:"code"`
+	Name          string              `json:"name"`
+	Credits       int                 `json:"credits"`
+	Type          TipologiaAsignatura `json:"type"`
+	Level         int                 `json:"level"`
+	Prerequisites []string            `json:"prerequisites"` // Materias que se deben aprobar antes
+	Unlocks       []string            `json:"unlocks"`       // Materias que tienen a esta como prerrequisito
+}
+
+// StudyPlanDiff representa las diferencias entre dos versiones de un plan de estudio
+type StudyPlanDiff struct {
+	FromStudyPlanID      uint                `json:"from_study_plan_id"`
+	ToStudyPlanID        uint                `json:"to_study_plan_id"`
+	FromVersion          string              `json:"from_version"`
+	ToVersion            string              `json:"to_version"`
+	AddedSubjects        []PlanSubjectInfo   `json:"added_subjects"`   // Materias que solo están en la versión nueva
+	RemovedSubjects      []PlanSubjectInfo   `json:"removed_subjects"` // Materias que solo están en la versión anterior
+	ChangedSubjects      []PlanSubjectChange `json:"changed_subjects"` // Materias cuya tipología o créditos cambiaron
+	RequirementChanges   []RequirementChange `json:"requirement_changes"`
+	AddedPrerequisites   []PrerequisiteEdge  `json:"added_prerequisites"`
+	RemovedPrerequisites []PrerequisiteEdge  `json:"removed_prerequisites"`
+}
+
+// PlanSubjectInfo representa una materia con la tipología y los créditos que tiene en un plan
+type PlanSubjectInfo struct {
+	Code    string              `json:"code"`
+	Name    string              `json:"name"`
+	Type    TipologiaAsignatura `json:"type"`
+	Credits int                 `json:"credits"`
+}
+
+// PlanSubjectChange representa una materia presente en ambas versiones con tipología o créditos distintos
+type PlanSubjectChange struct {
+	Code        string              `json:"code"`
+	Name        string              `json:"name"`
+	FromType    TipologiaAsignatura `json:"from_type"`
+	ToType      TipologiaAsignatura `json:"to_type"`
+	FromCredits int                 `json:"from_credits"`
+	ToCredits   int                 `json:"to_credits"`
+}
+
+// RequirementChange representa un cambio en los créditos exigidos por tipología
+type RequirementChange struct {
+	Name string `json:"name"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+// PrerequisiteEdge representa un prerrequisito: SubjectCode requiere PrerequisiteCode
+type PrerequisiteEdge struct {
+	SubjectCode      string `json:"subject_code"`
+	PrerequisiteCode string `json:"prerequisite_code"`
+}
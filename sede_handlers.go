@@ -0,0 +1,217 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/config"
+	"olimpo-vicedecanatura/functions"
+	"olimpo-vicedecanatura/models"
+)
+
+// SedeRequest estructura para la creación o actualización de una sede
+type SedeRequest struct {
+	Code       string `json:"code" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+	CodeSuffix string `json:"code_suffix"` // Sufijo de los códigos de materia de la sede (por ejemplo M)
+}
+
+// getSedes lista las sedes
+func getSedes(c *gin.Context) {
+	var sedes []models.Sede
+	if err := config.DB.Order("code").Find(&sedes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo sedes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sedes": sedes,
+	})
+}
+
+// getSedeByCode obtiene una sede por su código, con sus facultades
+func getSedeByCode(c *gin.Context) {
+	sede, ok := findSede(c)
+	if !ok {
+		return
+	}
+
+	var faculties []models.Faculty
+	if err := config.DB.Where("sede_id = ?", sede.ID).Order("code").Find(&faculties).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo las facultades de la sede"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sede":      sede,
+		"faculties": faculties,
+	})
+}
+
+// createSede registra una nueva sede
+func createSede(c *gin.Context) {
+	var req SedeRequest
+	if !bindSedeRequest(c, &req) {
+		return
+	}
+	if sedeCodeTaken(c, req.Code, 0) {
+		return
+	}
+
+	sede := models.Sede{
+		Code:       req.Code,
+		Name:       req.Name,
+		CodeSuffix: req.CodeSuffix,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sede).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), functions.AuditActionCreate, functions.AuditEntitySede, sede.ID, sede.Code, nil, sede)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando la sede"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"sede": sede,
+	})
+}
+
+// updateSede actualiza el código, el nombre y el sufijo de códigos de una sede
+func updateSede(c *gin.Context) {
+	sede, ok := findSede(c)
+	if !ok {
+		return
+	}
+
+	var req SedeRequest
+	if !bindSedeRequest(c, &req) {
+		return
+	}
+	if sedeCodeTaken(c, req.Code, sede.ID) {
+		return
+	}
+
+	before := *sede
+	sede.Code = req.Code
+	sede.Name = req.Name
+	sede.CodeSuffix = req.CodeSuffix
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(sede).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), functions.AuditActionUpdate, functions.AuditEntitySede, sede.ID, sede.Code, before, sede)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando la sede"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusOK, gin.H{
+		"sede": sede,
+	})
+}
+
+// deleteSede elimina una sede que no tenga facultades, materias ni planes de estudio asociados
+func deleteSede(c *gin.Context) {
+	sede, ok := findSede(c)
+	if !ok {
+		return
+	}
+
+	var facultyCount, subjectCount, planCount int64
+	if err := config.DB.Model(&models.Faculty{}).Where("sede_id = ?", sede.ID).Count(&facultyCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando las facultades de la sede"})
+		return
+	}
+	// Las materias con borrado lógico siguen apuntando a la sede y la llave foránea impide eliminarla
+	if err := config.DB.Unscoped().Model(&models.Subject{}).Where("sede_id = ?", sede.ID).Count(&subjectCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando las materias de la sede"})
+		return
+	}
+	if err := config.DB.Model(&models.StudyPlan{}).Where("sede_id = ?", sede.ID).Count(&planCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando los planes de estudio de la sede"})
+		return
+	}
+	if facultyCount > 0 || subjectCount > 0 || planCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "No se puede eliminar la sede porque tiene facultades, materias o planes de estudio asociados"})
+		return
+	}
+
+	before := *sede
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(sede).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), functions.AuditActionDelete, functions.AuditEntitySede, sede.ID, sede.Code, before, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando la sede"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sede eliminada",
+	})
+}
+
+// findSede obtiene la sede indicada en la ruta o responde con el error correspondiente
+func findSede(c *gin.Context) (*models.Sede, bool) {
+	return findSedeByCode(c, c.Param("code"))
+}
+
+// findSedeByCode obtiene una sede por su código o responde que no existe
+func findSedeByCode(c *gin.Context, code string) (*models.Sede, bool) {
+	var sede models.Sede
+	if err := config.DB.Where("code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&sede).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sede no encontrada: " + code})
+		return nil, false
+	}
+	return &sede, true
+}
+
+// bindSedeRequest lee y normaliza los datos de una sede, validando el formato del código y del sufijo
+func bindSedeRequest(c *gin.Context, req *SedeRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return false
+	}
+
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	req.Name = strings.TrimSpace(req.Name)
+	req.CodeSuffix = strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(req.CodeSuffix), "-"))
+	if !models.ValidarCodigoCarrera(req.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código de sede inválido: debe tener entre 2 y 20 letras mayúsculas o dígitos, separados opcionalmente por guiones"})
+		return false
+	}
+	if req.Name == "" || len(req.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El nombre de la sede debe tener entre 1 y 100 caracteres"})
+		return false
+	}
+	if req.CodeSuffix != "" && !models.ValidarSufijoSede(req.CodeSuffix) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sufijo de códigos inválido: debe tener entre 1 y 5 letras"})
+		return false
+	}
+	return true
+}
+
+// sedeCodeTaken verifica si el código ya lo usa otra sede y, de ser así, responde con un conflicto
+func sedeCodeTaken(c *gin.Context, code string, exceptID uint) bool {
+	var count int64
+	if err := config.DB.Model(&models.Sede{}).Where("code = ? AND id <> ?", code, exceptID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando el código de la sede"})
+		return true
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ya existe una sede con el código " + code})
+		return true
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+// Package seeds contiene los archivos de catálogo con los datos iniciales de las carreras. Se
+// importan con el subcomando seed, que usa el mismo importador que POST /api/catalog/import.
+package seeds
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	"olimpo-vicedecanatura/catalog"
+)
+
+//go:embed *.yaml
+var files embed.FS
+
+// Seed es un archivo de catálogo incluido en el binario
+type Seed struct {
+	Name string // Nombre del archivo
+	File *catalog.File
+}
+
+// Load lee y valida todos los archivos de catálogo incluidos, en orden alfabético
+func Load() ([]Seed, error) {
+	names, err := fs.Glob(files, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var seeds []Seed
+	for _, name := range names {
+		data, err := files.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		file, err := catalog.Parse(data, catalog.FormatFromName(name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		seeds = append(seeds, Seed{Name: path.Base(name), File: file})
+	}
+	return seeds, nil
+}
@@ -0,0 +1,628 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/config"
+	"olimpo-vicedecanatura/functions"
+	"olimpo-vicedecanatura/models"
+)
+
+// PlanGroupRequest estructura para la creación o actualización de una agrupación de un plan de estudio
+type PlanGroupRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Type         string   `json:"type" binding:"required"`
+	MinCredits   int      `json:"min_credits" binding:"required"`
+	SubjectCodes []string `json:"subject_codes" binding:"required"`
+}
+
+// getStudyPlanGroups obtiene las agrupaciones de un plan de estudio con sus materias
+func getStudyPlanGroups(c *gin.Context) {
+	studyPlanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de plan de estudio inválido"})
+		return
+	}
+
+	var groups []models.PlanGroup
+	if err := config.DB.Preload("Subjects").
+		Where("study_plan_id = ?", uint(studyPlanID)).
+		Find(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo agrupaciones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups": groups,
+	})
+}
+
+// createStudyPlanGroup crea una agrupación dentro de un plan de estudio
+func createStudyPlanGroup(c *gin.Context) {
+	studyPlanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de plan de estudio inválido"})
+		return
+	}
+
+	var req PlanGroupRequest
+	if !bindPlanGroupRequest(c, &req) {
+		return
+	}
+
+	var studyPlan models.StudyPlan
+	if err := config.DB.Preload("Subjects").First(&studyPlan, uint(studyPlanID)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plan de estudio no encontrado"})
+		return
+	}
+	subjects, ok := planGroupSubjects(c, studyPlan, req.SubjectCodes)
+	if !ok {
+		return
+	}
+
+	group := models.PlanGroup{
+		StudyPlanID: studyPlan.ID,
+		Name:        req.Name,
+		Type:        models.TipologiaAsignatura(req.Type),
+		MinCredits:  req.MinCredits,
+		Subjects:    subjects,
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		return functions.RecordStudyPlanChange(tx, requestAuditActor(c), functions.AuditActionAddGroup, studyPlan.ID, func() error {
+			return tx.Create(&group).Error
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando la agrupación"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"group": group,
+	})
+}
+
+// updateStudyPlanGroup reemplaza el nombre, la tipología, el mínimo de créditos y las materias de una agrupación
+func updateStudyPlanGroup(c *gin.Context) {
+	studyPlan, group, ok := findStudyPlanGroup(c)
+	if !ok {
+		return
+	}
+
+	var req PlanGroupRequest
+	if !bindPlanGroupRequest(c, &req) {
+		return
+	}
+	subjects, ok := planGroupSubjects(c, *studyPlan, req.SubjectCodes)
+	if !ok {
+		return
+	}
+
+	group.Name = req.Name
+	group.Type = models.TipologiaAsignatura(req.Type)
+	group.MinCredits = req.MinCredits
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return functions.RecordStudyPlanChange(tx, requestAuditActor(c), functions.AuditActionUpdateGroup, studyPlan.ID, func() error {
+			if err := tx.Omit("Subjects").Save(group).Error; err != nil {
+				return err
+			}
+			return tx.Model(group).Omit("Subjects.*").Association("Subjects").Replace(subjects)
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando la agrupación"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	group.Subjects = subjects
+	c.JSON(http.StatusOK, gin.H{
+		"group": group,
+	})
+}
+
+// deleteStudyPlanGroup elimina una agrupación del plan; sus materias siguen en el plan
+func deleteStudyPlanGroup(c *gin.Context) {
+	studyPlan, group, ok := findStudyPlanGroup(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return functions.RecordStudyPlanChange(tx, requestAuditActor(c), functions.AuditActionRemoveGroup, studyPlan.ID, func() error {
+			return tx.Select("Subjects").Delete(group).Error
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando la agrupación"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Agrupación eliminada",
+	})
+}
+
+// bindPlanGroupRequest lee los datos de una agrupación, validando la tipología y el mínimo de créditos
+func bindPlanGroupRequest(c *gin.Context, req *PlanGroupRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El nombre de la agrupación no puede estar vacío"})
+		return false
+	}
+	if !models.ValidarTipologia(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipología inválida: " + req.Type})
+		return false
+	}
+	if req.MinCredits <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El mínimo de créditos debe ser mayor que cero"})
+		return false
+	}
+	return true
+}
+
+// planGroupSubjects obtiene las materias de una agrupación, que deben pertenecer al plan
+func planGroupSubjects(c *gin.Context, studyPlan models.StudyPlan, codes []string) ([]models.Subject, bool) {
+	planSubjects := make(map[string]models.Subject)
+	for _, subject := range studyPlan.Subjects {
+		planSubjects[subject.Code] = subject
+	}
+	var subjects []models.Subject
+	for _, code := range codes {
+		subject, exists := planSubjects[code]
+		if !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "La materia " + code + " no pertenece al plan de estudio"})
+			return nil, false
+		}
+		subjects = append(subjects, subject)
+	}
+	return subjects, true
+}
+
+// findStudyPlanGroup obtiene el plan (con sus materias) y la agrupación indicados en la ruta o responde
+// con el error correspondiente
+func findStudyPlanGroup(c *gin.Context) (*models.StudyPlan, *models.PlanGroup, bool) {
+	studyPlanID, ok := studyPlanIDParam(c)
+	if !ok {
+		return nil, nil, false
+	}
+	groupID, err := strconv.ParseUint(c.Param("group"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de agrupación inválido"})
+		return nil, nil, false
+	}
+
+	var studyPlan models.StudyPlan
+	if err := config.DB.Preload("Subjects").First(&studyPlan, studyPlanID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plan de estudio no encontrado"})
+		return nil, nil, false
+	}
+	var group models.PlanGroup
+	if err := config.DB.Where("id = ? AND study_plan_id = ?", uint(groupID), studyPlan.ID).First(&group).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agrupación no encontrada en el plan de estudio"})
+		return nil, nil, false
+	}
+	return &studyPlan, &group, true
+}
+
+// StudyPlanRequest estructura para la creación de un plan de estudio
+type StudyPlanRequest struct {
+	CareerCode    string `json:"career_code" binding:"required"`
+	Version       string `json:"version" binding:"required"`
+	EffectiveFrom string `json:"effective_from"`
+	EffectiveTo   string `json:"effective_to"`
+	SedeCode      string `json:"sede_code"` // Opcional: sede en la que rige el plan; si se omite rige en todas
+	functions.PlanRequirements
+}
+
+// UpdateStudyPlanRequest estructura para la edición de un plan de estudio
+type UpdateStudyPlanRequest struct {
+	Version       *string `json:"version"`
+	IsActive      *bool   `json:"is_active"`
+	EffectiveFrom *string `json:"effective_from"` // Periodo de inicio de vigencia, por ejemplo 2023-1
+	EffectiveTo   *string `json:"effective_to"`   // Periodo de fin de vigencia; vacío si sigue vigente
+	SedeCode      *string `json:"sede_code"`      // Sede en la que rige el plan; vacío para que rija en todas
+}
+
+// PlanSubjectRequest estructura para asociar una materia a un plan de estudio
+type PlanSubjectRequest struct {
+	SubjectCode string `json:"subject_code" binding:"required"`
+	Type        string `json:"type"`    // Tipología en el plan; si se omite se usa la de la materia
+	Credits     int    `json:"credits"` // Créditos en el plan; si se omite se usan los de la materia
+}
+
+// PlanPrerequisiteRequest estructura para agregar un prerrequisito en un plan de estudio
+type PlanPrerequisiteRequest struct {
+	SubjectCode      string `json:"subject_code" binding:"required"`
+	PrerequisiteCode string `json:"prerequisite_code" binding:"required"`
+}
+
+// CloneStudyPlanRequest estructura para clonar un plan de estudio en una nueva versión
+type CloneStudyPlanRequest struct {
+	Version string `json:"version" binding:"required"`
+}
+
+// createStudyPlan crea un plan de estudio como borrador inactivo. Se activa con PUT /study-plans/:id
+// una vez tiene asociadas materias consistentes con los créditos exigidos.
+func createStudyPlan(c *gin.Context) {
+	var req StudyPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+	if issues := functions.ValidateRequirements(req.PlanRequirements); len(issues) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Créditos por tipología inválidos", "issues": issues})
+		return
+	}
+
+	var career models.Career
+	if err := config.DB.Where("code = ?", req.CareerCode).First(&career).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Carrera no encontrada"})
+		return
+	}
+
+	studyPlan := models.StudyPlan{
+		CareerID:      career.ID,
+		Version:       strings.TrimSpace(req.Version),
+		EffectiveFrom: strings.TrimSpace(req.EffectiveFrom),
+		EffectiveTo:   strings.TrimSpace(req.EffectiveTo),
+	}
+	if req.SedeCode != "" {
+		sede, ok := findSedeByCode(c, req.SedeCode)
+		if !ok {
+			return
+		}
+		studyPlan.SedeID = &sede.ID
+	}
+	functions.ApplyPlanRequirements(&studyPlan, req.PlanRequirements)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&studyPlan).Error; err != nil {
+			return errors.New("Error creando el plan de estudio")
+		}
+		if err := functions.CheckPlanConflicts(tx, &studyPlan); err != nil {
+			return err
+		}
+
+		state, err := functions.LoadStudyPlanAuditState(tx, studyPlan.ID)
+		if err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), functions.AuditActionCreate, functions.AuditEntityStudyPlan, studyPlan.ID, functions.StudyPlanAuditKey(state), nil, state)
+	})
+	var conflictErr *functions.PlanConflictError
+	var periodErr *functions.PlanPeriodError
+	switch {
+	case errors.As(err, &conflictErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.As(err, &periodErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	studyPlan.Career = career
+	c.JSON(http.StatusCreated, gin.H{
+		"study_plan": studyPlan,
+		"validation": functions.ValidateStudyPlan(studyPlan),
+	})
+}
+
+// updateStudyPlan edita la versión, la vigencia y la sede de un plan o lo activa/desactiva. Un plan solo
+// puede activarse si es consistente y si no hay otro plan activo de la carrera en la misma sede.
+func updateStudyPlan(c *gin.Context) {
+	studyPlanID, ok := studyPlanIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateStudyPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+	var sedeID *uint
+	if req.SedeCode != nil && strings.TrimSpace(*req.SedeCode) != "" {
+		sede, ok := findSedeByCode(c, *req.SedeCode)
+		if !ok {
+			return
+		}
+		sedeID = &sede.ID
+	}
+
+	if req.Version != nil && strings.TrimSpace(*req.Version) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La versión del plan no puede estar vacía"})
+		return
+	}
+
+	studyPlan, issues, err := functions.ChangeStudyPlan(config.DB, studyPlanID, requestAuditActor(c), functions.AuditActionUpdate, func(tx *gorm.DB, studyPlan *models.StudyPlan) error {
+		updates := map[string]interface{}{}
+		if req.Version != nil {
+			updates["version"] = strings.TrimSpace(*req.Version)
+		}
+		if req.IsActive != nil {
+			updates["is_active"] = *req.IsActive
+		}
+		if req.EffectiveFrom != nil {
+			updates["effective_from"] = strings.TrimSpace(*req.EffectiveFrom)
+		}
+		if req.EffectiveTo != nil {
+			updates["effective_to"] = strings.TrimSpace(*req.EffectiveTo)
+		}
+		if req.SedeCode != nil {
+			updates["sede_id"] = sedeID
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(studyPlan).Updates(updates).Error
+	})
+	respondStudyPlanChange(c, studyPlan, issues, err)
+}
+
+// setStudyPlanRequirements define los créditos exigidos por el plan en cada tipología
+func setStudyPlanRequirements(c *gin.Context) {
+	studyPlanID, ok := studyPlanIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req functions.PlanRequirements
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+	if issues := functions.ValidateRequirements(req); len(issues) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Créditos por tipología inválidos", "issues": issues})
+		return
+	}
+
+	studyPlan, issues, err := functions.ChangeStudyPlan(config.DB, studyPlanID, requestAuditActor(c), functions.AuditActionUpdate, func(tx *gorm.DB, studyPlan *models.StudyPlan) error {
+		functions.ApplyPlanRequirements(studyPlan, req)
+		return tx.Save(studyPlan).Error
+	})
+	respondStudyPlanChange(c, studyPlan, issues, err)
+}
+
+// attachStudyPlanSubject asocia una materia al plan con su tipología y créditos en ese plan
+func attachStudyPlanSubject(c *gin.Context) {
+	studyPlanID, ok := studyPlanIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req PlanSubjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+	if req.Type != "" && !models.ValidarTipologia(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipología inválida: " + req.Type})
+		return
+	}
+	if req.Credits < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Los créditos no pueden ser negativos"})
+		return
+	}
+
+	var subject models.Subject
+	if err := config.DB.Where("code = ?", req.SubjectCode).First(&subject).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Materia no encontrada: " + req.SubjectCode})
+		return
+	}
+
+	studyPlan, issues, err := functions.ChangeStudyPlan(config.DB, studyPlanID, requestAuditActor(c), functions.AuditActionAttachSubject, func(tx *gorm.DB, studyPlan *models.StudyPlan) error {
+		return functions.AttachSubjectToPlan(tx, studyPlan.ID, subject, models.TipologiaAsignatura(req.Type), req.Credits)
+	})
+	respondStudyPlanChange(c, studyPlan, issues, err)
+}
+
+// detachStudyPlanSubject retira una materia del plan
+func detachStudyPlanSubject(c *gin.Context) {
+	studyPlanID, ok := studyPlanIDParam(c)
+	if !ok {
+		return
+	}
+
+	var subject models.Subject
+	if err := config.DB.Where("code = ?", c.Param("code")).First(&subject).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Materia no encontrada: " + c.Param("code")})
+		return
+	}
+
+	studyPlan, issues, err := functions.ChangeStudyPlan(config.DB, studyPlanID, requestAuditActor(c), functions.AuditActionDetachSubject, func(tx *gorm.DB, studyPlan *models.StudyPlan) error {
+		return functions.DetachSubjectFromPlan(tx, studyPlan.ID, subject)
+	})
+	respondStudyPlanChange(c, studyPlan, issues, err)
+}
+
+// validateStudyPlan informa las inconsistencias de créditos de un plan de estudio
+func validateStudyPlan(c *gin.Context) {
+	studyPlanID, ok := studyPlanIDParam(c)
+	if !ok {
+		return
+	}
+
+	studyPlan, err := functions.LoadStudyPlanForValidation(config.DB, studyPlanID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	issues := functions.ValidateStudyPlan(*studyPlan)
+	c.JSON(http.StatusOK, gin.H{
+		"study_plan_id": studyPlan.ID,
+		"valid":         len(issues) == 0,
+		"issues":        issues,
+	})
+}
+
+// getStudyPlanPrerequisites obtiene el grafo de prerrequisitos del plan con el nivel de cada materia
+func getStudyPlanPrerequisites(c *gin.Context) {
+	studyPlanID, ok := studyPlanIDParam(c)
+	if !ok {
+		return
+	}
+
+	graph, err := functions.BuildPrerequisiteGraph(config.DB, studyPlanID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prerequisite_graph": graph,
+	})
+}
+
+// addStudyPlanPrerequisite agrega un prerrequisito entre dos materias del plan, rechazando ciclos
+func addStudyPlanPrerequisite(c *gin.Context) {
+	studyPlanID, ok := studyPlanIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req PlanPrerequisiteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+
+	err := functions.AddPlanPrerequisite(config.DB, studyPlanID, req.SubjectCode, req.PrerequisiteCode, requestAuditActor(c))
+	var cycleErr *functions.PrerequisiteCycleError
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, gin.H{
+			"message": req.PrerequisiteCode + " es ahora prerrequisito de " + req.SubjectCode,
+		})
+	case errors.Is(err, functions.ErrStudyPlanNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &cycleErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "cycle": cycleErr.Cycle})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// removeStudyPlanPrerequisite elimina un prerrequisito de una materia en el plan
+func removeStudyPlanPrerequisite(c *gin.Context) {
+	studyPlanID, ok := studyPlanIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := functions.RemovePlanPrerequisite(config.DB, studyPlanID, c.Param("code"), c.Param("prerequisite"), requestAuditActor(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Prerrequisito eliminado",
+	})
+}
+
+// cloneStudyPlan crea una nueva versión inactiva del plan para editarla, por ejemplo ante una reforma curricular
+func cloneStudyPlan(c *gin.Context) {
+	studyPlanID, ok := studyPlanIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req CloneStudyPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+
+	clone, err := functions.CloneStudyPlan(config.DB, studyPlanID, req.Version, requestAuditActor(c))
+	if errors.Is(err, functions.ErrStudyPlanNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"study_plan":             clone,
+		"cloned_from_study_plan": studyPlanID,
+	})
+}
+
+// diffStudyPlans lista las diferencias entre dos versiones de un plan (?from=&to=)
+func diffStudyPlans(c *gin.Context) {
+	fromID, err := strconv.ParseUint(c.Query("from"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro from debe ser un ID de plan de estudio válido"})
+		return
+	}
+	toID, err := strconv.ParseUint(c.Query("to"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro to debe ser un ID de plan de estudio válido"})
+		return
+	}
+
+	diff, err := functions.DiffStudyPlans(config.DB, uint(fromID), uint(toID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"diff": diff,
+	})
+}
+
+// studyPlanIDParam lee el ID de plan de estudio de la ruta o responde con el error correspondiente
+func studyPlanIDParam(c *gin.Context) (uint, bool) {
+	studyPlanID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de plan de estudio inválido"})
+		return 0, false
+	}
+	return uint(studyPlanID), true
+}
+
+// respondStudyPlanChange responde con el plan modificado y sus avisos de validación, o con el error del cambio
+func respondStudyPlanChange(c *gin.Context, studyPlan *models.StudyPlan, issues []string, err error) {
+	var validationErr *functions.PlanValidationError
+	var conflictErr *functions.PlanConflictError
+	var periodErr *functions.PlanPeriodError
+	var subjectErr *functions.PlanSubjectNotFoundError
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{
+			"study_plan": studyPlan,
+			"validation": issues,
+		})
+	case errors.Is(err, functions.ErrStudyPlanNotFound), errors.As(err, &subjectErr):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &conflictErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.As(err, &periodErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "El plan de estudio está activo y el cambio lo dejaría inconsistente",
+			"issues": validationErr.Issues,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"olimpo-vicedecanatura/config"
+	"olimpo-vicedecanatura/functions"
+	"olimpo-vicedecanatura/models"
+)
+
+// SubjectRequest estructura para la creación o actualización de una materia
+type SubjectRequest struct {
+	Code        string  `json:"code" binding:"required"`
+	Name        string  `json:"name" binding:"required"`
+	Credits     int     `json:"credits" binding:"required"`
+	Type        string  `json:"type" binding:"required"`
+	Description string  `json:"description"`
+	SedeCode    *string `json:"sede_code"` // Sede que ofrece esta variante del código; al actualizar, si se omite se conserva y vacío la quita
+}
+
+// searchSubjects busca materias por prefijo de código o por nombre, sin distinguir tildes ni mayúsculas.
+// Filtros opcionales: type, credits y study_plan_id; paginación con limit y offset.
+func searchSubjects(c *gin.Context) {
+	search := functions.SubjectSearch{
+		Query: c.Query("q"),
+		Type:  models.TipologiaAsignatura(c.Query("type")),
+	}
+	if search.Type != "" && !models.ValidarTipologia(string(search.Type)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipología inválida: " + string(search.Type)})
+		return
+	}
+
+	var err error
+	if credits := c.Query("credits"); credits != "" {
+		if search.Credits, err = strconv.Atoi(credits); err != nil || search.Credits <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro credits debe ser un entero positivo"})
+			return
+		}
+	}
+	if studyPlanParam := c.Query("study_plan_id"); studyPlanParam != "" {
+		studyPlanID, err := strconv.ParseUint(studyPlanParam, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de plan de estudio inválido"})
+			return
+		}
+		search.StudyPlanID = uint(studyPlanID)
+	}
+	search.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || search.Limit <= 0 || search.Limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro limit debe estar entre 1 y 200"})
+		return
+	}
+	search.Offset, err = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || search.Offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El parámetro offset debe ser un entero no negativo"})
+		return
+	}
+
+	subjects, total, err := functions.SearchSubjects(config.DB, search)
+	if errors.Is(err, functions.ErrStudyPlanNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subjects": subjects,
+		"total":    total,
+		"limit":    search.Limit,
+		"offset":   search.Offset,
+	})
+}
+
+// getSubjectByCode obtiene una materia por su código, con su sede. Los prerrequisitos dependen del plan y
+// se consultan en el grafo de prerrequisitos del plan de estudio.
+func getSubjectByCode(c *gin.Context) {
+	var subject models.Subject
+	if err := config.DB.Preload("Sede").Where("code = ?", c.Param("code")).First(&subject).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Materia no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subject": subject,
+	})
+}
+
+// createSubject registra una nueva materia en el catálogo. Si hay una materia eliminada con el mismo código
+// se restaura con los datos nuevos, conservando su ID y las referencias que tenía.
+func createSubject(c *gin.Context) {
+	var req SubjectRequest
+	if !bindSubjectRequest(c, &req) {
+		return
+	}
+	var deleted models.Subject
+	if err := config.DB.Unscoped().Where("code = ? AND deleted_at IS NOT NULL", req.Code).Limit(1).Find(&deleted).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando las materias eliminadas"})
+		return
+	}
+	if subjectCodeTaken(c, req.Code, deleted.ID) {
+		return
+	}
+	sede, ok := subjectSede(c, req.SedeCode)
+	if !ok {
+		return
+	}
+
+	subject := models.Subject{
+		Code:        req.Code,
+		Name:        req.Name,
+		Credits:     req.Credits,
+		Type:        models.TipologiaAsignatura(req.Type),
+		Description: req.Description,
+	}
+	setSubjectSede(&subject, sede)
+	action := functions.AuditActionCreate
+	if deleted.ID != 0 {
+		subject.ID, subject.CreatedAt = deleted.ID, deleted.CreatedAt
+		action = functions.AuditActionRestore
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Save crea la materia o, al restaurarla, la actualiza quitando la marca de borrado
+		if err := tx.Unscoped().Omit("Sede").Save(&subject).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), action, functions.AuditEntitySubject, subject.ID, subject.Code, nil, subject)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando la materia"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"subject": subject,
+	})
+}
+
+// updateSubject actualiza los datos generales de una materia. La tipología y los créditos
+// que la materia tiene en cada plan se editan en /study-plans/:id/subjects.
+func updateSubject(c *gin.Context) {
+	subject, ok := findSubject(c)
+	if !ok {
+		return
+	}
+
+	var req SubjectRequest
+	if !bindSubjectRequest(c, &req) {
+		return
+	}
+	if subjectCodeTaken(c, req.Code, subject.ID) {
+		return
+	}
+	sede, ok := subjectSede(c, req.SedeCode)
+	if !ok {
+		return
+	}
+
+	before := *subject
+	subject.Code = req.Code
+	subject.Name = req.Name
+	subject.Credits = req.Credits
+	subject.Type = models.TipologiaAsignatura(req.Type)
+	subject.Description = req.Description
+	if req.SedeCode != nil {
+		setSubjectSede(subject, sede)
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Sede").Save(subject).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, requestAuditActor(c), functions.AuditActionUpdate, functions.AuditEntitySubject, subject.ID, subject.Code, before, subject)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando la materia"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusOK, gin.H{
+		"subject": subject,
+	})
+}
+
+// deleteSubject elimina una materia que no esté en ningún plan, equivalencia vigente o en trámite ni
+// prerrequisito. El borrado es lógico: las equivalencias ya decididas y la auditoría siguen refiriéndose a ella.
+func deleteSubject(c *gin.Context) {
+	subject, ok := findSubject(c)
+	if !ok {
+		return
+	}
+
+	references := []struct {
+		table   string
+		where   string
+		message string
+	}{
+		{"study_plan_subjects", "subject_id = @id", "pertenece a planes de estudio"},
+		{"equivalences", "(source_subject_id = @id OR target_subject_id = @id) AND status IN @active AND deleted_at IS NULL", "tiene equivalencias aprobadas o en trámite"},
+		{"plan_prerequisites", "subject_id = @id OR prerequisite_id = @id", "tiene prerrequisitos o es prerrequisito de otras materias en algún plan"},
+	}
+	for _, ref := range references {
+		var count int64
+		if err := config.DB.Table(ref.table).Where(ref.where, sql.Named("id", subject.ID), sql.Named("active", functions.ActiveEquivalenceStatuses)).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando el uso de la materia"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "No se puede eliminar la materia porque " + ref.message})
+			return
+		}
+	}
+
+	// Los alias pertenecen a la materia y se eliminan con ella
+	who := requestAuditActor(c)
+	before := *subject
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		aliases, err := functions.ListSubjectAliases(tx, *subject)
+		if err != nil {
+			return err
+		}
+		for _, alias := range aliases {
+			if err := tx.Delete(&alias).Error; err != nil {
+				return err
+			}
+			if err := functions.RecordAudit(tx, who, functions.AuditActionRemoveAlias, functions.AuditEntitySubject, subject.ID, subject.Code, alias, nil); err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(subject).Error; err != nil {
+			return err
+		}
+		return functions.RecordAudit(tx, who, functions.AuditActionDelete, functions.AuditEntitySubject, subject.ID, subject.Code, before, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando la materia"})
+		return
+	}
+	functions.InvalidateCatalog()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Materia eliminada",
+	})
+}
+
+// findSubject obtiene la materia indicada en la ruta o responde con el error correspondiente
+func findSubject(c *gin.Context) (*models.Subject, bool) {
+	var subject models.Subject
+	if err := config.DB.Where("code = ?", c.Param("code")).First(&subject).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Materia no encontrada"})
+		return nil, false
+	}
+	return &subject, true
+}
+
+// bindSubjectRequest lee y normaliza los datos de una materia, validando código, tipología y créditos
+func bindSubjectRequest(c *gin.Context, req *SubjectRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return false
+	}
+
+	req.Code = strings.ToUpper(strings.TrimSpace(req.Code))
+	req.Name = strings.TrimSpace(req.Name)
+	if !models.ValidarCodigoMateria(req.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Código de materia inválido: debe tener hasta 20 letras mayúsculas o dígitos, separados opcionalmente por guiones"})
+		return false
+	}
+	if req.Name == "" || len(req.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El nombre de la materia debe tener entre 1 y 100 caracteres"})
+		return false
+	}
+	if req.Credits <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Los créditos de la materia deben ser mayores que cero"})
+		return false
+	}
+	if !models.ValidarTipologia(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipología inválida: " + req.Type})
+		return false
+	}
+	return true
+}
+
+// subjectCodeTaken verifica si el código ya lo usa otra materia y, de ser así, responde con un conflicto
+func subjectCodeTaken(c *gin.Context, code string, exceptID uint) bool {
+	// Las materias eliminadas conservan su código
+	var existing models.Subject
+	if err := config.DB.Unscoped().Select("id", "deleted_at").Where("code = ? AND id <> ?", code, exceptID).Limit(1).Find(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verificando el código de la materia"})
+		return true
+	}
+	if existing.ID != 0 && existing.DeletedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "El código " + code + " pertenece a una materia eliminada"})
+		return true
+	}
+	if existing.ID != 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ya existe una materia con el código " + code})
+		return true
+	}
+
+	var alias models.SubjectAlias
+	if err := config.DB.Preload("Subject").Where("alias = ? AND subject_id <> ?", functions.NormalizeSubjectCode(code), exceptID).First(&alias).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "El código " + code + " está registrado como alias de la materia " + alias.Subject.Code})
+		return true
+	}
+	return false
+}
+
+// SubjectAliasRequest estructura para registrar un alias de una materia
+type SubjectAliasRequest struct {
+	Alias string `json:"alias" binding:"required"`
+	Notes string `json:"notes"`
+}
+
+// getSubjectAliases lista los códigos alternos con los que la materia aparece en las historias académicas
+func getSubjectAliases(c *gin.Context) {
+	subject, ok := findSubject(c)
+	if !ok {
+		return
+	}
+
+	aliases, err := functions.ListSubjectAliases(config.DB, *subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subject": subject.Code,
+		"aliases": aliases,
+	})
+}
+
+// addSubjectAlias registra un código alterno de la materia; el alias se guarda normalizado
+func addSubjectAlias(c *gin.Context) {
+	subject, ok := findSubject(c)
+	if !ok {
+		return
+	}
+
+	var req SubjectAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Datos de entrada inválidos: " + err.Error()})
+		return
+	}
+
+	alias, err := functions.AddSubjectAlias(config.DB, *subject, req.Alias, req.Notes, requestAuditActor(c))
+	var aliasErr *functions.SubjectAliasError
+	var conflictErr *functions.SubjectAliasConflictError
+	switch {
+	case errors.As(err, &aliasErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.As(err, &conflictErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Alias registrado",
+		"alias":   alias,
+	})
+}
+
+// removeSubjectAlias elimina un código alterno de la materia
+func removeSubjectAlias(c *gin.Context) {
+	subject, ok := findSubject(c)
+	if !ok {
+		return
+	}
+
+	if err := functions.RemoveSubjectAlias(config.DB, *subject, c.Param("alias"), requestAuditActor(c)); err != nil {
+		if errors.Is(err, functions.ErrSubjectAliasNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alias no encontrado"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Alias eliminado",
+	})
+}
+
+// subjectSede obtiene la sede indicada para una materia; sin código la materia queda sin sede registrada
+// y su sede se deduce del sufijo del código
+func subjectSede(c *gin.Context, code *string) (*models.Sede, bool) {
+	if code == nil || strings.TrimSpace(*code) == "" {
+		return nil, true
+	}
+	return findSedeByCode(c, *code)
+}
+
+// setSubjectSede asigna (o quita, si es nil) la sede de una materia
+func setSubjectSede(subject *models.Subject, sede *models.Sede) {
+	subject.Sede = sede
+	subject.SedeID = nil
+	if sede != nil {
+		subject.SedeID = &sede.ID
+	}
+}